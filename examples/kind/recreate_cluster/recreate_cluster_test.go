@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recreate_cluster
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/support/kind"
+)
+
+// TestRecreateCluster creates, destroys and creates again a kind cluster using the same Cluster
+func TestRecreateCluster(t *testing.T) {
+	ctx := context.Background()
+	cluster := kind.NewCluster(envconf.RandomName("kind-recreate", 16))
+
+	for i := 0; i < 2; i++ {
+		kubeconfig, err := cluster.Create(ctx)
+		if err != nil {
+			t.Fatalf("failed to create cluster (attempt %d): %s", i+1, err)
+		}
+		if cluster.GetKubeconfig() != kubeconfig {
+			t.Errorf("expected kubeconfig %s, got %s", kubeconfig, cluster.GetKubeconfig())
+		}
+
+		res, err := resources.New(cluster.KubernetesRestConfig())
+		if err != nil {
+			t.Fatalf("failed to create resources client (attempt %d): %s", i+1, err)
+		}
+		var namespaces corev1.NamespaceList
+		if err := res.List(ctx, &namespaces); err != nil {
+			t.Errorf("failed to reach the cluster (attempt %d): %s", i+1, err)
+		}
+
+		if err := cluster.Destroy(ctx); err != nil {
+			t.Fatalf("failed to destroy cluster (attempt %d): %s", i+1, err)
+		}
+		if cluster.GetKubeconfig() != "" || cluster.KubernetesRestConfig() != nil {
+			t.Errorf("expected the cluster state to be reset after destroy (attempt %d)", i+1)
+		}
+	}
+}
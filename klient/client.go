@@ -18,9 +18,12 @@ package klient
 
 import (
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/e2e-framework/klient/conf"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
@@ -48,8 +51,10 @@ func NewControllerRuntimeClient(cfg *rest.Config, scheme *runtime.Scheme) (cr.Cl
 	return cr.New(cfg, cr.Options{Scheme: scheme})
 }
 
-// New returns a new Client value
-func New(cfg *rest.Config) (Client, error) {
+// New returns a new Client value. The options, if any, are applied
+// to a copy of cfg.
+func New(cfg *rest.Config, opts ...ConfigOption) (Client, error) {
+	cfg = ApplyConfigOptions(cfg, opts...)
 	res, err := resources.New(cfg)
 	if err != nil {
 		return nil, err
@@ -58,12 +63,12 @@ func New(cfg *rest.Config) (Client, error) {
 }
 
 // NewWithKubeConfigFile creates a client using the kubeconfig filePath
-func NewWithKubeConfigFile(filePath string) (Client, error) {
+func NewWithKubeConfigFile(filePath string, opts ...ConfigOption) (Client, error) {
 	cfg, err := conf.New(filePath)
 	if err != nil {
 		return nil, err
 	}
-	return New(cfg)
+	return New(cfg, opts...)
 }
 
 // RESTConfig returns the *rest.Config value associated
@@ -85,6 +90,20 @@ func (c *client) Resources(namespace ...string) *resources.Resources {
 	}
 }
 
+// RESTClientForGVK returns a REST client configured for the group and version of gvk, using the
+// configuration of c and the codecs of the scheme of its resources, or the unstructured codecs
+// if gvk is not registered in the scheme. This is an escape hatch for the requests that cannot be
+// expressed using Resources, such as the ones targeting custom subresources or aggregated APIs.
+func RESTClientForGVK(c Client, gvk schema.GroupVersionKind) (rest.Interface, error) {
+	cfg := c.RESTConfig()
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	scheme := c.Resources().GetScheme()
+	return apiutil.RESTClientForGVK(gvk, !scheme.Recognizes(gvk), cfg, serializer.NewCodecFactory(scheme), httpClient)
+}
+
 func init() {
 	log.SetLogger(klog.NewKlogr())
 }
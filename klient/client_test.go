@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestRESTClientForGVK(t *testing.T) {
+	c, err := New(&rest.Config{Host: "https://127.0.0.1:6443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		gvk         schema.GroupVersionKind
+		resource    string
+		subresource string
+		path        string
+	}{
+		{
+			name:        "core",
+			gvk:         schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			resource:    "pods",
+			subresource: "status",
+			path:        "/api/v1/namespaces/default/pods/test/status",
+		},
+		{
+			name:        "apps",
+			gvk:         schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			resource:    "deployments",
+			subresource: "scale",
+			path:        "/apis/apps/v1/namespaces/default/deployments/test/scale",
+		},
+		{
+			name:        "unregistered",
+			gvk:         schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"},
+			resource:    "widgets",
+			subresource: "scale",
+			path:        "/apis/example.com/v1/namespaces/default/widgets/test/scale",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restClient, err := RESTClientForGVK(c, test.gvk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			url := restClient.Get().Namespace("default").Resource(test.resource).Name("test").SubResource(test.subresource).URL()
+			if url.Path != test.path {
+				t.Errorf("unexpected request path %q, expected %q", url.Path, test.path)
+			}
+		})
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"strings"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -35,6 +36,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/e2e-framework/klient/k8s"
@@ -46,6 +48,11 @@ import (
 type Options struct {
 	DefaultGVK  *schema.GroupVersionKind
 	MutateFuncs []MutateFunc
+	// ContinueOnError makes the DecodeEach functions handle all the documents, even if some of them
+	// fail to be decoded or handled, and return an error aggregating the failures
+	ContinueOnError bool
+	// RESTMapper is used by MutateNamespace to find out whether the kind of an object is namespaced
+	RESTMapper meta.RESTMapper
 }
 
 // DecodeOption is a function that alters the configuration Options used to decode and optionally mutate objects via MutateFuncs
@@ -58,29 +65,84 @@ type MutateFunc func(obj k8s.Object) error
 // HandlerFunc is a function executed after an object has been decoded and patched. If an error is returned, further decoding is halted.
 type HandlerFunc func(ctx context.Context, obj k8s.Object) error
 
+// CanceledError is returned by the DecodeEach functions when the context is done before all the documents
+// have been decoded and handled. It reports the objects that were handled, for instance created, before the
+// cancellation so that a partially applied set of manifests can be identified and cleaned up.
+type CanceledError struct {
+	// Handled are the objects successfully handled before the cancellation, in the order they were handled
+	Handled []k8s.Object
+	// Err is the error of the context
+	Err error
+}
+
+func (e *CanceledError) Error() string {
+	names := make([]string, 0, len(e.Handled))
+	for _, obj := range e.Handled {
+		names = append(names, fmt.Sprintf("%s %s", obj.GetObjectKind().GroupVersionKind().Kind, objectName(obj)))
+	}
+	return fmt.Sprintf("decoding canceled after handling %d objects [%s]: %s", len(e.Handled), strings.Join(names, ", "), e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// contextReader is a reader returning the error of the context as soon as it is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
 // DecodeEachFile resolves files at the filesystem matching the pattern, decoding JSON or YAML files. Supports multi-document files.
 //
 // If handlerFn returns an error, decoding is halted.
 // Options may be provided to configure the behavior of the decoder.
+//
+// Decoding stops as soon as ctx is done, in between the reads of the files and the handling of the objects,
+// and a CanceledError reporting the objects handled so far, across all the files, is returned.
 func DecodeEachFile(ctx context.Context, fsys fs.FS, pattern string, handlerFn HandlerFunc, options ...DecodeOption) error {
 	files, err := fs.Glob(fsys, pattern)
 	if err != nil {
 		return err
 	}
+	decodeOpt := &Options{}
+	for _, opt := range options {
+		opt(decodeOpt)
+	}
+	var errs []error
+	var handled []k8s.Object
 	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return &CanceledError{Handled: handled, Err: err}
+		}
 		f, err := fsys.Open(file)
 		if err != nil {
 			return err
 		}
-		defer f.Close()
-		if err := DecodeEach(ctx, f, handlerFn, options...); err != nil {
-			return err
+		err = decodeEach(ctx, f, handlerFn, decodeOpt, options, &handled)
+		closeErr := f.Close()
+		var canceled *CanceledError
+		if errors.As(err, &canceled) {
+			return canceled
 		}
-		if err := f.Close(); err != nil {
-			return err
+		if err != nil {
+			if !decodeOpt.ContinueOnError {
+				return err
+			}
+			errs = append(errs, fmt.Errorf("file %s: %w", file, err))
+		}
+		if closeErr != nil {
+			return closeErr
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 // DecodeAllFiles  resolves files at the filesystem matching the pattern, decoding JSON or YAML files. Supports multi-document files.
@@ -98,40 +160,97 @@ func DecodeAllFiles(ctx context.Context, fsys fs.FS, pattern string, options ...
 // ApplyWithManifestDir resolves all the files in the Directory dirPath against the globbing pattern and creates a kubernetes
 // resource for each of the resources found under the manifest directory.
 func ApplyWithManifestDir(ctx context.Context, r *resources.Resources, dirPath, pattern string, createOptions []resources.CreateOption, options ...DecodeOption) error {
-	err := DecodeEachFile(ctx, os.DirFS(dirPath), pattern, CreateHandler(r, createOptions...), options...)
+	err := DecodeEachFile(ctx, os.DirFS(dirPath), pattern, CreateHandler(r, createOptions...), withResourcesMapper(r, options)...)
 	return err
 }
 
+// ApplyYAML decodes the objects of the, possibly multi-document, YAML manifest and creates each of them, or
+// updates it if it already exists, without going through the filesystem, which is handy for small inline
+// fixtures. The decode options, such as MutateNamespace, are applied to each object before it is applied.
+// The applied objects are returned in the order they appear in the manifest, updated with their state
+// returned by the API server.
+func ApplyYAML(ctx context.Context, r *resources.Resources, manifest string, options ...DecodeOption) ([]k8s.Object, error) {
+	var objects []k8s.Object
+	apply := CreateOrUpdateHandler(r)
+	err := DecodeEach(ctx, strings.NewReader(manifest), func(ctx context.Context, obj k8s.Object) error {
+		if err := apply(ctx, obj); err != nil {
+			return err
+		}
+		objects = append(objects, obj)
+		return nil
+	}, withResourcesMapper(r, options)...)
+	return objects, err
+}
+
 // DeleteWithManifestDir does the reverse of ApplyUsingManifestDir does. This will resolve all files in the dirPath against the pattern and then
 // delete those kubernetes resources found under the manifest directory.
 func DeleteWithManifestDir(ctx context.Context, r *resources.Resources, dirPath, pattern string, deleteOptions []resources.DeleteOption, options ...DecodeOption) error {
-	err := DecodeEachFile(ctx, os.DirFS(dirPath), pattern, DeleteHandler(r, deleteOptions...), options...)
+	err := DecodeEachFile(ctx, os.DirFS(dirPath), pattern, DeleteHandler(r, deleteOptions...), withResourcesMapper(r, options)...)
 	return err
 }
 
 // Decode a stream of documents of any Kind using either the innate typing of the scheme.
 // Falls back to the unstructured.Unstructured type if a matching type cannot be found for the Kind.
 //
-// If handlerFn returns an error, decoding is halted.
+// If handlerFn returns an error, decoding is halted, unless the WithContinueOnError option is provided.
 // Options may be provided to configure the behavior of the decoder.
+//
+// Decoding stops as soon as ctx is done, in between the reads of the manifest and the handling of the
+// objects, and a CanceledError reporting the objects handled so far is returned.
 func DecodeEach(ctx context.Context, manifest io.Reader, handlerFn HandlerFunc, options ...DecodeOption) error {
-	decoder := yaml.NewYAMLReader(bufio.NewReader(manifest))
-	for {
+	decodeOpt := &Options{}
+	for _, opt := range options {
+		opt(decodeOpt)
+	}
+	var handled []k8s.Object
+	return decodeEach(ctx, manifest, handlerFn, decodeOpt, options, &handled)
+}
+
+// decodeEach decodes and handles the documents of the manifest, appending the objects successfully handled to handled
+func decodeEach(ctx context.Context, manifest io.Reader, handlerFn HandlerFunc, decodeOpt *Options, options []DecodeOption, handled *[]k8s.Object) error {
+	decoder := yaml.NewYAMLReader(bufio.NewReader(&contextReader{ctx: ctx, r: manifest}))
+	var errs []error
+	for doc := 1; ; doc++ {
+		if err := ctx.Err(); err != nil {
+			return &CanceledError{Handled: *handled, Err: err}
+		}
 		b, err := decoder.Read()
 		if errors.Is(err, io.EOF) {
 			break
+		} else if ctxErr := ctx.Err(); ctxErr != nil {
+			return &CanceledError{Handled: *handled, Err: ctxErr}
 		} else if err != nil {
-			return err
+			return errors.Join(append(errs, err)...)
 		}
 		obj, err := DecodeAny(bytes.NewReader(b), options...)
-		if err != nil {
-			return err
+		if err == nil {
+			err = handlerFn(ctx, obj)
+			if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+				return &CanceledError{Handled: *handled, Err: ctxErr}
+			}
 		}
-		if err := handlerFn(ctx, obj); err != nil {
-			return err
+		if err == nil {
+			*handled = append(*handled, obj)
+		}
+		if err != nil {
+			if !decodeOpt.ContinueOnError {
+				return err
+			}
+			if obj != nil {
+				err = fmt.Errorf("%s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, objectName(obj), err)
+			}
+			errs = append(errs, fmt.Errorf("document %d: %w", doc, err))
 		}
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// objectName returns the name of the object, prefixed by its namespace if it has one
+func objectName(obj k8s.Object) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
 }
 
 // DecodeAll is a stream of  documents of any Kind using either the innate typing of the scheme.
@@ -217,6 +336,30 @@ func DecodeString(rawManifest string, obj k8s.Object, options ...DecodeOption) e
 	return Decode(strings.NewReader(rawManifest), obj, options...)
 }
 
+// WithContinueOnError makes the DecodeEach functions, and the functions built on them, decode and handle all the
+// documents instead of stopping at the first failure. The returned error aggregates the failures, identifying the
+// file, the position of the document in the file and, once decoded, the object each failure relates to. Errors
+// reading the stream of documents itself still halt decoding.
+func WithContinueOnError() DecodeOption {
+	return func(do *Options) {
+		do.ContinueOnError = true
+	}
+}
+
+// WithRESTMapper provides the RESTMapper used to find out whether the kind of a decoded object is namespaced, so
+// that MutateNamespace leaves the cluster scoped objects, such as CRDs and ClusterRoles, without a namespace. The
+// RESTMapper of a client can be obtained using resources.Resources.GetControllerRuntimeClient().RESTMapper().
+func WithRESTMapper(mapper meta.RESTMapper) DecodeOption {
+	return func(do *Options) {
+		do.RESTMapper = mapper
+	}
+}
+
+// withResourcesMapper returns the options preceded by the RESTMapper of r, which can be overridden by the options
+func withResourcesMapper(r *resources.Resources, options []DecodeOption) []DecodeOption {
+	return append([]DecodeOption{WithRESTMapper(r.GetControllerRuntimeClient().RESTMapper())}, options...)
+}
+
 // DefaultGVK instructs the decoder to use the given type to look up the appropriate Go type to decode into
 // instead of its default behavior of deciding this by decoding the Group, Version, and Kind fields.
 func DefaultGVK(defaults *schema.GroupVersionKind) DecodeOption {
@@ -270,12 +413,43 @@ func MutateOwnerAnnotations(owner k8s.Object) DecodeOption {
 	})
 }
 
-// MutateNamespace is an optional parameter to decoding functions that will patch objects with the given namespace name
+// MutateNamespace is an optional parameter to decoding functions that will patch objects with the given namespace name.
+// When a RESTMapper is provided using WithRESTMapper, as done by ApplyYAML, ApplyWithManifestDir and
+// DeleteWithManifestDir, the namespace is only set on the objects of namespaced kinds and the cluster scoped objects
+// are left untouched. The objects of kinds unknown to the RESTMapper, such as custom resources whose CRD is defined in
+// the same manifest, are assumed to be namespaced. Without a RESTMapper, the namespace is set on all the objects.
 func MutateNamespace(namespace string) DecodeOption {
-	return MutateOption(func(obj k8s.Object) error {
-		obj.SetNamespace(namespace)
-		return nil
-	})
+	return func(do *Options) {
+		do.MutateFuncs = append(do.MutateFuncs, func(obj k8s.Object) error {
+			namespaced, err := isNamespaced(do.RESTMapper, obj)
+			if err != nil {
+				return err
+			}
+			if namespaced {
+				obj.SetNamespace(namespace)
+			}
+			return nil
+		})
+	}
+}
+
+// isNamespaced reports whether the kind of the object is namespaced according to mapper, kinds are assumed to be
+// namespaced when mapper is nil or does not know them
+func isNamespaced(mapper meta.RESTMapper, obj k8s.Object) (bool, error) {
+	if mapper == nil {
+		return true, nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, scheme.Scheme)
+	if err != nil {
+		return false, err
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("%s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
 }
 
 // CreateHandler returns a HandlerFunc that will create objects
@@ -285,6 +459,26 @@ func CreateHandler(r *resources.Resources, opts ...resources.CreateOption) Handl
 	}
 }
 
+// CreateOrUpdateHandler returns a HandlerFunc that will create objects, or update them with the state
+// being decoded if they already exist
+func CreateOrUpdateHandler(r *resources.Resources, opts ...resources.CreateOption) HandlerFunc {
+	return func(ctx context.Context, obj k8s.Object) error {
+		err := r.Create(ctx, obj, opts...)
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		existing, ok := obj.DeepCopyObject().(k8s.Object)
+		if !ok {
+			return fmt.Errorf("resources: unexpected type %T does not satisfy k8s.Object", obj)
+		}
+		if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), existing); err != nil {
+			return err
+		}
+		obj.SetResourceVersion(existing.GetResourceVersion())
+		return r.Update(ctx, obj)
+	}
+}
+
 // ReadHandler returns a HandlerFunc that will use the provided object's Kind / Namespace / Name to retrieve
 // the current state of the object using the provided Resource client.
 // This helper makes it easy to use a stale reference to an object to retrieve its current version.
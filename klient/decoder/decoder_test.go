@@ -18,14 +18,19 @@ package decoder_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
@@ -212,6 +217,105 @@ func TestDecodeEach(t *testing.T) {
 	}
 }
 
+func TestDecodeEachContinueOnError(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+  namespace: default
+---
+apiVersion: v1
+kind: ConfigMap
+metadata: [
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: rejected
+  namespace: default
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: last
+  namespace: default
+`
+	var handled []string
+	handler := func(ctx context.Context, obj k8s.Object) error {
+		if obj.GetName() == "rejected" {
+			return errors.New("denied by admission")
+		}
+		handled = append(handled, obj.GetName())
+		return nil
+	}
+
+	if err := decoder.DecodeEach(context.TODO(), strings.NewReader(manifest), handler); err == nil {
+		t.Fatal("expected decoding to fail on the malformed document")
+	}
+	if len(handled) != 1 {
+		t.Fatalf("expected decoding to stop at the malformed document, handled: %v", handled)
+	}
+
+	handled = nil
+	err := decoder.DecodeEach(context.TODO(), strings.NewReader(manifest), handler, decoder.WithContinueOnError())
+	if err == nil {
+		t.Fatal("expected an error aggregating the failed documents")
+	}
+	if len(handled) != 2 || handled[0] != "first" || handled[1] != "last" {
+		t.Fatalf("expected the valid documents to be handled, handled: %v", handled)
+	}
+	for _, expected := range []string{"document 2: ", "document 3: ConfigMap default/rejected: denied by admission"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got: %s", expected, err)
+		}
+	}
+}
+
+func TestDecodeEachCanceled(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: second
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: third
+`
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	count := 0
+	err := decoder.DecodeEach(ctx, strings.NewReader(manifest), func(ctx context.Context, obj k8s.Object) error {
+		count++
+		if obj.GetName() == "second" {
+			cancel()
+		}
+		return nil
+	})
+	var canceled *decoder.CanceledError
+	if !errors.As(err, &canceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled error, got: %v", err)
+	}
+	if count != 2 || len(canceled.Handled) != 2 || canceled.Handled[1].GetName() != "second" {
+		t.Errorf("expected decoding to stop after the second object, got %d handled: %s", count, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.TODO())
+	defer cancel()
+	err = decoder.DecodeEachFile(ctx, os.DirFS(filepath.Join("testdata", "examples")), "*", func(ctx context.Context, obj k8s.Object) error {
+		cancel()
+		return nil
+	}, decoder.WithContinueOnError())
+	if !errors.As(err, &canceled) || len(canceled.Handled) != 1 {
+		t.Errorf("expected decoding of the files to stop after the first object even when continuing on errors, got: %v", err)
+	}
+}
+
 func TestDecodeAll(t *testing.T) {
 	testYAML := filepath.Join("testdata", "example-multidoc-1.yaml")
 	f, err := os.Open(testYAML)
@@ -276,6 +380,90 @@ func TestMutateAnnotations(t *testing.T) {
 	}
 }
 
+func TestMutateNamespaceClusterScoped(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: namespaced-config
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cluster-role
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+`
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, meta.RESTScopeRoot)
+
+	objects, err := decoder.DecodeAll(context.TODO(), strings.NewReader(manifest), decoder.MutateNamespace("test-ns"), decoder.WithRESTMapper(mapper))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"namespaced-config": "test-ns", "cluster-role": "", "widgets.example.com": "", "widget": "test-ns"}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %d objects, got %d", len(expected), len(objects))
+	}
+	for _, obj := range objects {
+		if obj.GetNamespace() != expected[obj.GetName()] {
+			t.Errorf("expected %s to have namespace %q, got %q", obj.GetName(), expected[obj.GetName()], obj.GetNamespace())
+		}
+	}
+}
+
+func TestApplyYAML(t *testing.T) {
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apply-yaml-test"}}
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	if err := res.Create(context.TODO(), ns); err != nil {
+		t.Fatalf("error while creating namespace %q: %s", ns.Name, err)
+	}
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: inline-config
+data:
+  key: %s
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: inline-sa
+`
+
+	objects, err := decoder.ApplyYAML(context.TODO(), res, fmt.Sprintf(manifest, "first"), decoder.MutateNamespace(ns.Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].GetUID() == "" || objects[1].GetNamespace() != ns.Name {
+		t.Fatalf("unexpected applied objects: %v", objects)
+	}
+
+	// applying the manifest again updates the existing objects
+	if _, err := decoder.ApplyYAML(context.TODO(), res, fmt.Sprintf(manifest, "second"), decoder.MutateNamespace(ns.Name)); err != nil {
+		t.Fatal(err)
+	}
+	var cm v1.ConfigMap
+	if err := res.Get(context.TODO(), "inline-config", ns.Name, &cm); err != nil {
+		t.Fatal(err)
+	}
+	if cm.Data["key"] != "second" {
+		t.Errorf("expected the config map to be updated, got: %v", cm.Data)
+	}
+}
+
 func TestHandlerFuncs(t *testing.T) {
 	handlerNS := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "handler-test"}}
 	res, err := resources.New(cfg)
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+
+	"sigs.k8s.io/e2e-framework/klient"
+)
+
+// This example reads the scale subresource of a Deployment, which is not modeled by Resources
+func ExampleRESTClientForGVK() {
+	client, err := klient.NewWithKubeConfigFile(os.Getenv("KUBECONFIG"))
+	if err != nil {
+		panic(err)
+	}
+	restClient, err := klient.RESTClientForGVK(client, appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	if err != nil {
+		panic(err)
+	}
+
+	scale := &autoscalingv1.Scale{}
+	err = restClient.Get().
+		Namespace("default").
+		Resource("deployments").
+		Name("my-app").
+		SubResource("scale").
+		Do(context.TODO()).
+		Into(scale)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(scale.Spec.Replicas)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// ExpectUpdateRejected applies the changes made by mutate to obj and attempts to update it, to check that
+// the change is refused, for instance by a webhook guarding immutable fields. It returns true along with the
+// message reported by the API server when the update is rejected as invalid or forbidden, and false when the
+// update is accepted. Any other failure, such as a conflict or a missing object, is returned as an error.
+func (r *Resources) ExpectUpdateRejected(ctx context.Context, obj k8s.Object, mutate func(), opts ...UpdateOption) (bool, string, error) {
+	mutate()
+	err := r.Update(ctx, obj, opts...)
+	if err == nil {
+		return false, "", nil
+	}
+	if message, ok := rejectionMessage(err); ok {
+		return true, message, nil
+	}
+	return false, "", err
+}
+
+// ExpectUpdateAccepted applies the changes made by mutate to obj and updates it, to check that the change is
+// allowed. When the update is rejected as invalid or forbidden, the returned error carries the message
+// reported by the API server.
+func (r *Resources) ExpectUpdateAccepted(ctx context.Context, obj k8s.Object, mutate func(), opts ...UpdateOption) error {
+	mutate()
+	err := r.Update(ctx, obj, opts...)
+	if err == nil {
+		return nil
+	}
+	if message, ok := rejectionMessage(err); ok {
+		name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		return fmt.Errorf("resources: update of %s %s was rejected: %s", r.kindOf(obj), name, message)
+	}
+	return err
+}
+
+// ExpectCreateDenied attempts to create obj to check that it is denied, for instance by a policy enforced by
+// an admission webhook such as Gatekeeper or Kyverno, or by a ValidatingAdmissionPolicy. It returns nil when
+// the create is rejected as invalid or forbidden with a message containing messageSubstr, any message matches
+// when messageSubstr is empty. Otherwise it returns an error, reporting the message of the rejection when it
+// does not match, or that the object was created, in which case the object is left for the caller to delete.
+func (r *Resources) ExpectCreateDenied(ctx context.Context, obj k8s.Object, messageSubstr string, opts ...CreateOption) error {
+	err := r.Create(ctx, obj, opts...)
+	name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	if err == nil {
+		return fmt.Errorf("resources: create of %s %s was allowed, expected it to be denied", r.kindOf(obj), name)
+	}
+	message, ok := rejectionMessage(err)
+	if !ok {
+		return err
+	}
+	if !strings.Contains(message, messageSubstr) {
+		return fmt.Errorf("resources: create of %s %s was denied with %q, expected the message to contain %q", r.kindOf(obj), name, message, messageSubstr)
+	}
+	return nil
+}
+
+// ExpectCreateAllowed creates obj to check that its creation is allowed by the admission policies. When the
+// create is rejected as invalid or forbidden, the returned error carries the message reported by the API server.
+func (r *Resources) ExpectCreateAllowed(ctx context.Context, obj k8s.Object, opts ...CreateOption) error {
+	err := r.Create(ctx, obj, opts...)
+	if err == nil {
+		return nil
+	}
+	if message, ok := rejectionMessage(err); ok {
+		name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		return fmt.Errorf("resources: create of %s %s was denied: %s", r.kindOf(obj), name, message)
+	}
+	return err
+}
+
+// rejectionMessage returns the message of err when the API server refused the request as invalid, including
+// the validation done by admission policies, or as forbidden, which is how admission webhooks deny requests
+func rejectionMessage(err error) (string, bool) {
+	if !apierrors.IsInvalid(err) && !apierrors.IsForbidden(err) {
+		return "", false
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return status.Status().Message, true
+	}
+	return err.Error(), true
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// diffFieldManager is the field manager used for the server-side dry-run apply performed by Diff
+const diffFieldManager = "e2e-framework-diff"
+
+// Diff reports what applying the object would change on the server, similarly to kubectl diff. The object is
+// applied using a server-side dry-run apply and the result is compared against the live object. The changes are
+// returned as a unified diff of both objects rendered as YAML, ignoring the managedFields and resourceVersion
+// metadata. An empty string means that applying the object is a no-op. If the object does not exist yet, the
+// whole object is reported as added.
+func (r *Resources) Diff(ctx context.Context, obj k8s.Object) (string, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return "", err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return "", err
+	}
+	merged := &unstructured.Unstructured{Object: content}
+	merged.SetGroupVersionKind(gvk)
+	merged.SetResourceVersion("")
+	merged.SetManagedFields(nil)
+	merged.SetUID("")
+	if merged.GetNamespace() == "" && r.defaultNamespace != "" {
+		merged.SetNamespace(r.defaultNamespace)
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	var liveYAML string
+	if err := r.Get(ctx, merged.GetName(), merged.GetNamespace(), live); err == nil {
+		if liveYAML, err = diffableYAML(live); err != nil {
+			return "", err
+		}
+	} else if !errors.IsNotFound(err) {
+		return "", fmt.Errorf("resources: diff: get live object %s: %w", merged.GetName(), err)
+	}
+
+	if err := r.client.Patch(ctx, merged, cr.Apply, cr.DryRunAll, cr.ForceOwnership, cr.FieldOwner(diffFieldManager)); err != nil {
+		return "", fmt.Errorf("resources: diff: dry-run apply %s: %w", merged.GetName(), err)
+	}
+	mergedYAML, err := diffableYAML(merged)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(liveYAML),
+		B:        difflib.SplitLines(mergedYAML),
+		FromFile: "live",
+		ToFile:   "merged",
+		Context:  3,
+	})
+}
+
+// SnapshotYAML fetches the live object and renders it as YAML without the managedFields and resourceVersion
+// metadata, the way Diff does, so that the snapshots of an object taken at different times can be compared to see
+// what changed in between. Only the kind, name and namespace of obj are used, an empty string is returned when the
+// object does not exist.
+func (r *Resources) SnapshotYAML(ctx context.Context, obj k8s.Object) (string, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return "", err
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), live); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("resources: snapshot %s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	return diffableYAML(live)
+}
+
+// diffableYAML renders the object as YAML without the metadata fields that change on every write
+func diffableYAML(obj *unstructured.Unstructured) (string, error) {
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	out, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// DumpClusterState writes the state of the cluster to dir in order to produce a self-contained bundle that
+// can be attached to the CI artifacts to investigate a failure. For each namespace, all the resources are
+// written as YAML to a file per resource type, the logs of the containers of the pods that have been started
+// are written to a file per container under the logs directory and the events are written to an events.txt file ordered
+// by time. The nodes of the cluster are written to nodes.yaml. If no namespace is provided, the state of
+// all the namespaces is dumped.
+//
+// Dumping continues when the state of some of the resources cannot be collected and the errors are
+// returned aggregated once the dump has completed.
+func (r *Resources) DumpClusterState(ctx context.Context, dir string, namespaces ...string) error {
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(r.config)
+	if err != nil {
+		return err
+	}
+
+	if len(namespaces) == 0 {
+		nsList, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("resources: dump: list namespaces: %w", err)
+		}
+		for _, ns := range nsList.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	var errs []error
+	nodes, err := dynamicClient.Resource(v1.SchemeGroupVersion.WithResource("nodes")).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("list nodes: %w", err))
+	} else if err := writeObjectsYAML(filepath.Join(dir, "nodes.yaml"), nodes.Items); err != nil {
+		errs = append(errs, err)
+	}
+
+	resourceTypes, err := listableNamespacedResources(clientset.Discovery())
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, ns := range namespaces {
+		nsDir := filepath.Join(dir, ns)
+		for _, gvr := range resourceTypes {
+			list, err := dynamicClient.Resource(gvr).Namespace(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("list %s in namespace %s: %w", gvr.String(), ns, err))
+				continue
+			}
+			if len(list.Items) == 0 {
+				continue
+			}
+			name := gvr.Resource
+			if gvr.Group != "" {
+				name = fmt.Sprintf("%s.%s", gvr.Resource, gvr.Group)
+			}
+			if err := writeObjectsYAML(filepath.Join(nsDir, name+".yaml"), list.Items); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		errs = append(errs, dumpPodLogs(ctx, clientset, ns, filepath.Join(nsDir, "logs"))...)
+		if err := dumpEvents(ctx, clientset, ns, filepath.Join(nsDir, "events.txt")); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// listableNamespacedResources returns the preferred version of all the namespaced resource types that can be
+// listed, except for the events that are dumped separately. Resource types of API groups that could not be
+// discovered are skipped.
+func listableNamespacedResources(client discovery.DiscoveryInterface) ([]schema.GroupVersionResource, error) {
+	lists, err := client.ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("discover resource types: %w", err)
+	}
+	var result []schema.GroupVersionResource
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, res := range list.APIResources {
+			if res.Kind == "Event" || !sets.New(res.Verbs...).Has("list") {
+				continue
+			}
+			result = append(result, gv.WithResource(res.Name))
+		}
+	}
+	return result, nil
+}
+
+func writeObjectsYAML(path string, objs []unstructured.Unstructured) error {
+	var buf bytes.Buffer
+	for _, obj := range objs {
+		obj.SetManagedFields(nil)
+		out, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("---\n")
+		buf.Write(out)
+	}
+	return writeDumpFile(path, buf.Bytes())
+}
+
+func dumpPodLogs(ctx context.Context, clientset kubernetes.Interface, namespace, dir string) []error {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return []error{fmt.Errorf("list pods in namespace %s: %w", namespace, err)}
+	}
+	var errs []error
+	for _, pod := range pods.Items {
+		for _, container := range startedContainers(pod) {
+			stream, err := clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &v1.PodLogOptions{Container: container}).Stream(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("logs of container %s of pod %s/%s: %w", container, namespace, pod.Name, err))
+				continue
+			}
+			logs, err := io.ReadAll(stream)
+			stream.Close()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("logs of container %s of pod %s/%s: %w", container, namespace, pod.Name, err))
+				continue
+			}
+			if err := writeDumpFile(filepath.Join(dir, fmt.Sprintf("%s-%s.log", pod.Name, container)), logs); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// startedContainers returns the names of the init and regular containers of the pod that have been started at
+// least once, the other ones having no logs to fetch
+func startedContainers(pod v1.Pod) []string {
+	var names []string
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Running != nil || status.State.Terminated != nil || status.LastTerminationState.Terminated != nil {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
+
+func dumpEvents(ctx context.Context, clientset kubernetes.Interface, namespace, path string) error {
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list events in namespace %s: %w", namespace, err)
+	}
+	if len(events.Items) == 0 {
+		return nil
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+	})
+	var buf bytes.Buffer
+	for _, e := range events.Items {
+		fmt.Fprintf(&buf, "%s\t%s\t%s\t%s/%s\t%s\n", e.LastTimestamp.UTC().Format("2006-01-02T15:04:05Z"), e.Type, e.Reason, e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Message)
+	}
+	return writeDumpFile(path, buf.Bytes())
+}
+
+func writeDumpFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDumpPodLogs(t *testing.T) {
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	waiting := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "PodInitializing"}}
+	clientset := fake.NewSimpleClientset(
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "started", Namespace: "default"},
+			Status: v1.PodStatus{
+				InitContainerStatuses: []v1.ContainerStatus{{Name: "init", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}}}},
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "app", State: running},
+					{Name: "crashing", State: waiting, LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}},
+				},
+			},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "initializing", Namespace: "default"},
+			Status: v1.PodStatus{
+				InitContainerStatuses: []v1.ContainerStatus{{Name: "init", State: running}},
+				ContainerStatuses:     []v1.ContainerStatus{{Name: "app", State: waiting}},
+			},
+		},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"}},
+	)
+
+	dir := t.TempDir()
+	if errs := dumpPodLogs(context.TODO(), clientset, "default", dir); len(errs) != 0 {
+		t.Fatalf("expected the logs to be dumped without errors, got: %v", errs)
+	}
+
+	for _, name := range []string{"started-init.log", "started-app.log", "started-crashing.log", "initializing-init.log"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected the logs of the started container to be written to %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"initializing-app.log", "pending-app.log"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected no logs to be written to %s for a container that never started, got: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	log "k8s.io/klog/v2"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+const (
+	// jobPollInterval is the delay between the checks of the conditions of the Job run by RunJobToCompletion
+	jobPollInterval = 2 * time.Second
+	// jobDeleteTimeout bounds the deletion of the Job run by RunJobToCompletion
+	jobDeleteTimeout = 30 * time.Second
+)
+
+// JobFailedError is returned by RunJobToCompletion when the Job fails. It carries the logs of the pods of
+// the Job, so that the cause of the failure can be reported.
+type JobFailedError struct {
+	// Job is the namespaced name of the Job
+	Job types.NamespacedName
+	// Reason is the reason and the message of the Failed condition of the Job
+	Reason string
+	// Logs are the logs of the containers of the pods of the Job
+	Logs string
+}
+
+func (e *JobFailedError) Error() string {
+	return fmt.Sprintf("resources: job %s failed: %s", e.Job, e.Reason)
+}
+
+// RunJobToCompletion creates the Job, waits up to timeout for it to complete or fail and returns the logs of
+// the containers of its pods, which is convenient for one-shot tasks such as a migration or a smoke check
+// run inside the cluster. When the Job fails, the returned error is a *JobFailedError carrying the logs.
+// The Job and its pods are deleted before returning. A timeout of zero uses the default timeout of the
+// klient/wait package, or the deadline of ctx if any, and opts, such as wait.WithJitter, configure the wait
+// further.
+func (r *Resources) RunJobToCompletion(ctx context.Context, job k8s.Object, timeout time.Duration, opts ...wait.Option) (string, error) {
+	name := types.NamespacedName{Namespace: job.GetNamespace(), Name: job.GetName()}
+	if err := r.Create(ctx, job); err != nil {
+		return "", fmt.Errorf("resources: create job %s: %w", name, err)
+	}
+	defer func() {
+		// the Job is also deleted when ctx is done, which is when the wait timed out or was cancelled
+		deleteCtx, cancel := context.WithTimeout(detachedContext{ctx}, jobDeleteTimeout)
+		defer cancel()
+		if err := r.Delete(deleteCtx, job, WithDeletePropagation(string(metav1.DeletePropagationBackground))); cr.IgnoreNotFound(err) != nil {
+			log.ErrorS(err, "failed to delete job", "job", name)
+		}
+	}()
+
+	var current batchv1.Job
+	var failed *batchv1.JobCondition
+	err := wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+		if err := r.Get(ctx, name.Name, name.Namespace, &current); err != nil {
+			return false, err
+		}
+		for i, cond := range current.Status.Conditions {
+			if cond.Status != v1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				failed = &current.Status.Conditions[i]
+				return true, nil
+			}
+		}
+		return false, nil
+	}, waitOptions(jobPollInterval, timeout, opts)...)
+
+	logs, logsErr := r.jobLogs(ctx, &current)
+	if err != nil {
+		return logs, fmt.Errorf("resources: waiting for job %s to complete: %w", name, err)
+	}
+	if failed != nil {
+		reason := failed.Reason
+		if failed.Message != "" {
+			reason = fmt.Sprintf("%s: %s", failed.Reason, failed.Message)
+		}
+		return logs, &JobFailedError{Job: name, Reason: reason, Logs: logs}
+	}
+	if logsErr != nil {
+		return logs, fmt.Errorf("resources: logs of job %s: %w", name, logsErr)
+	}
+	return logs, nil
+}
+
+// jobLogs returns the logs of the containers of the pods selected by the Job, oldest pod first. The logs of
+// each container are preceded by a header naming the pod and the container when there is more than one.
+func (r *Resources) jobLogs(ctx context.Context, job *batchv1.Job) (string, error) {
+	if job.Spec.Selector == nil {
+		return "", nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return "", err
+	}
+	var pods v1.PodList
+	if err := r.client.List(ctx, &pods, cr.InNamespace(job.Namespace), cr.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return "", err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return "", err
+	}
+	headers := len(pods.Items) > 1 || (len(pods.Items) == 1 && len(pods.Items[0].Spec.Containers) > 1)
+	var logs strings.Builder
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			data, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &v1.PodLogOptions{Container: container.Name}).DoRaw(ctx)
+			if err != nil {
+				return logs.String(), fmt.Errorf("container %s of pod %s: %w", container.Name, pod.Name, err)
+			}
+			if headers {
+				fmt.Fprintf(&logs, "==> pod %s, container %s <==\n", pod.Name, container.Name)
+			}
+			logs.Write(data)
+		}
+	}
+	return logs.String(), nil
+}
+
+// detachedContext keeps the values of the wrapped context while ignoring its cancellation, so that the Job can
+// still be deleted once the context used to wait for it is done.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detachedContext) Done() <-chan struct{}             { return nil }
+func (d detachedContext) Err() error                        { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRunJobToCompletion_WaitTimeout(t *testing.T) {
+	// the fake client does not honor the context, the deletion fails like a request sent with a done context
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Delete: func(ctx context.Context, client cr.WithWatch, obj cr.Object, opts ...cr.DeleteOption) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return client.Delete(ctx, obj, opts...)
+		},
+	}).Build()
+	res, err := New(&rest.Config{}, WithClient(client))
+	if err != nil {
+		t.Fatalf("unexpected error creating resources: %s", err)
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "never-completes", Namespace: "default"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := res.RunJobToCompletion(ctx, job, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to stop at the deadline of the context, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the deadline of the context to bound the wait, took %s", elapsed)
+	}
+	if err := res.Get(context.TODO(), job.Name, job.Namespace, &batchv1.Job{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the job to be deleted once the wait timed out, got: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// ListAcrossNamespaces lists the objects of each of the namespaces and aggregates them into objList.
+// The list options, such as label and field selectors, are applied to each namespace. When a limit is
+// set, every namespace is paginated separately until all its objects have been retrieved, so the limit
+// only bounds the size of the individual requests and not the size of the aggregated list.
+func (r *Resources) ListAcrossNamespaces(ctx context.Context, objList k8s.ObjectList, namespaces []string, opts ...ListOption) error {
+	var items []runtime.Object
+	for _, ns := range namespaces {
+		o, err := newListOptions(opts...)
+		if err != nil {
+			return err
+		}
+		o.Namespace = ns
+		for {
+			page, ok := objList.DeepCopyObject().(k8s.ObjectList)
+			if !ok {
+				return fmt.Errorf("resources: unexpected list type %T", objList)
+			}
+			if err := r.client.List(ctx, page, o); err != nil {
+				return fmt.Errorf("resources: list namespace %s: %w", ns, err)
+			}
+			pageItems, err := meta.ExtractList(page)
+			if err != nil {
+				return err
+			}
+			items = append(items, pageItems...)
+			if page.GetContinue() == "" {
+				break
+			}
+			o.Continue = page.GetContinue()
+			o.Raw.Continue = page.GetContinue()
+		}
+	}
+	objList.SetContinue("")
+	return meta.SetList(objList, items)
+}
+
+// ListTyped lists the objects of type T, e.g. ListTyped[corev1.Pod](ctx, r), and returns them as typed
+// objects. The objects are listed as unstructured objects, which also works for the custom resources that
+// are registered in the scheme without a list type, and converted to T using the scheme. The conversion of
+// every item must succeed, an item that cannot be converted fails the whole list.
+func ListTyped[T any, PT interface {
+	*T
+	k8s.Object
+}](ctx context.Context, r *Resources, opts ...ListOption) ([]T, error) {
+	gvk, err := apiutil.GVKForObject(PT(new(T)), r.scheme)
+	if err != nil {
+		return nil, fmt.Errorf("resources: list %T: %w", new(T), err)
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, list, opts...); err != nil {
+		return nil, err
+	}
+
+	items := make([]T, 0, len(list.Items))
+	for i := range list.Items {
+		var item T
+		if err := r.scheme.Convert(&list.Items[i], PT(&item), nil); err != nil {
+			return nil, fmt.Errorf("resources: convert %s %s/%s to %T: %w", gvk.Kind, list.Items[i].GetNamespace(), list.Items[i].GetName(), &item, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
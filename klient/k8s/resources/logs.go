@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+// logStreamRetryInterval is the delay before the log stream is opened again when the
+// container is not started yet or when the stream ended without a matching line.
+const logStreamRetryInterval = time.Second
+
+// WaitForLogLine follows the logs of the container of the pod until a line containing substring is
+// seen or the timeout expires. This is useful to wait for applications that print a readiness
+// signal without exposing it through a probe. The log stream is opened again if the container has
+// not started yet or restarts, and it is closed as soon as ctx is cancelled. A timeout of zero uses
+// the default timeout of the klient/wait package, or the deadline of ctx if any, and opts, such as
+// wait.WithJitter, configure the wait further.
+func (r *Resources) WaitForLogLine(ctx context.Context, pod *v1.Pod, container, substring string, timeout time.Duration, opts ...wait.Option) error {
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	err = wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+		found, err := followLogsFor(ctx, clientset, pod, container, substring)
+		if err != nil && ctx.Err() == nil {
+			lastErr = err
+		}
+		return found, nil
+	}, waitOptions(logStreamRetryInterval, timeout, opts)...)
+	if err == nil {
+		return nil
+	}
+	if lastErr != nil {
+		return fmt.Errorf("resources: log line %q not found in container %s of pod %s/%s: %w (last error: %s)", substring, container, pod.Namespace, pod.Name, err, lastErr)
+	}
+	return fmt.Errorf("resources: log line %q not found in container %s of pod %s/%s: %w", substring, container, pod.Namespace, pod.Name, err)
+}
+
+// followLogsFor streams the logs of the container and reports whether a line containing substring
+// was seen. The stream is bound to ctx so reading from it stops once ctx is done.
+func followLogsFor(ctx context.Context, clientset kubernetes.Interface, pod *v1.Pod, container, substring string) (bool, error) {
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &v1.PodLogOptions{Container: container, Follow: true}).Stream(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), substring) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+const namespaceDeletionInterval = time.Second
+
+// WaitForNamespaceDeletion waits until the namespace is gone. A namespace containing resources guarded by
+// finalizers that are never removed stays Terminating, so when the namespace is still present once timeout
+// has elapsed, the returned error lists the resources remaining in the namespace with their finalizers along
+// with the deletion conditions reported by the namespace, to make it clear what is blocking the deletion.
+// A timeout of zero uses the default timeout of the klient/wait package, or the deadline of ctx if any, and
+// opts, such as wait.WithJitter, configure the wait further. The wait stops as soon as the namespace cannot
+// be retrieved for another reason than it being gone, for instance when access is forbidden, and the error
+// is returned.
+func (r *Resources) WaitForNamespaceDeletion(ctx context.Context, name string, timeout time.Duration, opts ...wait.Option) error {
+	ns := &v1.Namespace{}
+	var getErr error
+	err := wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+		if getErr = r.Get(ctx, name, "", ns); getErr != nil {
+			if apierrors.IsNotFound(getErr) {
+				return true, nil
+			}
+			return false, getErr
+		}
+		return false, nil
+	}, waitOptions(namespaceDeletionInterval, timeout, opts)...)
+	if err == nil {
+		return nil
+	}
+	if getErr != nil && !errors.Is(getErr, context.DeadlineExceeded) && !errors.Is(getErr, context.Canceled) {
+		return fmt.Errorf("resources: waiting for namespace %s to be deleted: %w", name, err)
+	}
+
+	var details []string
+	for _, cond := range ns.Status.Conditions {
+		if cond.Status == v1.ConditionTrue && cond.Message != "" {
+			details = append(details, fmt.Sprintf("%s: %s", cond.Type, cond.Message))
+		}
+	}
+	// the context may be done already, the remaining resources are listed using a fresh one
+	listCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	remaining, listErr := r.resourcesWithFinalizers(listCtx, name)
+	if listErr != nil {
+		details = append(details, fmt.Sprintf("failed to list remaining resources: %s", listErr))
+	}
+	for _, obj := range remaining {
+		details = append(details, fmt.Sprintf("%s %s has finalizers %s", obj.GetKind(), obj.GetName(), strings.Join(obj.GetFinalizers(), ", ")))
+	}
+	if len(details) == 0 {
+		return fmt.Errorf("resources: namespace %s was not deleted: %w", name, err)
+	}
+	return fmt.Errorf("resources: namespace %s was not deleted: %w:\n  %s", name, err, strings.Join(details, "\n  "))
+}
+
+// ForceFinalizeNamespace removes the provided finalizers, typically the ones added by the tests or by the
+// controllers under test, from all the resources of the namespace so that a namespace stuck terminating can
+// be deleted, for instance when the controller responsible for the finalizers has already been uninstalled.
+// The other finalizers are left in place. Resources that cannot be updated do not stop the processing of
+// the remaining ones, the errors are returned aggregated.
+func (r *Resources) ForceFinalizeNamespace(ctx context.Context, name string, finalizers ...string) error {
+	if len(finalizers) == 0 {
+		return fmt.Errorf("resources: force finalize namespace %s: no finalizers provided", name)
+	}
+	dynamicClient, err := dynamic.NewForConfig(r.config)
+	if err != nil {
+		return err
+	}
+	remaining, err := r.resourcesWithFinalizers(ctx, name)
+	if err != nil {
+		return fmt.Errorf("resources: force finalize namespace %s: %w", name, err)
+	}
+
+	removed := sets.New(finalizers...)
+	var errs []error
+	for _, obj := range remaining {
+		var kept []string
+		for _, f := range obj.GetFinalizers() {
+			if !removed.Has(f) {
+				kept = append(kept, f)
+			}
+		}
+		if len(kept) == len(obj.GetFinalizers()) {
+			continue
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"finalizers": kept, "resourceVersion": obj.GetResourceVersion()},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := dynamicClient.Resource(obj.gvr).Namespace(name).Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("remove finalizers of %s %s: %w", obj.GetKind(), obj.GetName(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("resources: force finalize namespace %s: %w", name, utilerrors.NewAggregate(errs))
+	}
+	return nil
+}
+
+// finalizedObject is an object with finalizers along with the resource type it was listed from
+type finalizedObject struct {
+	unstructured.Unstructured
+	gvr schema.GroupVersionResource
+}
+
+// resourcesWithFinalizers lists the resources of the namespace which have finalizers. Resource types that
+// cannot be listed are skipped.
+func (r *Resources) resourcesWithFinalizers(ctx context.Context, namespace string) ([]finalizedObject, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(r.config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(r.config)
+	if err != nil {
+		return nil, err
+	}
+	resourceTypes, err := listableNamespacedResources(discoveryClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []finalizedObject
+	for _, gvr := range resourceTypes {
+		list, err := dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			continue
+		}
+		for _, obj := range list.Items {
+			if len(obj.GetFinalizers()) > 0 {
+				result = append(result, finalizedObject{Unstructured: obj, gvr: gvr})
+			}
+		}
+	}
+	return result, nil
+}
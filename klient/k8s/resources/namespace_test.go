@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestWaitForNamespaceDeletion_GetError(t *testing.T) {
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "forbidden"}}
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ns).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client cr.WithWatch, key cr.ObjectKey, obj cr.Object, opts ...cr.GetOption) error {
+			return apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, key.Name, nil)
+		},
+	}).Build()
+	res, err := New(&rest.Config{}, WithClient(client))
+	if err != nil {
+		t.Fatalf("unexpected error creating resources: %s", err)
+	}
+
+	start := time.Now()
+	err = res.WaitForNamespaceDeletion(context.TODO(), ns.Name, time.Minute)
+	if !apierrors.IsForbidden(err) {
+		t.Fatalf("expected the forbidden error to be returned, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the wait to stop on the error, took %s", elapsed)
+	}
+}
+
+func TestWaitForNamespaceDeletion_NotFound(t *testing.T) {
+	res, err := New(&rest.Config{}, WithClient(fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()))
+	if err != nil {
+		t.Fatalf("unexpected error creating resources: %s", err)
+	}
+	if err := res.WaitForNamespaceDeletion(context.TODO(), "gone", time.Minute); err != nil {
+		t.Fatalf("expected a missing namespace to be reported as deleted, got: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// NodeAllocatable returns the CPU and memory allocatable to pods, summed across the nodes that are Ready and not
+// cordoned. This is the room the scheduler has on the cluster before accounting for the requests of the pods
+// already running, and helps to tell whether a workload can fit before creating it, for instance when sizing the
+// fake nodes of scale tests.
+func (r *Resources) NodeAllocatable(ctx context.Context) (cpu, mem resource.Quantity, err error) {
+	var nodes v1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return cpu, mem, err
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Spec.Unschedulable || !nodeReady(node) {
+			continue
+		}
+		cpu.Add(node.Status.Allocatable[v1.ResourceCPU])
+		mem.Add(node.Status.Allocatable[v1.ResourceMemory])
+	}
+	return cpu, mem, nil
+}
+
+// nodeReady reports whether the node has the Ready condition set to true
+func nodeReady(node *v1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == v1.NodeReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const oomKilledReason = "OOMKilled"
+
+// OOMKills returns the containers of the pods of the namespace which were killed for running out of memory
+// since the provided time, as "<pod>/<container>" along with the time of the kill. Both the current state and
+// the last terminated state of the containers are looked at, since a restarted container only keeps its last
+// termination. A zero since reports all the kills still recorded by the pods.
+func (r *Resources) OOMKills(ctx context.Context, namespace string, since time.Time) ([]string, error) {
+	var pods v1.PodList
+	if err := r.client.List(ctx, &pods, cr.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("resources: list pods of namespace %s: %w", namespace, err)
+	}
+	var kills []string
+	for _, pod := range pods.Items {
+		statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			for _, terminated := range []*v1.ContainerStateTerminated{cs.State.Terminated, cs.LastTerminationState.Terminated} {
+				if terminated == nil || terminated.Reason != oomKilledReason || terminated.FinishedAt.Time.Before(since) {
+					continue
+				}
+				kills = append(kills, fmt.Sprintf("%s/%s at %s", pod.Name, cs.Name, terminated.FinishedAt.Format(time.RFC3339)))
+			}
+		}
+	}
+	return kills, nil
+}
+
+// AssertNoOOMKills checks that none of the containers of the pods of the namespace was killed for running out of
+// memory, which a wait for the pods to be ready can miss when a container is restarted in time. The returned
+// error lists the containers which were killed.
+func (r *Resources) AssertNoOOMKills(ctx context.Context, namespace string) error {
+	kills, err := r.OOMKills(ctx, namespace, time.Time{})
+	if err != nil {
+		return err
+	}
+	if len(kills) > 0 {
+		return fmt.Errorf("resources: containers of namespace %s were OOMKilled: %s", namespace, strings.Join(kills, ", "))
+	}
+	return nil
+}
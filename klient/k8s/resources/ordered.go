@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+const (
+	crdKind                 = "CustomResourceDefinition"
+	crdEstablishedInterval  = time.Second
+	crdEstablishedCondition = "Established"
+)
+
+var crdGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: crdKind}
+
+// createOrder is the order in which the resources are created by CreateAllOrdered. Namespaces and
+// CustomResourceDefinitions are created first so that the resources depending on them can be created.
+// Kinds that are not part of this list, such as custom resources, are created last.
+var createOrder = []string{
+	"Namespace",
+	crdKind,
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+// CreateAllOrdered creates all the objects in an order that satisfies the dependencies between their kinds.
+// Namespaces and CustomResourceDefinitions are created first and the CustomResourceDefinitions are waited
+// upon until they are Established before any of the remaining objects are created. This makes it possible
+// to create a whole bundle of resources, such as the CRDs of an operator along with instances of them, in
+// a single call. The relative order of objects of the same kind is preserved.
+func (r *Resources) CreateAllOrdered(ctx context.Context, objs []k8s.Object, opts ...CreateOption) error {
+	ordered := make([]k8s.Object, len(objs))
+	copy(ordered, objs)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return r.createPriority(ordered[i]) < r.createPriority(ordered[j])
+	})
+
+	var pendingCRDs []string
+	for _, obj := range ordered {
+		kind := r.kindOf(obj)
+		if kind != crdKind && len(pendingCRDs) > 0 {
+			if err := r.waitForCRDsEstablished(ctx, pendingCRDs); err != nil {
+				return err
+			}
+			pendingCRDs = nil
+		}
+		if err := r.Create(ctx, obj, opts...); err != nil {
+			return fmt.Errorf("resources: create %s %s: %w", kind, obj.GetName(), err)
+		}
+		if kind == crdKind {
+			pendingCRDs = append(pendingCRDs, obj.GetName())
+		}
+	}
+	if len(pendingCRDs) > 0 {
+		return r.waitForCRDsEstablished(ctx, pendingCRDs)
+	}
+	return nil
+}
+
+// kindOf returns the Kind of the object, falling back to the scheme when the object is
+// a typed object that does not carry its own type information.
+func (r *Resources) kindOf(obj k8s.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return ""
+	}
+	return gvk.Kind
+}
+
+func (r *Resources) createPriority(obj k8s.Object) int {
+	kind := r.kindOf(obj)
+	for i, k := range createOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(createOrder)
+}
+
+// waitForCRDsEstablished waits until all the named CustomResourceDefinitions have reached the Established condition,
+// for up to the default timeout of the klient/wait package or the deadline of ctx
+func (r *Resources) waitForCRDsEstablished(ctx context.Context, names []string) error {
+	for _, name := range names {
+		err := wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+			crd := &unstructured.Unstructured{}
+			crd.SetGroupVersionKind(crdGVK)
+			if err := r.Get(ctx, name, "", crd); err != nil {
+				return false, nil
+			}
+			conditions, _, err := unstructured.NestedSlice(crd.Object, "status", "conditions")
+			if err != nil {
+				return false, err
+			}
+			for _, c := range conditions {
+				cond, ok := c.(map[string]interface{})
+				if ok && cond["type"] == crdEstablishedCondition && cond["status"] == "True" {
+					return true, nil
+				}
+			}
+			return false, nil
+		}, waitOptions(crdEstablishedInterval, 0, nil)...)
+		if err != nil {
+			return fmt.Errorf("resources: waiting for CustomResourceDefinition %s to be established: %w", name, err)
+		}
+	}
+	return nil
+}
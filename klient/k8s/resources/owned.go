@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// CreateOwnedBy creates the object with an owner reference to owner, so that the object is garbage collected
+// by Kubernetes once owner is deleted. This makes it possible to clean up everything created by a test, including
+// cluster scoped objects, by deleting a single parent such as the namespace of the test.
+//
+// The owner must exist in the cluster as its UID is required. Namespaced owners can only own objects of their own
+// namespace, which is verified using the RESTMapper of the client before the object is created. The reference
+// blocks the foreground deletion of the owner until the object is deleted but is not a controller reference, so
+// the object can still be adopted by its actual controller.
+func (r *Resources) CreateOwnedBy(ctx context.Context, obj, owner k8s.Object, opts ...CreateOption) error {
+	if owner.GetUID() == "" {
+		return fmt.Errorf("resources: owner %s has no UID, it must be created first", owner.GetName())
+	}
+	ownerGVK, err := r.client.GroupVersionKindFor(owner)
+	if err != nil {
+		return fmt.Errorf("resources: owner %s: %w", owner.GetName(), err)
+	}
+	ownerNamespaced, err := r.client.IsObjectNamespaced(owner)
+	if err != nil {
+		return fmt.Errorf("resources: owner %s: %w", owner.GetName(), err)
+	}
+	r.applyDefaultNamespace(obj)
+	objNamespaced, err := r.client.IsObjectNamespaced(obj)
+	if err != nil {
+		return fmt.Errorf("resources: %s %s: %w", r.kindOf(obj), obj.GetName(), err)
+	}
+	if ownerNamespaced {
+		if !objNamespaced {
+			return fmt.Errorf("resources: cluster scoped %s %s cannot be owned by namespaced %s %s",
+				r.kindOf(obj), obj.GetName(), ownerGVK.Kind, owner.GetName())
+		}
+		if obj.GetNamespace() != owner.GetNamespace() {
+			return fmt.Errorf("resources: %s %s in namespace %s cannot be owned by %s %s in namespace %s",
+				r.kindOf(obj), obj.GetName(), obj.GetNamespace(), ownerGVK.Kind, owner.GetName(), owner.GetNamespace())
+		}
+	}
+
+	controller, blockOwnerDeletion := false, true
+	ref := metav1.OwnerReference{
+		APIVersion:         ownerGVK.GroupVersion().String(),
+		Kind:               ownerGVK.Kind,
+		Name:               owner.GetName(),
+		UID:                owner.GetUID(),
+		Controller:         &controller,
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}
+	refs := []metav1.OwnerReference{ref}
+	for _, existing := range obj.GetOwnerReferences() {
+		if existing.UID != ref.UID {
+			refs = append(refs, existing)
+		}
+	}
+	obj.SetOwnerReferences(refs)
+	return r.Create(ctx, obj, opts...)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// FetchHTTPFromPod port-forwards a local port to the port of the pod and issues an HTTP GET request for path,
+// returning the body of the response. This gives access to the endpoints a pod doesn't expose through a
+// Service, such as /debug/pprof/heap, /metrics or /healthz, for instance to profile a controller during a test.
+// The port-forward is closed once the response is read, and both the port-forward and the request are bound
+// to ctx. A response with a status other than 2xx is returned along with an error reporting the status.
+func (r *Resources) FetchHTTPFromPod(ctx context.Context, pod k8s.Object, port int, path string) ([]byte, error) {
+	namespace := pod.GetNamespace()
+	if namespace == "" {
+		namespace = r.defaultNamespace
+	}
+	name := types.NamespacedName{Namespace: namespace, Name: pod.GetName()}
+
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return nil, err
+	}
+	transport, upgrader, err := spdy.RoundTripperFor(r.config)
+	if err != nil {
+		return nil, err
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod.GetName()).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	stopCh, readyCh := make(chan struct{}), make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("resources: port-forward to pod %s: %w", name, err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- forwarder.ForwardPorts() }()
+	defer close(stopCh)
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return nil, fmt.Errorf("resources: port-forward to pod %s: %w", name, err)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("resources: port-forward to pod %s: %w", name, ctx.Err())
+	}
+	ports, err := forwarder.GetPorts()
+	if err != nil || len(ports) == 0 {
+		return nil, fmt.Errorf("resources: port-forward to pod %s: no local port: %v", name, err)
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/%s", ports[0].Local, strings.TrimPrefix(path, "/"))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("resources: GET %s from pod %s port %d: %w", path, name, port, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("resources: GET %s from pod %s port %d: %w", path, name, port, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return body, fmt.Errorf("resources: GET %s from pod %s port %d: %s", path, name, port, resp.Status)
+	}
+	return body, nil
+}
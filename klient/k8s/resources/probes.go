@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// ProbeStatus gathers the probe related status of a container. Kubernetes doesn't expose the raw results of the
+// probes, so this combines the fields of the container status driven by the probes with the events recorded by
+// the kubelet when the probes fail, which are usually what is needed to understand why a pod never becomes Ready.
+type ProbeStatus struct {
+	// Ready reports if the container passed its readiness probe
+	Ready bool
+	// Started reports if the container passed its startup probe. It is nil if the status is not known yet.
+	Started *bool
+	// RestartCount is the number of times the container was restarted, for instance after failing its liveness probe
+	RestartCount int32
+	// State is a readable summary of the current state of the container, such as "Running" or
+	// "Waiting: CrashLoopBackOff"
+	State string
+	// PodReady is the Ready condition of the pod, which carries the reason why the pod is not ready
+	PodReady *v1.PodCondition
+	// ProbeEvents are the messages of the warning events recorded for the probes of the container,
+	// such as "Readiness probe failed: ...", ordered from the oldest to the most recent
+	ProbeEvents []string
+}
+
+// GetPodProbeStatus returns the probe related status of each of the containers of the pod, indexed by container name.
+func (r *Resources) GetPodProbeStatus(ctx context.Context, pod k8s.Object) (map[string]ProbeStatus, error) {
+	var p v1.Pod
+	if err := r.Get(ctx, pod.GetName(), pod.GetNamespace(), &p); err != nil {
+		return nil, err
+	}
+
+	var podReady *v1.PodCondition
+	for i := range p.Status.Conditions {
+		if p.Status.Conditions[i].Type == v1.PodReady {
+			podReady = &p.Status.Conditions[i]
+		}
+	}
+
+	result := make(map[string]ProbeStatus)
+	for _, c := range p.Spec.Containers {
+		result[c.Name] = ProbeStatus{State: "Unknown", PodReady: podReady}
+	}
+	for _, cs := range p.Status.ContainerStatuses {
+		result[cs.Name] = ProbeStatus{
+			Ready:        cs.Ready,
+			Started:      cs.Started,
+			RestartCount: cs.RestartCount,
+			State:        containerState(cs.State),
+			PodReady:     podReady,
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(r.config)
+	if err != nil {
+		return nil, err
+	}
+	selector := fields.Set{"involvedObject.name": p.Name, "involvedObject.uid": string(p.UID), "type": v1.EventTypeWarning}
+	events, err := clientset.CoreV1().Events(p.Namespace).List(ctx, metav1.ListOptions{FieldSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("resources: list events of pod %s: %w", p.Name, err)
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+	})
+	for _, e := range events.Items {
+		if e.Reason != "Unhealthy" && e.Reason != "ProbeWarning" {
+			continue
+		}
+		// the field path of the events recorded for a container is "spec.containers{<name>}"
+		name := strings.TrimSuffix(strings.TrimPrefix(e.InvolvedObject.FieldPath, "spec.containers{"), "}")
+		status, ok := result[name]
+		if !ok {
+			continue
+		}
+		status.ProbeEvents = append(status.ProbeEvents, e.Message)
+		result[name] = status
+	}
+	return result, nil
+}
+
+func containerState(state v1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return "Running"
+	case state.Waiting != nil:
+		return "Waiting: " + state.Waiting.Reason
+	case state.Terminated != nil:
+		return fmt.Sprintf("Terminated: %s (exit code %d)", state.Terminated.Reason, state.Terminated.ExitCode)
+	default:
+		return "Unknown"
+	}
+}
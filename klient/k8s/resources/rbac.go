@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// RBACSubject identifies the ServiceAccount that was granted permissions using CreateRBAC
+// or CreateClusterRBAC. It can be used to issue requests against the cluster as that
+// ServiceAccount, either by impersonating it or by requesting a token for it.
+type RBACSubject struct {
+	ServiceAccount *corev1.ServiceAccount
+	// Username is the name the API server authenticates the ServiceAccount as
+	Username string
+	// Groups are the groups the API server associates to the ServiceAccount
+	Groups []string
+}
+
+// CreateRBAC creates the ServiceAccount, the Role and the RoleBinding granting the Role to the ServiceAccount.
+// The Role and the RoleBinding are created in the namespace of the ServiceAccount when they don't specify one.
+// The RoleRef and the Subjects of the binding are filled in to reference the Role and the ServiceAccount
+// when they are left empty.
+func (r *Resources) CreateRBAC(ctx context.Context, sa *corev1.ServiceAccount, role *rbacv1.Role, binding *rbacv1.RoleBinding) (*RBACSubject, error) {
+	if sa.Namespace == "" {
+		return nil, fmt.Errorf("resources: service account %s must have a namespace", sa.Name)
+	}
+	if role.Namespace == "" {
+		role.Namespace = sa.Namespace
+	}
+	if binding.Namespace == "" {
+		binding.Namespace = sa.Namespace
+	}
+	if binding.RoleRef.Name == "" {
+		binding.RoleRef = rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: role.Name}
+	}
+	if len(binding.Subjects) == 0 {
+		binding.Subjects = []rbacv1.Subject{serviceAccountSubject(sa)}
+	}
+	return r.createRBAC(ctx, sa, role, binding)
+}
+
+// CreateClusterRBAC creates the ServiceAccount, the ClusterRole and the ClusterRoleBinding granting the
+// ClusterRole to the ServiceAccount. The RoleRef and the Subjects of the binding are filled in to reference
+// the ClusterRole and the ServiceAccount when they are left empty.
+func (r *Resources) CreateClusterRBAC(ctx context.Context, sa *corev1.ServiceAccount, role *rbacv1.ClusterRole, binding *rbacv1.ClusterRoleBinding) (*RBACSubject, error) {
+	if sa.Namespace == "" {
+		return nil, fmt.Errorf("resources: service account %s must have a namespace", sa.Name)
+	}
+	if binding.RoleRef.Name == "" {
+		binding.RoleRef = rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: role.Name}
+	}
+	if len(binding.Subjects) == 0 {
+		binding.Subjects = []rbacv1.Subject{serviceAccountSubject(sa)}
+	}
+	return r.createRBAC(ctx, sa, role, binding)
+}
+
+// createRBAC creates the objects in the order they are provided, stopping at the first failure
+func (r *Resources) createRBAC(ctx context.Context, sa *corev1.ServiceAccount, role, binding k8s.Object) (*RBACSubject, error) {
+	for _, obj := range []k8s.Object{sa, role, binding} {
+		if err := r.Create(ctx, obj); err != nil {
+			return nil, fmt.Errorf("resources: create %s %s: %w", r.kindOf(obj), obj.GetName(), err)
+		}
+	}
+	return newRBACSubject(sa), nil
+}
+
+func serviceAccountSubject(sa *corev1.ServiceAccount) rbacv1.Subject {
+	return rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: sa.Name, Namespace: sa.Namespace}
+}
+
+func newRBACSubject(sa *corev1.ServiceAccount) *RBACSubject {
+	return &RBACSubject{
+		ServiceAccount: sa,
+		Username:       fmt.Sprintf("system:serviceaccount:%s:%s", sa.Namespace, sa.Name),
+		Groups:         []string{"system:serviceaccounts", "system:serviceaccounts:" + sa.Namespace, "system:authenticated"},
+	}
+}
+
+// ImpersonationConfig returns the configuration to impersonate the ServiceAccount
+func (s *RBACSubject) ImpersonationConfig() rest.ImpersonationConfig {
+	return rest.ImpersonationConfig{UserName: s.Username, Groups: s.Groups}
+}
+
+// Config returns a copy of base that impersonates the ServiceAccount. The result can be used with New to
+// get a Resources issuing all its requests as the ServiceAccount.
+func (s *RBACSubject) Config(base *rest.Config) *rest.Config {
+	cfg := rest.CopyConfig(base)
+	cfg.Impersonate = s.ImpersonationConfig()
+	return cfg
+}
+
+// Token requests a token for the ServiceAccount through the TokenRequest API using the configuration of r.
+// The token expires after the provided duration, which the API server may extend to its minimum of 10 minutes.
+func (s *RBACSubject) Token(ctx context.Context, r *Resources, expiration time.Duration) (string, error) {
+	clientset, err := kubernetes.NewForConfig(r.GetConfig())
+	if err != nil {
+		return "", err
+	}
+	seconds := int64(expiration.Seconds())
+	req := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds}}
+	resp, err := clientset.CoreV1().ServiceAccounts(s.ServiceAccount.Namespace).CreateToken(ctx, s.ServiceAccount.Name, req, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("resources: request token for service account %s: %w", s.ServiceAccount.Name, err)
+	}
+	return resp.Status.Token, nil
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -31,7 +32,9 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	cr "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -51,31 +54,136 @@ type Resources struct {
 
 	// namespace for namespaced object requests
 	namespace string
+
+	// defaultNamespace is applied to the namespaced objects without a namespace, see WithDefaultNamespace
+	defaultNamespace string
+
+	// cacheCtx is the context bounding the lifetime of the cache enabled using WithCache
+	cacheCtx context.Context
+
+	// cache serves the reads of client when enabled using WithCache
+	cache cache.Cache
+
+	// direct is a client reading from the API server, used by Uncached when a cache is enabled
+	direct cr.Client
+}
+
+// Option is used to customize the Resources created by New
+type Option func(*Resources)
+
+// WithDefaultNamespace sets the default namespace of the Resources. The default namespace is used by List,
+// unless a namespace is set using the WithNamespace method, and applied to namespaced objects that have no
+// namespace set, so that code scoped to a single namespace doesn't have to repeat it. The namespace explicitly
+// set on an object always takes precedence.
+func WithDefaultNamespace(ns string) Option {
+	return func(r *Resources) { r.defaultNamespace = ns }
+}
+
+// WithCache makes the Resources serve Get and List from a local cache fed by watches, the way controllers do,
+// which cuts the load on the API server in features making many reads. The informer of a kind is started on
+// the first read of the kind, which waits for the informer to be synced, and runs until ctx is done.
+//
+// The cache is eventually consistent: the reads reflect the state of the cluster as of the last watch event
+// received, so an object that was just created, updated or deleted can be read in its previous state for a
+// short while. Writes always go to the API server, as do the reads of unstructured objects. Use Uncached for a
+// strongly consistent read, and for the lists filtered using a field selector, which the cache cannot serve.
+//
+// Most of the conditions of the wait/conditions package return the error of the Get of the object they check, so
+// a wait started right after creating the object can fail at once with a NotFound error as the cache has not
+// seen the object yet. Pass Uncached() to conditions.New to wait on objects that were just created.
+func WithCache(ctx context.Context) Option {
+	return func(r *Resources) { r.cacheCtx = ctx }
+}
+
+// WithClient makes the Resources use client instead of a client created from the rest.Config, for
+// instance the fake client of controller runtime to unit test code using the Resources.
+func WithClient(client cr.Client) Option {
+	return func(r *Resources) { r.client = client }
 }
 
 // New instantiates the controller runtime client
 // object. User can get panic for belopw scenarios.
 // 1. if user does not provide k8s config
 // 2. if controller runtime client instantiation fails.
-func New(cfg *rest.Config) (*Resources, error) {
+func New(cfg *rest.Config, opts ...Option) (*Resources, error) {
 	if cfg == nil {
 		return nil, errors.New("must provide rest.Config")
 	}
 
-	cl, err := cr.New(cfg, cr.Options{Scheme: scheme.Scheme})
-	if err != nil {
-		return nil, err
-	}
-
 	res := &Resources{
 		config: cfg,
 		scheme: scheme.Scheme,
-		client: cl,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	if res.client == nil {
+		cl, err := cr.New(cfg, cr.Options{Scheme: scheme.Scheme})
+		if err != nil {
+			return nil, err
+		}
+		res.client = cl
 	}
 
+	if res.cacheCtx != nil {
+		if err := res.startCache(); err != nil {
+			return nil, err
+		}
+	}
 	return res, nil
 }
 
+// startCache starts the cache enabled using WithCache and makes the client read from it
+func (r *Resources) startCache() error {
+	c, err := cache.New(r.config, cache.Options{Scheme: r.scheme})
+	if err != nil {
+		return err
+	}
+	cl, err := cr.New(r.config, cr.Options{Scheme: r.scheme, Cache: &cr.CacheOptions{Reader: c}})
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := c.Start(r.cacheCtx); err != nil {
+			klog.ErrorS(err, "Resources cache stopped")
+		}
+	}()
+	// no informer is running yet, this returns as soon as the cache is started
+	if !c.WaitForCacheSync(r.cacheCtx) {
+		return fmt.Errorf("resources: cache not started: %w", r.cacheCtx.Err())
+	}
+	r.cache, r.direct, r.client = c, r.client, cl
+	return nil
+}
+
+// WaitForCacheSync waits for the informers of the cache enabled using WithCache to be synced, which is the
+// case once the cache reflects the state of the objects of the kinds read so far. This returns right away
+// when no cache is enabled.
+func (r *Resources) WaitForCacheSync(ctx context.Context) error {
+	if r.cache == nil {
+		return nil
+	}
+	if !r.cache.WaitForCacheSync(ctx) {
+		return fmt.Errorf("resources: cache not synced: %w", ctx.Err())
+	}
+	return nil
+}
+
+// Uncached returns a copy of the Resources reading from the API server rather than from the cache enabled
+// using WithCache, for the reads that have to be strongly consistent. The Resources are returned as is when
+// no cache is enabled.
+func (r *Resources) Uncached() *Resources {
+	if r.direct == nil {
+		return r
+	}
+	uncached := *r
+	uncached.client = r.direct
+	uncached.cache = nil
+	uncached.direct = nil
+	return &uncached
+}
+
 // GetConfig hepls to get config type *rest.Config
 func (r *Resources) GetConfig() *rest.Config {
 	return r.config
@@ -86,13 +194,34 @@ func (r *Resources) WithNamespace(ns string) *Resources {
 	return r
 }
 
+// applyDefaultNamespace sets the default namespace on the object if it is namespaced and has no namespace set
+func (r *Resources) applyDefaultNamespace(obj k8s.Object) {
+	if obj.GetNamespace() == "" && r.usesDefaultNamespace(obj) {
+		obj.SetNamespace(r.defaultNamespace)
+	}
+}
+
+// usesDefaultNamespace reports if a default namespace is set using WithDefaultNamespace and applies to the
+// kind of the object
+func (r *Resources) usesDefaultNamespace(obj k8s.Object) bool {
+	if r.defaultNamespace == "" {
+		return false
+	}
+	namespaced, err := r.client.IsObjectNamespaced(obj)
+	return err == nil && namespaced
+}
+
 func (r *Resources) Get(ctx context.Context, name, namespace string, obj k8s.Object) error {
+	if namespace == "" && r.usesDefaultNamespace(obj) {
+		namespace = r.defaultNamespace
+	}
 	return r.client.Get(ctx, cr.ObjectKey{Namespace: namespace, Name: name}, obj)
 }
 
 type CreateOption func(*metav1.CreateOptions)
 
 func (r *Resources) Create(ctx context.Context, obj k8s.Object, opts ...CreateOption) error {
+	r.applyDefaultNamespace(obj)
 	createOptions := &metav1.CreateOptions{}
 	for _, fn := range opts {
 		fn(createOptions)
@@ -110,6 +239,7 @@ func (r *Resources) Create(ctx context.Context, obj k8s.Object, opts ...CreateOp
 type UpdateOption func(*metav1.UpdateOptions)
 
 func (r *Resources) Update(ctx context.Context, obj k8s.Object, opts ...UpdateOption) error {
+	r.applyDefaultNamespace(obj)
 	updateOptions := &metav1.UpdateOptions{}
 	for _, fn := range opts {
 		fn(updateOptions)
@@ -123,8 +253,25 @@ func (r *Resources) Update(ctx context.Context, obj k8s.Object, opts ...UpdateOp
 	return r.client.Update(ctx, obj, o)
 }
 
+// UpdateWithRetryOnConflict updates the object after applying the changes made by mutate, retrying when the
+// update fails with a conflict because the object was modified concurrently, for instance by a controller
+// reconciling it. The object is fetched again and mutate is invoked on it before each attempt, so mutate
+// must apply its changes to obj. Once successful, obj holds the final state of the object.
+func (r *Resources) UpdateWithRetryOnConflict(ctx context.Context, obj k8s.Object, mutate func() error, opts ...UpdateOption) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			return err
+		}
+		if err := mutate(); err != nil {
+			return err
+		}
+		return r.Update(ctx, obj, opts...)
+	})
+}
+
 // UpdateSubresource updates the subresource of the object
 func (r *Resources) UpdateSubresource(ctx context.Context, obj k8s.Object, subresource string, opts ...UpdateOption) error {
+	r.applyDefaultNamespace(obj)
 	updateOptions := &metav1.UpdateOptions{}
 	for _, fn := range opts {
 		fn(updateOptions)
@@ -143,6 +290,7 @@ func (r *Resources) UpdateStatus(ctx context.Context, obj k8s.Object, opts ...Up
 type DeleteOption func(*metav1.DeleteOptions)
 
 func (r *Resources) Delete(ctx context.Context, obj k8s.Object, opts ...DeleteOption) error {
+	r.applyDefaultNamespace(obj)
 	deleteOptions := &metav1.DeleteOptions{}
 	for _, fn := range opts {
 		fn(deleteOptions)
@@ -171,6 +319,21 @@ func WithDeletePropagation(prop string) DeleteOption {
 type ListOption func(*metav1.ListOptions)
 
 func (r *Resources) List(ctx context.Context, objs k8s.ObjectList, opts ...ListOption) error {
+	o, err := newListOptions(opts...)
+	if err != nil {
+		return err
+	}
+	if r.namespace != "" {
+		o.Namespace = r.namespace
+	} else if r.defaultNamespace != "" && o.Namespace == "" {
+		o.Namespace = r.defaultNamespace
+	}
+
+	return r.client.List(ctx, objs, o)
+}
+
+// newListOptions converts the ListOptions into the options of the controller runtime client
+func newListOptions(opts ...ListOption) (*cr.ListOptions, error) {
 	listOptions := &metav1.ListOptions{}
 
 	for _, fn := range opts {
@@ -179,25 +342,21 @@ func (r *Resources) List(ctx context.Context, objs k8s.ObjectList, opts ...ListO
 
 	ls, err := labels.Parse(listOptions.LabelSelector)
 	if err != nil {
-		return err
-	}
-	fs, err := fields.ParseSelector(listOptions.FieldSelector)
-	if err != nil {
-		return err
+		return nil, err
 	}
-
 	o := &cr.ListOptions{
 		Raw:           listOptions,
-		FieldSelector: fs,
 		LabelSelector: ls,
 		Continue:      listOptions.Continue,
 		Limit:         listOptions.Limit,
 	}
-	if r.namespace != "" {
-		o.Namespace = r.namespace
+	// an empty field selector is left unset, as the fake client of controller runtime rejects it
+	if listOptions.FieldSelector != "" {
+		if o.FieldSelector, err = fields.ParseSelector(listOptions.FieldSelector); err != nil {
+			return nil, err
+		}
 	}
-
-	return r.client.List(ctx, objs, o)
+	return o, nil
 }
 
 func WithLabelSelector(sel string) ListOption {
@@ -218,6 +377,7 @@ type PatchOption func(*metav1.PatchOptions)
 
 // Patch patches portion of object `obj` with data from object `patch`
 func (r *Resources) Patch(ctx context.Context, obj k8s.Object, patch k8s.Patch, opts ...PatchOption) error {
+	r.applyDefaultNamespace(obj)
 	patchOptions := &metav1.PatchOptions{}
 
 	for _, fn := range opts {
@@ -237,6 +397,7 @@ func (r *Resources) Patch(ctx context.Context, obj k8s.Object, patch k8s.Patch,
 
 // PatchSubresource patches portion of object `obj` with data from object `patch`
 func (r *Resources) PatchSubresource(ctx context.Context, obj k8s.Object, subresource string, patch k8s.Patch, opts ...PatchOption) error {
+	r.applyDefaultNamespace(obj)
 	patchOptions := &metav1.PatchOptions{}
 
 	for _, fn := range opts {
@@ -20,21 +20,38 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/vladimirvivien/gexe"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/rest"
 	log "k8s.io/klog/v2"
 
+	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources/testdata/projectExample"
+	"sigs.k8s.io/e2e-framework/klient/wait"
 )
 
 func TestCreate(t *testing.T) {
@@ -98,6 +115,43 @@ func TestResNoConfig(t *testing.T) {
 	}
 }
 
+func TestResWithDefaultNamespace(t *testing.T) {
+	res, err := resources.New(cfg, resources.WithDefaultNamespace("default-ns-test"))
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default-ns-test"}}
+	if err := res.Create(ctx, ns); err != nil {
+		t.Fatalf("error while creating namespace: %v", err)
+	}
+	if ns.Namespace != "" {
+		t.Errorf("default namespace should not be applied to cluster scoped objects, got %s", ns.Namespace)
+	}
+
+	defaulted := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "defaulted"}}
+	if err := res.Create(ctx, defaulted); err != nil {
+		t.Fatalf("error while creating config map: %v", err)
+	}
+	if defaulted.Namespace != ns.Name {
+		t.Errorf("expected config map to be created in namespace %s, got %s", ns.Name, defaulted.Namespace)
+	}
+	if err := res.Get(ctx, defaulted.Name, "", &corev1.ConfigMap{}); err != nil {
+		t.Errorf("error while getting config map from the default namespace: %v", err)
+	}
+
+	explicit := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "explicit", Namespace: "default"}}
+	if err := res.Create(ctx, explicit); err != nil {
+		t.Fatalf("error while creating config map: %v", err)
+	}
+	if explicit.Namespace != "default" {
+		t.Errorf("explicit namespace should take precedence over the default, got %s", explicit.Namespace)
+	}
+	if err := res.Delete(ctx, explicit); err != nil {
+		t.Errorf("error while deleting config map: %v", err)
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	res, err := resources.New(cfg)
 	if err != nil {
@@ -435,3 +489,879 @@ func TestExecInPod(t *testing.T) {
 		t.Fatal("Couldn't find proper env")
 	}
 }
+
+func TestCreateAllOrdered(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	// the bundle lists the custom resource before its namespace and CRD
+	objs, err := decoder.DecodeAllFiles(ctx, os.DirFS("./testdata/orderedExample"), "*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to decode bundle: %v", err)
+	}
+	if err := res.CreateAllOrdered(ctx, objs); err != nil {
+		t.Fatalf("Failed to create bundle in order: %v", err)
+	}
+
+	widget := &unstructured.Unstructured{}
+	widget.SetGroupVersionKind(schema.GroupVersionKind{Group: "e2e.example.com", Version: "v1", Kind: "Widget"})
+	if err := res.Get(ctx, "example-widget", "ordered-test", widget); err != nil {
+		t.Error("error while getting custom resource created from bundle", err)
+	}
+}
+
+func TestCreateRBAC(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "rbac-test"}}
+	if err := res.Create(ctx, ns); err != nil {
+		t.Fatalf("error while creating namespace: %v", err)
+	}
+
+	subject, err := res.CreateRBAC(ctx,
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "pod-reader", Namespace: ns.Name}},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-reader"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}}},
+		},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "pod-reader"}},
+	)
+	if err != nil {
+		t.Fatalf("error while creating rbac fixtures: %v", err)
+	}
+	if subject.Username != "system:serviceaccount:rbac-test:pod-reader" {
+		t.Errorf("unexpected username %s", subject.Username)
+	}
+
+	asSA, err := resources.New(subject.Config(cfg))
+	if err != nil {
+		t.Fatalf("Error creating impersonating resources object: %v", err)
+	}
+	var pods corev1.PodList
+	err = wait.For(func(ctx context.Context) (bool, error) {
+		return asSA.WithNamespace(ns.Name).List(ctx, &pods) == nil, nil
+	}, wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("service account should be allowed to list pods in its namespace", err)
+	}
+	if err := asSA.WithNamespace("default").List(ctx, &pods); err == nil {
+		t.Error("service account should not be allowed to list pods in the default namespace")
+	}
+
+	token, err := subject.Token(ctx, res, 10*time.Minute)
+	if err != nil {
+		t.Error("error while requesting service account token", err)
+	}
+	if token == "" {
+		t.Error("expected a service account token")
+	}
+}
+
+func TestListAcrossNamespaces(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	namespaces := []string{"list-across-a", "list-across-b"}
+	for _, name := range namespaces {
+		if err := res.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+			t.Fatalf("error while creating namespace: %v", err)
+		}
+		for _, cm := range []*corev1.ConfigMap{
+			{ObjectMeta: metav1.ObjectMeta{Name: "mirrored-1", Namespace: name, Labels: map[string]string{"app": "mirror"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "mirrored-2", Namespace: name, Labels: map[string]string{"app": "mirror"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: name}},
+		} {
+			if err := res.Create(ctx, cm); err != nil {
+				t.Fatalf("error while creating config map: %v", err)
+			}
+		}
+	}
+
+	var cms corev1.ConfigMapList
+	err = res.ListAcrossNamespaces(ctx, &cms, namespaces, resources.WithLabelSelector("app=mirror"), func(lo *metav1.ListOptions) { lo.Limit = 1 })
+	if err != nil {
+		t.Fatal("error while listing config maps across namespaces", err)
+	}
+	if len(cms.Items) != 4 {
+		t.Errorf("expected 4 config maps, got %d", len(cms.Items))
+	}
+	perNamespace := map[string]int{}
+	for _, cm := range cms.Items {
+		if cm.Labels["app"] != "mirror" {
+			t.Errorf("config map %s/%s does not match the label selector", cm.Namespace, cm.Name)
+		}
+		perNamespace[cm.Namespace]++
+	}
+	for _, name := range namespaces {
+		if perNamespace[name] != 2 {
+			t.Errorf("expected 2 config maps in namespace %s, got %d", name, perNamespace[name])
+		}
+	}
+}
+
+func TestCreateOwnedBy(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "owned-by-test"}}
+	if err := res.Create(ctx, ns); err != nil {
+		t.Fatalf("error while creating namespace: %v", err)
+	}
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: ns.Name}}
+	if err := res.Create(ctx, owner); err != nil {
+		t.Fatalf("error while creating owner: %v", err)
+	}
+
+	owned := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owned", Namespace: ns.Name}}
+	if err := res.CreateOwnedBy(ctx, owned, owner); err != nil {
+		t.Fatalf("error while creating owned config map: %v", err)
+	}
+	refs := owned.GetOwnerReferences()
+	if len(refs) != 1 || refs[0].UID != owner.UID || refs[0].Kind != "ConfigMap" || !*refs[0].BlockOwnerDeletion {
+		t.Errorf("unexpected owner references: %v", refs)
+	}
+
+	clusterScoped := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "owned-by-test"}}
+	if err := res.CreateOwnedBy(ctx, clusterScoped, owner); err == nil {
+		t.Error("expected cluster scoped object owned by a namespaced owner to be rejected")
+	}
+	otherNamespace := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owned", Namespace: "default"}}
+	if err := res.CreateOwnedBy(ctx, otherNamespace, owner); err == nil {
+		t.Error("expected object owned by an owner of another namespace to be rejected")
+	}
+
+	if err := res.Delete(ctx, owner); err != nil {
+		t.Fatalf("error while deleting owner: %v", err)
+	}
+	err = wait.For(func(ctx context.Context) (bool, error) {
+		err := res.Get(ctx, owned.Name, owned.Namespace, &corev1.ConfigMap{})
+		return apierrors.IsNotFound(err), nil
+	}, wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("owned config map should be garbage collected once its owner is deleted", err)
+	}
+}
+
+func TestWaitForNamespaceDeletion(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	const finalizer = "e2e-framework.sigs.k8s.io/test"
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "namespace-deletion-test"}}
+	if err := res.Create(ctx, ns); err != nil {
+		t.Fatalf("error while creating namespace: %v", err)
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "blocking", Namespace: ns.Name, Finalizers: []string{finalizer}}}
+	if err := res.Create(ctx, cm); err != nil {
+		t.Fatalf("error while creating config map: %v", err)
+	}
+	if err := res.Delete(ctx, ns); err != nil {
+		t.Fatalf("error while deleting namespace: %v", err)
+	}
+
+	err = res.WaitForNamespaceDeletion(ctx, ns.Name, 10*time.Second)
+	if err == nil {
+		t.Fatal("expected namespace deletion to be blocked by the finalizer")
+	}
+	if !strings.Contains(err.Error(), "ConfigMap blocking has finalizers "+finalizer) {
+		t.Errorf("expected the error to report the blocking config map, got: %v", err)
+	}
+
+	if err := res.ForceFinalizeNamespace(ctx, ns.Name, finalizer); err != nil {
+		t.Fatalf("error while removing finalizers: %v", err)
+	}
+	if err := res.WaitForNamespaceDeletion(ctx, ns.Name, time.Minute); err != nil {
+		t.Error("namespace should be deleted once the finalizers are removed", err)
+	}
+}
+
+func TestWaitForLogLine(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-logs-ns"}}
+	if err := res.Create(ctx, namespace); err != nil {
+		t.Fatalf("Error while creating namespace resource: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-logs", Namespace: namespace.Name},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:    "busybox",
+			Image:   "busybox",
+			Command: []string{"sh", "-c", "sleep 5; echo server is ready; sleep 3600"},
+		}}},
+	}
+	if err := res.Create(ctx, pod); err != nil {
+		t.Fatalf("Error while creating pod resource: %v", err)
+	}
+
+	if err := res.WaitForLogLine(ctx, pod, "busybox", "server is ready", 3*time.Minute); err != nil {
+		t.Error("expected log line was not found", err)
+	}
+	if err := res.WaitForLogLine(ctx, pod, "busybox", "never printed", 10*time.Second); err == nil {
+		t.Error("expected an error waiting for a log line that is never printed")
+	}
+}
+
+func TestUpdateWithRetryOnConflict(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "retry-on-conflict", Namespace: "default"}}
+	if err := res.Create(ctx, cm); err != nil {
+		t.Fatalf("error while creating configmap: %v", err)
+	}
+
+	attempts := 0
+	err = res.UpdateWithRetryOnConflict(ctx, cm, func() error {
+		attempts++
+		if attempts == 1 {
+			// modify the configmap behind our back to force a conflict on the first attempt
+			concurrent := cm.DeepCopy()
+			concurrent.Labels = map[string]string{"modified": "concurrently"}
+			if _, err := clientset.CoreV1().ConfigMaps(cm.Namespace).Update(ctx, concurrent, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+		}
+		cm.Data = map[string]string{"key": "value"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error while updating configmap: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected the update to be retried once, got %d attempts", attempts)
+	}
+	if cm.Data["key"] != "value" || cm.Labels["modified"] != "concurrently" {
+		t.Errorf("expected the final object to carry both changes, got data %v and labels %v", cm.Data, cm.Labels)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "diff-test", Namespace: "default"},
+		Data:       map[string]string{"key": "old"},
+	}
+	if err := res.Create(ctx, cm); err != nil {
+		t.Fatalf("error while creating configmap: %v", err)
+	}
+
+	unchanged := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "diff-test", Namespace: "default"},
+		Data:       map[string]string{"key": "old"},
+	}
+	diff, err := res.Diff(ctx, unchanged)
+	if err != nil {
+		t.Fatalf("error while diffing configmap: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("expected no difference, got:\n%s", diff)
+	}
+
+	changed := unchanged.DeepCopy()
+	changed.Data["key"] = "new"
+	diff, err = res.Diff(ctx, changed)
+	if err != nil {
+		t.Fatalf("error while diffing configmap: %v", err)
+	}
+	if !strings.Contains(diff, "-  key: old") || !strings.Contains(diff, "+  key: new") {
+		t.Errorf("expected the data change in the diff, got:\n%s", diff)
+	}
+
+	var live corev1.ConfigMap
+	if err := res.Get(ctx, cm.Name, cm.Namespace, &live); err != nil {
+		t.Fatal(err)
+	}
+	if live.Data["key"] != "old" {
+		t.Error("diff should not modify the live object")
+	}
+}
+
+func TestDumpClusterState(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := res.DumpClusterState(ctx, dir, "kube-system"); err != nil {
+		t.Fatalf("error while dumping cluster state: %v", err)
+	}
+
+	for _, path := range []string{"nodes.yaml", "kube-system/pods.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("expected %s to be dumped: %v", path, err)
+		}
+	}
+	logs, err := filepath.Glob(filepath.Join(dir, "kube-system", "logs", "*.log"))
+	if err != nil || len(logs) == 0 {
+		t.Error("expected the pod logs to be dumped", err)
+	}
+}
+
+func TestGetPodProbeStatus(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-probes-ns"}}
+	if err := res.Create(ctx, namespace); err != nil {
+		t.Fatalf("Error while creating namespace resource: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-probes", Namespace: namespace.Name},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "nginx",
+			Image: "nginx",
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler:  corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/missing", Port: intstr.FromInt(80)}},
+				PeriodSeconds: 1,
+			},
+		}}},
+	}
+	if err := res.Create(ctx, pod); err != nil {
+		t.Fatalf("Error while creating pod resource: %v", err)
+	}
+
+	var status map[string]resources.ProbeStatus
+	err = wait.For(func(ctx context.Context) (bool, error) {
+		status, err = res.GetPodProbeStatus(ctx, pod)
+		if err != nil {
+			return false, err
+		}
+		return len(status["nginx"].ProbeEvents) > 0, nil
+	}, wait.WithTimeout(3*time.Minute), wait.WithInterval(time.Second))
+	if err != nil {
+		t.Fatalf("expected readiness probe failures to be reported: %v", err)
+	}
+
+	nginx := status["nginx"]
+	if nginx.Ready {
+		t.Error("expected the container with a failing readiness probe not to be ready")
+	}
+	if !strings.Contains(nginx.ProbeEvents[0], "Readiness probe failed") {
+		t.Errorf("unexpected probe event: %s", nginx.ProbeEvents[0])
+	}
+	if nginx.PodReady == nil || nginx.PodReady.Status != corev1.ConditionFalse {
+		t.Errorf("expected the pod Ready condition to be false, got %v", nginx.PodReady)
+	}
+}
+
+func TestExpectUpdateRejected(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	immutable := true
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "immutable-configmap", Namespace: "default"},
+		Data:       map[string]string{"key": "value"},
+		Immutable:  &immutable,
+	}
+	if err := res.Create(ctx, cm); err != nil {
+		t.Fatalf("error while creating configmap: %v", err)
+	}
+
+	rejected, message, err := res.ExpectUpdateRejected(ctx, cm, func() { cm.Data["key"] = "changed" })
+	if err != nil {
+		t.Fatalf("unexpected error while updating configmap: %v", err)
+	}
+	if !rejected || !strings.Contains(message, "field is immutable") {
+		t.Errorf("expected the update of the immutable data to be rejected, got rejected=%t message=%q", rejected, message)
+	}
+
+	if err := res.Get(ctx, cm.Name, cm.Namespace, cm); err != nil {
+		t.Fatalf("error while getting configmap: %v", err)
+	}
+	if err := res.ExpectUpdateAccepted(ctx, cm, func() { cm.Labels = map[string]string{"updated": "true"} }); err != nil {
+		t.Errorf("expected the update of the labels to be accepted: %v", err)
+	}
+}
+
+func TestExpectCreateDenied(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "create-denied"}}
+	if err := res.Create(ctx, ns); err != nil {
+		t.Fatalf("error while creating namespace: %v", err)
+	}
+	// the ResourceQuota admission plugin denies the pods exceeding the quota
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "no-pods", Namespace: ns.Name},
+		Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("0")}},
+	}
+	if err := res.Create(ctx, quota); err != nil {
+		t.Fatalf("error while creating resource quota: %v", err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "denied", Namespace: ns.Name},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+	}
+	if err := res.ExpectCreateDenied(ctx, pod, "quota"); err != nil {
+		t.Errorf("expected the pod to be denied: %v", err)
+	}
+	if err := res.ExpectCreateDenied(ctx, pod, "unrelated policy"); err == nil {
+		t.Error("expected a denial with a different message to be reported")
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "allowed", Namespace: ns.Name}}
+	if err := res.ExpectCreateAllowed(ctx, cm); err != nil {
+		t.Errorf("expected the configmap to be allowed: %v", err)
+	}
+	if err := res.ExpectCreateAllowed(ctx, pod); err == nil || !strings.Contains(err.Error(), "quota") {
+		t.Errorf("expected the denial of the pod to be reported, got: %v", err)
+	}
+}
+
+func TestListTyped(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "list-typed", Namespace: "default", Labels: map[string]string{"list": "typed"}},
+		Data:       map[string]string{"key": "value"},
+	}
+	if err := res.Create(ctx, cm); err != nil {
+		t.Fatalf("error while creating configmap: %v", err)
+	}
+
+	configMaps, err := resources.ListTyped[corev1.ConfigMap](ctx, res.WithNamespace("default"), resources.WithLabelSelector("list=typed"))
+	if err != nil {
+		t.Fatalf("error while listing configmaps: %v", err)
+	}
+	if len(configMaps) != 1 || configMaps[0].Name != cm.Name || configMaps[0].Data["key"] != "value" {
+		t.Errorf("unexpected configmaps: %v", configMaps)
+	}
+}
+
+func TestWatchDeploymentAvailability(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	deployment := getDeployment("availability-watch-dep")
+	if err := res.Create(ctx, deployment); err != nil {
+		t.Fatalf("error while creating deployment: %v", err)
+	}
+	availableReplicas := func(n int32) func(ctx context.Context) (bool, error) {
+		return func(ctx context.Context) (bool, error) {
+			if err := res.Get(ctx, deployment.Name, deployment.Namespace, deployment); err != nil {
+				return false, err
+			}
+			return deployment.Status.AvailableReplicas == n, nil
+		}
+	}
+	if err := wait.For(availableReplicas(2), wait.WithInterval(time.Second), wait.WithTimeout(3*time.Minute)); err != nil {
+		t.Fatalf("error while waiting for the deployment to be available: %v", err)
+	}
+
+	check, err := res.WatchDeploymentAvailability(ctx, deployment, 2)
+	if err != nil {
+		t.Fatalf("error while watching deployment: %v", err)
+	}
+	err = res.UpdateWithRetryOnConflict(ctx, deployment, func() error {
+		replicas := int32(1)
+		deployment.Spec.Replicas = &replicas
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error while scaling down deployment: %v", err)
+	}
+	if err := wait.For(availableReplicas(1), wait.WithInterval(time.Second), wait.WithTimeout(time.Minute)); err != nil {
+		t.Fatalf("error while waiting for the deployment to be scaled down: %v", err)
+	}
+	if err := check(); err == nil || !strings.Contains(err.Error(), "down to 1 available replicas") {
+		t.Errorf("expected the availability drop to be reported, got: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	if err := res.Validate(ctx, getDeployment("validate")); err != nil {
+		t.Errorf("expected a valid deployment, got: %v", err)
+	}
+
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: invalid
+  namespace: default
+spec:
+  replicas: two
+  selectr:
+    matchLabels:
+      app: invalid
+`
+	obj := &unstructured.Unstructured{}
+	if err := decoder.DecodeString(manifest, obj); err != nil {
+		t.Fatal(err)
+	}
+	err = res.Validate(ctx, obj)
+	if err == nil {
+		t.Fatal("expected the deployment to be invalid")
+	}
+	if !strings.Contains(err.Error(), ".spec.replicas") || !strings.Contains(err.Error(), ".spec.selectr") {
+		t.Errorf("expected the invalid fields to be reported, got: %v", err)
+	}
+}
+
+func TestValidate_SchemaHashChange(t *testing.T) {
+	var mu sync.Mutex
+	hash, downloads := "1", 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/openapi/v3":
+			fmt.Fprintf(w, `{"paths":{"api/v1":{"serverRelativeURL":"/openapi/v3/api/v1?hash=%s"}}}`, hash)
+		case "/openapi/v3/api/v1":
+			downloads++
+			immutable := ""
+			if r.URL.Query().Get("hash") == "2" {
+				immutable = `,"immutable":{"type":"boolean"}`
+			}
+			fmt.Fprintf(w, `{"openapi":"3.0.0","info":{"title":"test","version":"v1"},"paths":{},"components":{"schemas":{"io.k8s.api.core.v1.ConfigMap":{"type":"object","properties":{"apiVersion":{"type":"string"},"kind":{"type":"string"},"metadata":{"type":"object","x-kubernetes-preserve-unknown-fields":true}%s},"x-kubernetes-group-version-kind":[{"group":"","kind":"ConfigMap","version":"v1"}]}}}}`, immutable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	res, err := resources.New(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	immutable := true
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "validate", Namespace: "default"},
+		Immutable:  &immutable,
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := res.Validate(context.TODO(), configMap); err == nil || !strings.Contains(err.Error(), ".immutable") {
+			t.Fatalf("expected .immutable to be rejected by the first schema, got: %v", err)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("expected the schema to be downloaded once while its hash is unchanged, got %d downloads", downloads)
+	}
+
+	mu.Lock()
+	hash = "2"
+	mu.Unlock()
+	if err := res.Validate(context.TODO(), configMap); err != nil {
+		t.Errorf("expected the updated schema to accept .immutable, got: %v", err)
+	}
+	if downloads != 2 {
+		t.Errorf("expected the schema to be downloaded again after its hash changed, got %d downloads", downloads)
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	cacheCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	res, err := resources.New(cfg, resources.WithCache(cacheCtx))
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cached", Namespace: "default"}, Data: map[string]string{"key": "value"}}
+	if err := res.Create(ctx, cm); err != nil {
+		t.Fatalf("error while creating configmap: %v", err)
+	}
+
+	// a strongly consistent read sees the configmap right away
+	var direct corev1.ConfigMap
+	if err := res.Uncached().Get(ctx, cm.Name, cm.Namespace, &direct); err != nil {
+		t.Fatalf("error while getting configmap from the API server: %v", err)
+	}
+
+	// the cache catches up with the creation
+	err = wait.For(func(ctx context.Context) (bool, error) {
+		var cached corev1.ConfigMap
+		if err := res.Get(ctx, cm.Name, cm.Namespace, &cached); err != nil {
+			return false, nil
+		}
+		return cached.Data["key"] == "value", nil
+	}, wait.WithImmediate(), wait.WithTimeout(30*time.Second))
+	if err != nil {
+		t.Fatalf("configmap not served by the cache: %v", err)
+	}
+	if err := res.WaitForCacheSync(ctx); err != nil {
+		t.Errorf("unexpected error waiting for the cache to sync: %v", err)
+	}
+}
+
+func TestFetchHTTPFromPod(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "fetch-http", Namespace: "default"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+	}
+	if err := res.Create(ctx, pod); err != nil {
+		t.Fatalf("error while creating pod: %v", err)
+	}
+	err = wait.For(func(ctx context.Context) (bool, error) {
+		if err := res.Get(ctx, pod.Name, pod.Namespace, pod); err != nil {
+			return false, err
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady {
+				return cond.Status == corev1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	}, wait.WithTimeout(5*time.Minute))
+	if err != nil {
+		t.Fatalf("pod not ready: %v", err)
+	}
+
+	body, err := res.FetchHTTPFromPod(ctx, pod, 80, "/")
+	if err != nil {
+		t.Fatalf("error while fetching from pod: %v", err)
+	}
+	if !strings.Contains(string(body), "nginx") {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	if _, err := res.FetchHTTPFromPod(ctx, pod, 80, "/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the status of the response to be reported, got: %v", err)
+	}
+}
+
+func TestExportImportNamespace(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	source := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "export-source"}}
+	if err := res.Create(ctx, source); err != nil {
+		t.Fatalf("error while creating namespace: %v", err)
+	}
+	objs := []k8s.Object{
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "exported", Namespace: source.Name}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "exported", Namespace: source.Name}, Data: map[string]string{"key": "value"}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "exported", Namespace: source.Name},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "exported"}, Ports: []corev1.ServicePort{{Port: 80}}},
+		},
+	}
+	for _, obj := range objs {
+		if err := res.Create(ctx, obj); err != nil {
+			t.Fatalf("error while creating %s: %v", obj.GetName(), err)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := res.ExportNamespace(ctx, source.Name, dir); err != nil {
+		t.Fatalf("error while exporting namespace: %v", err)
+	}
+	if err := res.ImportNamespace(ctx, "export-target", dir); err != nil {
+		t.Fatalf("error while importing namespace: %v", err)
+	}
+
+	var cm corev1.ConfigMap
+	if err := res.Get(ctx, "exported", "export-target", &cm); err != nil || cm.Data["key"] != "value" {
+		t.Errorf("expected the configmap to be imported, got %v: %v", cm.Data, err)
+	}
+	var sa corev1.ServiceAccount
+	if err := res.Get(ctx, "exported", "export-target", &sa); err != nil {
+		t.Errorf("expected the service account to be imported: %v", err)
+	}
+	var svc corev1.Service
+	if err := res.Get(ctx, "exported", "export-target", &svc); err != nil || svc.Spec.ClusterIP == "" {
+		t.Errorf("expected the service to be imported with a new cluster IP: %v", err)
+	}
+}
+
+func TestOOMKills(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	start := time.Now()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "oom-killed", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:    "hog",
+				Image:   "busybox",
+				Command: []string{"sh", "-c", "tail /dev/zero"},
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Mi")},
+				},
+			}},
+		},
+	}
+	if err := res.Create(ctx, pod); err != nil {
+		t.Fatalf("error while creating pod: %v", err)
+	}
+
+	var kills []string
+	err = wait.For(func(ctx context.Context) (bool, error) {
+		kills, err = res.OOMKills(ctx, pod.Namespace, start)
+		return len(kills) > 0, err
+	}, wait.WithTimeout(5*time.Minute))
+	if err != nil {
+		t.Fatalf("expected the container to be OOMKilled: %v", err)
+	}
+	if !strings.HasPrefix(kills[0], "oom-killed/hog") {
+		t.Errorf("unexpected OOMKilled container: %v", kills)
+	}
+	if err := res.AssertNoOOMKills(ctx, pod.Namespace); err == nil || !strings.Contains(err.Error(), "oom-killed/hog") {
+		t.Errorf("expected the OOMKilled container to be reported, got: %v", err)
+	}
+	if kills, err := res.OOMKills(ctx, pod.Namespace, time.Now().Add(time.Hour)); err != nil || len(kills) != 0 {
+		t.Errorf("expected the kills before the provided time to be ignored, got %v: %v", kills, err)
+	}
+}
+
+func TestCreateTracked(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	deleted := make(chan string, 10)
+	w := res.Watch(&corev1.ConfigMapList{}, resources.WithLabelSelector("tracked=true")).
+		WithDeleteFunc(func(obj interface{}) {
+			deleted <- obj.(*corev1.ConfigMap).Name
+		})
+	if err := w.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	trackedCtx := ctx
+	names := []string{"tracked-1", "tracked-2", "tracked-3"}
+	for _, name := range names {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"tracked": "true"}}}
+		if trackedCtx, err = res.CreateTracked(trackedCtx, cm); err != nil {
+			t.Fatalf("error while creating tracked configmap: %v", err)
+		}
+	}
+	// an object already gone is ignored
+	gone := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tracked-2", Namespace: "default"}}
+	if err := res.Delete(ctx, gone); err != nil {
+		t.Fatalf("error while deleting configmap: %v", err)
+	}
+
+	if err := res.CleanupTracked(trackedCtx); err != nil {
+		t.Fatalf("error while cleaning up tracked objects: %v", err)
+	}
+	var order []string
+	for len(order) < len(names) {
+		select {
+		case name := <-deleted:
+			order = append(order, name)
+		case <-time.After(time.Minute):
+			t.Fatalf("configmaps not deleted, got deletions: %v", order)
+		}
+	}
+	if expected := []string{"tracked-2", "tracked-3", "tracked-1"}; strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the tracked objects to be deleted in reverse order %v, got %v", expected, order)
+	}
+	if err := res.CleanupTracked(trackedCtx); err != nil {
+		t.Errorf("expected the tracked objects to only be deleted once, got: %v", err)
+	}
+}
+
+func TestRunJobToCompletion(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	newJob := func(name, script string) *batchv1.Job {
+		backoffLimit := int32(0)
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: batchv1.JobSpec{
+				BackoffLimit: &backoffLimit,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						RestartPolicy: corev1.RestartPolicyNever,
+						Containers:    []corev1.Container{{Name: "task", Image: "busybox", Command: []string{"sh", "-c", script}}},
+					},
+				},
+			},
+		}
+	}
+
+	logs, err := res.RunJobToCompletion(ctx, newJob("job-complete", "echo migrated"), 3*time.Minute)
+	if err != nil {
+		t.Fatalf("error while running job: %v", err)
+	}
+	if !strings.Contains(logs, "migrated") {
+		t.Errorf("expected the logs of the job, got %q", logs)
+	}
+
+	logs, err = res.RunJobToCompletion(ctx, newJob("job-failed", "echo broken; exit 1"), 3*time.Minute)
+	var failed *resources.JobFailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("expected a job failure, got: %v", err)
+	}
+	if !strings.Contains(failed.Logs, "broken") || logs != failed.Logs {
+		t.Errorf("expected the logs of the failed job, got %q", failed.Logs)
+	}
+}
+
+func TestSnapshotYAML(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	ref := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "snapshot-test", Namespace: "default"}}
+	snapshot, err := res.SnapshotYAML(ctx, ref)
+	if err != nil || snapshot != "" {
+		t.Fatalf("expected an empty snapshot of a missing object, got %q: %v", snapshot, err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: ref.ObjectMeta, Data: map[string]string{"key": "value"}}
+	if err := res.Create(ctx, cm); err != nil {
+		t.Fatalf("error while creating configmap: %v", err)
+	}
+	snapshot, err = res.SnapshotYAML(ctx, ref)
+	if err != nil {
+		t.Fatalf("error while taking snapshot: %v", err)
+	}
+	if !strings.Contains(snapshot, "key: value") || strings.Contains(snapshot, "resourceVersion") || strings.Contains(snapshot, "managedFields") {
+		t.Errorf("unexpected snapshot:\n%s", snapshot)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// RestartedAtAnnotation is the pod template annotation set by RolloutRestart, the same way
+// kubectl rollout restart does, to trigger a rolling update of the workload
+const RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// RolloutRestart triggers a rolling update of the Deployment, StatefulSet or DaemonSet by patching its
+// pod template with the RestartedAtAnnotation, which is what kubectl rollout restart does. The object is
+// updated with the patched state, so its generation can be used to wait for the rollout to complete,
+// for instance using the RolloutComplete condition.
+func (r *Resources) RolloutRestart(ctx context.Context, obj k8s.Object) error {
+	switch obj.(type) {
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.DaemonSet:
+	default:
+		return fmt.Errorf("resources: rollout restart is not supported for %T", obj)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{RestartedAtAnnotation: time.Now().Format(time.RFC3339Nano)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.Patch(ctx, obj, k8s.Patch{PatchType: types.StrategicMergePatchType, Data: patch}); err != nil {
+		return fmt.Errorf("resources: rollout restart %s %s: %w", r.kindOf(obj), obj.GetName(), err)
+	}
+	return nil
+}
+
+// WatchDeploymentAvailability starts watching the Deployment and records every time its available replicas drop
+// below minAvailable, which catches the transient drops that checks made at a point in time miss, for instance
+// to verify that a rolling update causes no downtime. The watch is started before returning, so the watch should
+// be started before triggering the update. The returned function stops the watch and returns an error describing
+// the drops that occurred, if any, or if the watch ended early, in which case the availability was not observed
+// the whole time.
+func (r *Resources) WatchDeploymentAvailability(ctx context.Context, dep k8s.Object, minAvailable int32) (func() error, error) {
+	if _, ok := dep.(*appsv1.Deployment); !ok {
+		return nil, fmt.Errorf("resources: watch deployment availability: unexpected type %T", dep)
+	}
+	name := types.NamespacedName{Namespace: dep.GetNamespace(), Name: dep.GetName()}
+	if name.Namespace == "" {
+		name.Namespace = r.defaultNamespace
+	}
+	client, err := cr.NewWithWatch(r.config, cr.Options{Scheme: r.scheme})
+	if err != nil {
+		return nil, err
+	}
+	w, err := client.Watch(ctx, &appsv1.DeploymentList{}, cr.InNamespace(name.Namespace), cr.MatchingFields{"metadata.name": name.Name})
+	if err != nil {
+		return nil, fmt.Errorf("resources: watch deployment %s: %w", name, err)
+	}
+
+	var (
+		mu          sync.Mutex
+		stopping    bool
+		endedEarly  bool
+		drops       int
+		lowest      int32
+		firstDropAt time.Time
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range w.ResultChan() {
+			d, ok := event.Object.(*appsv1.Deployment)
+			if !ok {
+				continue
+			}
+			available := d.Status.AvailableReplicas
+			if event.Type == watch.Deleted {
+				available = 0
+			}
+			if available >= minAvailable {
+				continue
+			}
+			mu.Lock()
+			if drops == 0 || available < lowest {
+				lowest = available
+			}
+			if drops == 0 {
+				firstDropAt = time.Now()
+			}
+			drops++
+			mu.Unlock()
+		}
+		mu.Lock()
+		endedEarly = !stopping
+		mu.Unlock()
+	}()
+
+	return func() error {
+		mu.Lock()
+		stopping = true
+		mu.Unlock()
+		w.Stop()
+		<-done
+
+		mu.Lock()
+		defer mu.Unlock()
+		if drops > 0 {
+			return fmt.Errorf("resources: deployment %s availability dropped below %d replicas %d times, down to %d available replicas, first at %s",
+				name, minAvailable, drops, lowest, firstDropAt.Format(time.RFC3339))
+		}
+		if endedEarly {
+			return fmt.Errorf("resources: watch of deployment %s ended before being stopped, its availability was not observed the whole time", name)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// generatedKinds are the kinds of the resources maintained by Kubernetes itself, which are not exported
+var generatedKinds = sets.New("Event", "Endpoints", "EndpointSlice", "ControllerRevision", "PodMetrics")
+
+// ExportNamespace writes the resources of the namespace to dir as YAML, one file per resource type, so that
+// they can be recreated later using ImportNamespace, for instance to set up a golden environment without
+// running the controllers from scratch. All the namespaced resource types that can be listed and created are
+// exported, except for the ones maintained by Kubernetes, such as the Events and the Endpoints, and the objects
+// owned by a controller, such as the Pods of a ReplicaSet, which are recreated by their controller. The fields
+// set by the server, such as the UID, the status or the cluster IP of the Services, are stripped along with the
+// namespace.
+func (r *Resources) ExportNamespace(ctx context.Context, namespace, dir string) error {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(r.config)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(r.config)
+	if err != nil {
+		return err
+	}
+	resourceTypes, err := exportableNamespacedResources(discoveryClient)
+	if err != nil {
+		return fmt.Errorf("resources: export namespace %s: %w", namespace, err)
+	}
+
+	for _, gvr := range resourceTypes {
+		list, err := dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("resources: export namespace %s: list %s: %w", namespace, gvr.String(), err)
+		}
+		var objs []unstructured.Unstructured
+		for _, obj := range list.Items {
+			if !exportable(&obj) {
+				continue
+			}
+			stripServerFields(&obj)
+			objs = append(objs, obj)
+		}
+		if len(objs) == 0 {
+			continue
+		}
+		name := gvr.Resource
+		if gvr.Group != "" {
+			name = fmt.Sprintf("%s.%s", gvr.Resource, gvr.Group)
+		}
+		if err := writeObjectsYAML(filepath.Join(dir, name+".yaml"), objs); err != nil {
+			return fmt.Errorf("resources: export namespace %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// ImportNamespace recreates in the namespace the resources exported to dir using ExportNamespace, creating the
+// namespace first if it does not exist. The resources are created in the order used by CreateAllOrdered, so that
+// the ServiceAccounts, Secrets and ConfigMaps exist before the workloads using them. The namespace can differ
+// from the one the resources were exported from.
+func (r *Resources) ImportNamespace(ctx context.Context, namespace, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("resources: import namespace %s: %w", namespace, err)
+	}
+	var objs []k8s.Object
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		fileObjs, err := readObjectsYAML(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("resources: import namespace %s: %w", namespace, err)
+		}
+		for _, obj := range fileObjs {
+			obj.SetNamespace(namespace)
+			objs = append(objs, obj)
+		}
+	}
+
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	if err := r.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("resources: import namespace %s: %w", namespace, err)
+	}
+	return r.CreateAllOrdered(ctx, objs)
+}
+
+// exportableNamespacedResources returns the preferred version of the namespaced resource types that can be
+// listed and created, except for the ones maintained by Kubernetes
+func exportableNamespacedResources(client discovery.DiscoveryInterface) ([]schema.GroupVersionResource, error) {
+	lists, err := client.ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("discover resource types: %w", err)
+	}
+	var result []schema.GroupVersionResource
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, res := range list.APIResources {
+			verbs := sets.New(res.Verbs...)
+			if generatedKinds.Has(res.Kind) || !verbs.Has("list") || !verbs.Has("create") {
+				continue
+			}
+			result = append(result, gv.WithResource(res.Name))
+		}
+	}
+	return result, nil
+}
+
+// exportable returns false for the objects owned by a controller and the objects Kubernetes creates in every
+// namespace, which are recreated on their own
+func exportable(obj *unstructured.Unstructured) bool {
+	if metav1.GetControllerOf(obj) != nil {
+		return false
+	}
+	switch obj.GetKind() {
+	case "ServiceAccount":
+		return obj.GetName() != "default"
+	case "ConfigMap":
+		return obj.GetName() != "kube-root-ca.crt"
+	case "Secret":
+		secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+		return secretType != string(v1.SecretTypeServiceAccountToken)
+	}
+	return true
+}
+
+// stripServerFields removes the fields set by the server, which cannot be set when the object is recreated
+func stripServerFields(obj *unstructured.Unstructured) {
+	obj.SetNamespace("")
+	obj.SetUID("")
+	obj.SetResourceVersion("")
+	obj.SetGeneration(0)
+	obj.SetSelfLink("")
+	obj.SetManagedFields(nil)
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(obj.Object, "status")
+	switch obj.GetKind() {
+	case "Service":
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+	case "PersistentVolumeClaim":
+		unstructured.RemoveNestedField(obj.Object, "spec", "volumeName")
+		annotations := obj.GetAnnotations()
+		for key := range annotations {
+			if strings.HasPrefix(key, "pv.kubernetes.io/") || strings.HasPrefix(key, "volume.kubernetes.io/") {
+				delete(annotations, key)
+			}
+		}
+		obj.SetAnnotations(annotations)
+	case "Pod":
+		unstructured.RemoveNestedField(obj.Object, "spec", "nodeName")
+	}
+}
+
+// readObjectsYAML reads the objects of a multi-document YAML file
+func readObjectsYAML(path string) ([]*unstructured.Unstructured, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	var objs []*unstructured.Unstructured
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		jsonDoc, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		if len(bytes.TrimSpace(jsonDoc)) == 0 || string(jsonDoc) == "null" {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(jsonDoc); err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		objs = append(objs, obj)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+type trackerContextKey struct{}
+
+// tracker records the objects created using CreateTracked, in creation order
+type tracker struct {
+	mu   sync.Mutex
+	objs []k8s.Object
+}
+
+// CreateTracked creates the object and records it in a tracker carried by the returned context, so that it can be
+// deleted along with the other tracked objects using CleanupTracked, typically in the teardown of a feature. The
+// tracker of ctx is used if there is one, otherwise a new one is attached to the returned context. The returned
+// context must be passed down to the following steps, or returned by the step, for the object to be cleaned up.
+func (r *Resources) CreateTracked(ctx context.Context, obj k8s.Object, opts ...CreateOption) (context.Context, error) {
+	if err := r.Create(ctx, obj, opts...); err != nil {
+		return ctx, err
+	}
+	t, ok := ctx.Value(trackerContextKey{}).(*tracker)
+	if !ok {
+		t = &tracker{}
+		ctx = context.WithValue(ctx, trackerContextKey{}, t)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.objs = append(t.objs, obj)
+	return ctx, nil
+}
+
+// CleanupTracked deletes the objects created using CreateTracked with ctx, in the reverse order of their creation,
+// so that the objects are deleted before the objects they depend on, such as their namespace. The objects which are
+// already gone, for instance because they were garbage collected along with their owner, are ignored. All the
+// objects are attempted and the failed deletions are returned together. The tracker is emptied, so the objects are
+// only deleted once.
+func (r *Resources) CleanupTracked(ctx context.Context, opts ...DeleteOption) error {
+	t, ok := ctx.Value(trackerContextKey{}).(*tracker)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	objs := t.objs
+	t.objs = nil
+	t.mu.Unlock()
+
+	var errs []error
+	for i := len(objs) - 1; i >= 0; i-- {
+		obj := objs[i]
+		if err := r.Delete(ctx, obj, opts...); err != nil && !apierrors.IsNotFound(err) {
+			name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+			errs = append(errs, fmt.Errorf("resources: delete tracked %s %s: %w", r.kindOf(obj), name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
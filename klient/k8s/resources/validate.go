@@ -0,0 +1,164 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/handler3"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// schemaCache holds the type converters built from the OpenAPI schemas of the API servers, keyed by API server
+// host and URL of the schema of the group version. The URL includes a hash of the schema, which changes when the
+// schema does, such as when a CRD is installed or updated, so that a changed schema is downloaded again.
+var schemaCache = struct {
+	mu      sync.Mutex
+	entries map[string]*schemaEntry
+}{entries: make(map[string]*schemaEntry)}
+
+// schemaEntry is the type converter of a schema, the callers needing it wait for done to be closed while it is
+// being built. The entries of the schemas that failed to be downloaded are removed, so that they are retried.
+type schemaEntry struct {
+	done      chan struct{}
+	converter managedfields.TypeConverter
+	err       error
+}
+
+// Validate checks the object against the OpenAPI schema served by the API server for its kind, the way
+// kubectl --validate=strict does, without sending the object to the API server. Unknown fields and fields of
+// the wrong type are reported with their path, which catches the typos of the test fixtures early with a
+// precise message. The schema of each group version is cached per cluster until it changes on the cluster.
+func (r *Resources) Validate(ctx context.Context, obj k8s.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return fmt.Errorf("resources: validate: %w", err)
+	}
+	name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	converter, err := r.typeConverter(ctx, gvk.GroupVersion())
+	if err != nil {
+		return fmt.Errorf("resources: validate %s %s: %w", gvk.Kind, name, err)
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return fmt.Errorf("resources: validate %s %s: %w", gvk.Kind, name, err)
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	if _, err := converter.ObjectToTyped(u); err != nil {
+		return fmt.Errorf("resources: %s %s is invalid: %w", gvk.Kind, name, err)
+	}
+	return nil
+}
+
+// typeConverter returns the type converter built from the OpenAPI v3 schema of the group version, which is
+// downloaded from the API server unless the current version of the schema is cached already. Each schema is
+// only downloaded once at a time, without blocking the validations using the other schemas.
+func (r *Resources) typeConverter(ctx context.Context, gv schema.GroupVersion) (managedfields.TypeConverter, error) {
+	path := "apis/" + gv.String()
+	if gv.Group == "" {
+		path = "api/" + gv.Version
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(r.config)
+	if err != nil {
+		return nil, err
+	}
+	data, err := client.RESTClient().Get().AbsPath("/openapi/v3").Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("discover openapi schemas: %w", err)
+	}
+	var root handler3.OpenAPIV3Discovery
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("discover openapi schemas: %w", err)
+	}
+	gvPath, ok := root.Paths[path]
+	if !ok {
+		return nil, fmt.Errorf("no openapi schema served for %s", gv)
+	}
+	key := r.config.Host + gvPath.ServerRelativeURL
+
+	schemaCache.mu.Lock()
+	entry, ok := schemaCache.entries[key]
+	if !ok {
+		entry = &schemaEntry{done: make(chan struct{})}
+		schemaCache.entries[key] = entry
+	}
+	schemaCache.mu.Unlock()
+	if ok {
+		select {
+		case <-entry.done:
+			return entry.converter, entry.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	entry.converter, entry.err = downloadTypeConverter(ctx, client, gv, gvPath.ServerRelativeURL)
+	if entry.err != nil {
+		schemaCache.mu.Lock()
+		delete(schemaCache.entries, key)
+		schemaCache.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.converter, entry.err
+}
+
+// downloadTypeConverter downloads the OpenAPI v3 schema of the group version served at the server relative URL
+// and builds a type converter from it
+func downloadTypeConverter(ctx context.Context, client *discovery.DiscoveryClient, gv schema.GroupVersion, serverRelativeURL string) (managedfields.TypeConverter, error) {
+	locator, err := url.Parse(serverRelativeURL)
+	if err != nil {
+		return nil, fmt.Errorf("download openapi schema of %s: %w", gv, err)
+	}
+	req := client.RESTClient().Get().AbsPath(locator.Path).SetHeader("Accept", runtime.ContentTypeJSON)
+	// the hash of the schema is passed as a query parameter, which AbsPath does not handle
+	for k, values := range locator.Query() {
+		for _, v := range values {
+			req = req.Param(k, v)
+		}
+	}
+	data, err := req.Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("download openapi schema of %s: %w", gv, err)
+	}
+	var doc spec3.OpenAPI
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi schema of %s: %w", gv, err)
+	}
+	if doc.Components == nil {
+		return nil, fmt.Errorf("openapi schema of %s has no components", gv)
+	}
+	converter, err := managedfields.NewTypeConverter(doc.Components.Schemas, false)
+	if err != nil {
+		return nil, fmt.Errorf("openapi schema of %s: %w", gv, err)
+	}
+	return converter, nil
+}
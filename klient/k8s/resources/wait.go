@@ -0,0 +1,35 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"time"
+
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+// waitOptions returns the options of the waits performed by the helpers of this package. The condition is
+// checked right away then every interval, for up to timeout when it is positive. Otherwise the default timeout
+// of the klient/wait package and the deadline of the context apply. The options provided by the caller come
+// last so that they take precedence.
+func waitOptions(interval, timeout time.Duration, opts []wait.Option) []wait.Option {
+	options := []wait.Option{wait.WithInterval(interval), wait.WithImmediate()}
+	if timeout > 0 {
+		options = append(options, wait.WithTimeout(timeout))
+	}
+	return append(options, opts...)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import "k8s.io/client-go/rest"
+
+// ConfigOption overrides a setting of the *rest.Config used to reach the API server
+type ConfigOption func(*rest.Config)
+
+// WithQPS sets the maximum queries per second to the API server. Raising it avoids the
+// client side throttling that slows down suites issuing many requests in parallel.
+func WithQPS(qps float32) ConfigOption {
+	return func(cfg *rest.Config) {
+		cfg.QPS = qps
+	}
+}
+
+// WithBurst sets the maximum burst of queries allowed above the QPS limit
+func WithBurst(burst int) ConfigOption {
+	return func(cfg *rest.Config) {
+		cfg.Burst = burst
+	}
+}
+
+// WithUserAgent sets the user agent sent to the API server. Using a distinct user agent
+// per test suite makes it easy to identify its requests in the API server audit logs.
+func WithUserAgent(userAgent string) ConfigOption {
+	return func(cfg *rest.Config) {
+		cfg.UserAgent = userAgent
+	}
+}
+
+// ApplyConfigOptions returns a copy of cfg with the options applied. cfg is
+// returned unchanged when no option is provided.
+func ApplyConfigOptions(cfg *rest.Config, opts ...ConfigOption) *rest.Config {
+	if len(opts) == 0 {
+		return cfg
+	}
+	cfg = rest.CopyConfig(cfg)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
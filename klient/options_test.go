@@ -0,0 +1,38 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestApplyConfigOptions(t *testing.T) {
+	base := &rest.Config{Host: "https://127.0.0.1:6443"}
+	cfg := ApplyConfigOptions(base, WithQPS(50), WithBurst(100), WithUserAgent("my-suite"))
+
+	if cfg.QPS != 50 || cfg.Burst != 100 || cfg.UserAgent != "my-suite" {
+		t.Errorf("options not applied: QPS=%v Burst=%v UserAgent=%q", cfg.QPS, cfg.Burst, cfg.UserAgent)
+	}
+	if cfg.Host != base.Host {
+		t.Errorf("expected host %q, got %q", base.Host, cfg.Host)
+	}
+	if base.QPS != 0 || base.Burst != 0 || base.UserAgent != "" {
+		t.Error("base config should not be modified")
+	}
+}
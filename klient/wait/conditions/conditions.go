@@ -18,17 +18,30 @@ package conditions
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	log "k8s.io/klog/v2"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 
 	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
@@ -304,6 +317,38 @@ func (c *Condition) DeploymentAvailable(name, namespace string) apimachinerywait
 	)
 }
 
+// AllDeploymentsAvailable is a helper function used to check if all the Deployments of the namespace, such as the
+// ones installed by a Helm release, are Available and run the desired number of available replicas. The list
+// options can be used to narrow down the set of Deployments checked, for instance using a label selector. The
+// Deployments that are not available yet are logged on each check. A namespace without any Deployment is
+// considered available.
+func (c *Condition) AllDeploymentsAvailable(namespace string, listOptions ...resources.ListOption) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		var deployments appsv1.DeploymentList
+		if err := c.resources.ListAcrossNamespaces(ctx, &deployments, []string{namespace}, listOptions...); err != nil {
+			return false, err
+		}
+		done = true
+		for _, d := range deployments.Items {
+			replicas := int32(1)
+			if d.Spec.Replicas != nil {
+				replicas = *d.Spec.Replicas
+			}
+			available := false
+			for _, cond := range d.Status.Conditions {
+				if cond.Type == appsv1.DeploymentAvailable && cond.Status == v1.ConditionTrue {
+					available = true
+				}
+			}
+			if !available || d.Status.AvailableReplicas < replicas {
+				log.V(4).InfoS("Waiting for deployment to be available", "resource", c.namespacedName(&d), "availableReplicas", d.Status.AvailableReplicas, "replicas", replicas)
+				done = false
+			}
+		}
+		return done, nil
+	}
+}
+
 // DaemonSetReady is a helper function used to check if a daemonset's pods are scheduled and ready
 func (c *Condition) DaemonSetReady(daemonset k8s.Object) apimachinerywait.ConditionWithContextFunc {
 	return func(ctx context.Context) (done bool, err error) {
@@ -317,3 +362,611 @@ func (c *Condition) DaemonSetReady(daemonset k8s.Object) apimachinerywait.Condit
 		return
 	}
 }
+
+// ErrReadinessOrderUnknown is returned by StatefulSetPodsReadyInOrder when the order in which pods became ready
+// cannot be determined
+var ErrReadinessOrderUnknown = stderrors.New("readiness order unknown")
+
+// StatefulSetPodsReadyInOrder is a helper function used to check if all the pods of a StatefulSet are ready and
+// that they became ready in the order of their ordinals (0, 1, 2, ...), as guaranteed by the OrderedReady pod
+// management policy. This is stricter than checking the ready replicas and catches ordering regressions. The
+// readiness is tracked across the calls of the returned function, pods becoming ready between two calls are
+// ordered using the time of their transition to ready. The function returns an error including the observed
+// readiness sequence as soon as a pod becomes ready before a pod with a lower ordinal.
+//
+// The transition times have a resolution of one second, so the pods becoming ready within the same second and
+// observed by the same call cannot be ordered. The function returns an error wrapping ErrReadinessOrderUnknown
+// in that case rather than assuming they became ready in order. Using a short polling interval makes this less
+// likely, as the pods observed by different calls are ordered by the calls.
+func (c *Condition) StatefulSetPodsReadyInOrder(sts k8s.Object) apimachinerywait.ConditionWithContextFunc {
+	var sequence []int
+	seen := make(map[int]bool)
+	return func(ctx context.Context) (done bool, err error) {
+		if err := c.resources.Get(ctx, sts.GetName(), sts.GetNamespace(), sts); err != nil {
+			return false, err
+		}
+		statefulSet := sts.(*appsv1.StatefulSet)
+		selector, err := metav1.LabelSelectorAsSelector(statefulSet.Spec.Selector)
+		if err != nil {
+			return false, err
+		}
+		var pods v1.PodList
+		if err := c.resources.ListAcrossNamespaces(ctx, &pods, []string{statefulSet.Namespace}, resources.WithLabelSelector(selector.String())); err != nil {
+			return false, err
+		}
+
+		replicas := 1
+		if statefulSet.Spec.Replicas != nil {
+			replicas = int(*statefulSet.Spec.Replicas)
+		}
+		type readyPod struct {
+			ordinal int
+			since   time.Time
+		}
+		var newlyReady []readyPod
+		ready := 0
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			ordinal, err := strconv.Atoi(strings.TrimPrefix(pod.Name, statefulSet.Name+"-"))
+			if err != nil || !metav1.IsControlledBy(pod, statefulSet) {
+				continue
+			}
+			for _, cond := range pod.Status.Conditions {
+				if cond.Type != v1.PodReady || cond.Status != v1.ConditionTrue {
+					continue
+				}
+				if ordinal < replicas {
+					ready++
+				}
+				if !seen[ordinal] {
+					newlyReady = append(newlyReady, readyPod{ordinal: ordinal, since: cond.LastTransitionTime.Time})
+				}
+			}
+		}
+		// the ordinals only order the pods reported in the errors, not the ones that became ready at the same time
+		sort.Slice(newlyReady, func(i, j int) bool {
+			if !newlyReady[i].since.Equal(newlyReady[j].since) {
+				return newlyReady[i].since.Before(newlyReady[j].since)
+			}
+			return newlyReady[i].ordinal < newlyReady[j].ordinal
+		})
+		for start, end := 0, 0; start < len(newlyReady); start = end {
+			// the pods that became ready at the same time are checked together
+			var tied []int
+			for end = start; end < len(newlyReady) && newlyReady[end].since.Equal(newlyReady[start].since); end++ {
+				tied = append(tied, newlyReady[end].ordinal)
+				seen[newlyReady[end].ordinal] = true
+			}
+			sequence = append(sequence, tied...)
+			for _, ordinal := range tied {
+				for lower := 0; lower < ordinal; lower++ {
+					if !seen[lower] {
+						return false, fmt.Errorf("pod %s-%d of %s became ready before pod %s-%d, observed readiness sequence: %v",
+							statefulSet.Name, ordinal, c.namespacedName(statefulSet), statefulSet.Name, lower, sequence)
+					}
+				}
+			}
+			if len(tied) > 1 {
+				return false, fmt.Errorf("%w: pods %v of %s became ready within the same second, observed readiness sequence: %v",
+					ErrReadinessOrderUnknown, tied, c.namespacedName(statefulSet), sequence)
+			}
+		}
+		log.V(4).InfoS("Checking ordered readiness of statefulset pods", "resource", c.namespacedName(statefulSet), "ready", ready, "replicas", replicas, "sequence", sequence)
+		return ready == replicas, nil
+	}
+}
+
+// AllDaemonSetsReady is a helper function used to check if all the DaemonSets of the namespace have their pods
+// scheduled and ready on all the nodes they target, the same way DaemonSetReady does. The list options can be
+// used to narrow down the set of DaemonSets checked. A namespace without any DaemonSet is considered ready.
+func (c *Condition) AllDaemonSetsReady(namespace string, listOptions ...resources.ListOption) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		var daemonSets appsv1.DaemonSetList
+		if err := c.resources.ListAcrossNamespaces(ctx, &daemonSets, []string{namespace}, listOptions...); err != nil {
+			return false, err
+		}
+		done = true
+		for _, ds := range daemonSets.Items {
+			status := ds.Status
+			if status.NumberReady != status.DesiredNumberScheduled || status.NumberUnavailable != 0 {
+				log.V(4).InfoS("Waiting for daemonset to be ready", "resource", c.namespacedName(&ds), "numberReady", status.NumberReady, "desiredNumberScheduled", status.DesiredNumberScheduled)
+				done = false
+			}
+		}
+		return done, nil
+	}
+}
+
+// RolloutComplete is a helper function used to check if the latest rollout of a Deployment, StatefulSet or
+// DaemonSet is complete, using the same checks as kubectl rollout status: the controller has observed the
+// latest generation of the object and all the replicas run the latest pod template and are available.
+func (c *Condition) RolloutComplete(obj k8s.Object) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		if err := c.resources.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			return false, err
+		}
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			if o.Status.ObservedGeneration < o.Generation {
+				return false, nil
+			}
+			replicas := int32(1)
+			if o.Spec.Replicas != nil {
+				replicas = *o.Spec.Replicas
+			}
+			return o.Status.UpdatedReplicas == replicas && o.Status.Replicas == o.Status.UpdatedReplicas &&
+				o.Status.AvailableReplicas == o.Status.UpdatedReplicas, nil
+		case *appsv1.StatefulSet:
+			if o.Status.ObservedGeneration < o.Generation {
+				return false, nil
+			}
+			replicas := int32(1)
+			if o.Spec.Replicas != nil {
+				replicas = *o.Spec.Replicas
+			}
+			if o.Status.ReadyReplicas < replicas {
+				return false, nil
+			}
+			if rollingUpdate := o.Spec.UpdateStrategy.RollingUpdate; o.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType &&
+				rollingUpdate != nil && rollingUpdate.Partition != nil && *rollingUpdate.Partition > 0 {
+				return o.Status.UpdatedReplicas >= replicas-*rollingUpdate.Partition, nil
+			}
+			return o.Status.UpdateRevision == o.Status.CurrentRevision, nil
+		case *appsv1.DaemonSet:
+			if o.Status.ObservedGeneration < o.Generation {
+				return false, nil
+			}
+			return o.Status.UpdatedNumberScheduled == o.Status.DesiredNumberScheduled &&
+				o.Status.NumberAvailable == o.Status.DesiredNumberScheduled, nil
+		default:
+			return false, fmt.Errorf("rollout status is not supported for %T", obj)
+		}
+	}
+}
+
+// GenerationObserved is a helper function used to check if the controller of the object has observed its latest
+// spec, which is when the .status.observedGeneration of the object is at least its .metadata.generation. Waiting
+// for it before asserting on the status of the object ensures that the status does not reflect a previous spec.
+// The status is read generically, which supports the built-in resources as well as any custom resource
+// following the conventions. An object without .status.observedGeneration is considered not observed yet.
+func (c *Condition) GenerationObserved(obj k8s.Object) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		if err := c.resources.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			return false, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return false, err
+		}
+		observed, found, err := unstructured.NestedInt64(content, "status", "observedGeneration")
+		if err != nil {
+			return false, err
+		}
+		log.V(4).InfoS("Checking for observed generation", "resource", c.namespacedName(obj), "generation", obj.GetGeneration(), "observedGeneration", observed)
+		return found && observed >= obj.GetGeneration(), nil
+	}
+}
+
+// terminalConditionTypes are the condition types that indicate that an object will not reach the expected
+// state anymore when their status is v1.ConditionTrue
+var terminalConditionTypes = []string{"Degraded", "Failed"}
+
+// HasConditionOfType is a helper function used to check if the object has a condition of the provided type and
+// status in its .status.conditions. The conditions are read generically, which supports both the standard
+// []metav1.Condition slices and the older type specific condition slices, as long as they carry the type and
+// status fields, making this suitable for any custom resource following the conventions.
+//
+// Waiting stops with an error if the object reports a terminal condition, such as Degraded or Failed, with the
+// status True, unless that condition is the one being waited upon.
+func (c *Condition) HasConditionOfType(obj k8s.Object, condType string, status metav1.ConditionStatus) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		if err := c.resources.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			return false, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return false, err
+		}
+		conditions, _, err := unstructured.NestedSlice(content, "status", "conditions")
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range conditions {
+			fields, ok := cond.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			t, _ := fields["type"].(string)
+			s, _ := fields["status"].(string)
+			if t == condType && s == string(status) {
+				return true, nil
+			}
+			for _, terminal := range terminalConditionTypes {
+				if t == terminal && t != condType && s == string(metav1.ConditionTrue) {
+					message, _ := fields["message"].(string)
+					return false, fmt.Errorf("condition: %s reports terminal condition %s=True: %s", c.namespacedName(obj), t, message)
+				}
+			}
+		}
+		return false, nil
+	}
+}
+
+// HPAReplicas is a helper function used to check if the HorizontalPodAutoscaler has scaled its target to the
+// expected number of replicas, which is when both its current and desired replicas match want. The status is
+// read generically, which supports every version of the autoscaling API. The current metric values observed by
+// the autoscaler are logged on every poll to help understanding why the target is not scaled as expected.
+func (c *Condition) HPAReplicas(hpa k8s.Object, want int32) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		if err := c.resources.Get(ctx, hpa.GetName(), hpa.GetNamespace(), hpa); err != nil {
+			return false, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hpa)
+		if err != nil {
+			return false, err
+		}
+		current, _, _ := unstructured.NestedInt64(content, "status", "currentReplicas")
+		desired, _, _ := unstructured.NestedInt64(content, "status", "desiredReplicas")
+		// autoscaling/v1 only reports the CPU utilization while later versions report every metric
+		var metrics interface{}
+		if m, found, _ := unstructured.NestedSlice(content, "status", "currentMetrics"); found {
+			metrics = m
+		} else if m, found, _ := unstructured.NestedInt64(content, "status", "currentCPUUtilizationPercentage"); found {
+			metrics = fmt.Sprintf("cpu: %d%%", m)
+		}
+		log.V(4).InfoS("HorizontalPodAutoscaler replicas", "hpa", c.namespacedName(hpa), "current", current,
+			"desired", desired, "want", want, "metrics", fmt.Sprintf("%v", metrics))
+		return current == int64(want) && desired == int64(want), nil
+	}
+}
+
+// SecretHasKeys is a helper function used to wait for a Secret populated asynchronously by a controller, such as a
+// TLS certificate issued by cert-manager. The condition is met once the Secret exists and all the keys have a
+// non-empty value. The keys still missing are logged on each check.
+func (c *Condition) SecretHasKeys(secret k8s.Object, keys ...string) apimachinerywait.ConditionWithContextFunc {
+	return c.hasKeys(secret, keys, func() []string {
+		var present []string
+		for key, value := range secret.(*v1.Secret).Data {
+			if len(value) > 0 {
+				present = append(present, key)
+			}
+		}
+		return present
+	})
+}
+
+// ConfigMapHasKeys is a helper function used to wait for a ConfigMap populated asynchronously by a controller. The
+// condition is met once the ConfigMap exists and all the keys have a non-empty value, either in its data or in its
+// binary data. The keys still missing are logged on each check.
+func (c *Condition) ConfigMapHasKeys(configMap k8s.Object, keys ...string) apimachinerywait.ConditionWithContextFunc {
+	return c.hasKeys(configMap, keys, func() []string {
+		cm := configMap.(*v1.ConfigMap)
+		var present []string
+		for key, value := range cm.Data {
+			if value != "" {
+				present = append(present, key)
+			}
+		}
+		for key, value := range cm.BinaryData {
+			if len(value) > 0 {
+				present = append(present, key)
+			}
+		}
+		return present
+	})
+}
+
+// hasKeys fetches the object and checks that presentKeys, which reads the keys with a non-empty value from the
+// fetched object, returns all the keys
+func (c *Condition) hasKeys(obj k8s.Object, keys []string, presentKeys func() []string) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		if err := c.resources.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			log.V(4).InfoS("Waiting for resource to be created", "resource", c.namespacedName(obj), "error", err)
+			return false, nil
+		}
+		present := make(map[string]bool)
+		for _, key := range presentKeys() {
+			present[key] = true
+		}
+		var missing []string
+		for _, key := range keys {
+			if !present[key] {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			log.V(4).InfoS("Waiting for keys to be populated", "resource", c.namespacedName(obj), "missing", missing)
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+// NoPodsCrashLooping is a helper function used to check that none of the pods matching the list options has a
+// container waiting in CrashLoopBackOff. The pods are listed from the namespace of the resources, or from all the
+// namespaces if none is set. The condition returns an error naming the offending pod and container as soon as one
+// is found, which makes it suitable as a smoke test gate after deploying a workload.
+func (c *Condition) NoPodsCrashLooping(listOptions ...resources.ListOption) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		var pods v1.PodList
+		if err := c.resources.List(ctx, &pods, listOptions...); err != nil {
+			return false, err
+		}
+		for _, pod := range pods.Items {
+			statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+			for _, status := range statuses {
+				if waiting := status.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+					return false, fmt.Errorf("container %s of pod %s/%s is in CrashLoopBackOff (restarts: %d): %s",
+						status.Name, pod.Namespace, pod.Name, status.RestartCount, waiting.Message)
+				}
+			}
+		}
+		return true, nil
+	}
+}
+
+// NodesReady is a helper function used to check if the cluster has at least one node and all of its nodes
+// have the v1.NodeReady condition set to v1.ConditionTrue
+func (c *Condition) NodesReady() apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		var nodes v1.NodeList
+		if err := c.resources.List(ctx, &nodes); err != nil {
+			return false, err
+		}
+		if len(nodes.Items) == 0 {
+			return false, nil
+		}
+		for _, node := range nodes.Items {
+			ready := false
+			for _, cond := range node.Status.Conditions {
+				if cond.Type == v1.NodeReady && cond.Status == v1.ConditionTrue {
+					ready = true
+				}
+			}
+			if !ready {
+				log.V(4).InfoS("Node is not ready yet", "node", node.Name)
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// SchedulableCapacity is a helper function used to check if the nodes that are Ready and not cordoned have, in
+// total, at least the provided CPU and memory allocatable to pods, as computed by resources.Resources.NodeAllocatable.
+// The requests of the pods already running are not deducted, so this tells whether the cluster is big enough for a
+// workload rather than whether it currently has room for it, which lets a feature wait for nodes to join or be
+// skipped instead of timing out on pods failing to be scheduled.
+func (c *Condition) SchedulableCapacity(cpu, mem resource.Quantity) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		allocatableCPU, allocatableMem, err := c.resources.NodeAllocatable(ctx)
+		if err != nil {
+			return false, err
+		}
+		log.V(4).InfoS("Checking for schedulable capacity", "cpu", allocatableCPU.String(), "memory", allocatableMem.String(), "wantCPU", cpu.String(), "wantMemory", mem.String())
+		return allocatableCPU.Cmp(cpu) >= 0 && allocatableMem.Cmp(mem) >= 0, nil
+	}
+}
+
+// LeaseHeld is a helper function used to check if the coordination.k8s.io Lease, such as the one used for the
+// leader election of a controller, is held, which is when it has a holder and was renewed within its lease
+// duration. A missing Lease is not held.
+func (c *Condition) LeaseHeld(name, namespace string) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		holder, err := c.leaseHolder(ctx, name, namespace)
+		return holder != "", err
+	}
+}
+
+// LeaseHolderChanged is a helper function used to check if the coordination.k8s.io Lease is held, as checked by
+// LeaseHeld, by another holder than previousHolder, which makes it possible to assert that the leadership fails
+// over to another replica once the leader is gone.
+func (c *Condition) LeaseHolderChanged(name, namespace, previousHolder string) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		holder, err := c.leaseHolder(ctx, name, namespace)
+		return holder != "" && holder != previousHolder, err
+	}
+}
+
+// leaseHolder returns the holder of the Lease, or an empty string if the Lease is missing, has no holder or has
+// not been renewed within its lease duration
+func (c *Condition) leaseHolder(ctx context.Context, name, namespace string) (string, error) {
+	var lease coordinationv1.Lease
+	if err := c.resources.Get(ctx, name, namespace, &lease); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" {
+		log.V(4).InfoS("Lease has no holder", "resource", c.namespacedName(&lease))
+		return "", nil
+	}
+	if spec.RenewTime != nil && spec.LeaseDurationSeconds != nil {
+		expiry := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+		if time.Now().After(expiry) {
+			log.V(4).InfoS("Lease has expired", "resource", c.namespacedName(&lease), "holder", *spec.HolderIdentity, "renewTime", spec.RenewTime)
+			return "", nil
+		}
+	}
+	return *spec.HolderIdentity, nil
+}
+
+// APIServerReady is a helper function used to check if the API server is reachable and reports itself as healthy
+// by performing a lightweight GET request against its /healthz endpoint. Unlike the checks performed by the cluster
+// providers, this does not wait for any of the system addons to be running, which makes it suitable for early setup
+// steps that only need to talk to the API server.
+func (c *Condition) APIServerReady() apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		clientset, err := kubernetes.NewForConfig(c.resources.GetConfig())
+		if err != nil {
+			return false, err
+		}
+		var statusCode int
+		result := clientset.Discovery().RESTClient().Get().AbsPath("/healthz").Do(ctx).StatusCode(&statusCode)
+		if result.Error() != nil {
+			log.V(4).InfoS("API server is not ready yet", "error", result.Error())
+			return false, nil
+		}
+		return statusCode == http.StatusOK, nil
+	}
+}
+
+// WebhookReady is a helper function used to check if all the webhooks of a ValidatingWebhookConfiguration or a
+// MutatingWebhookConfiguration backed by a service have at least one ready endpoint. Waiting for this before
+// creating the resources guarded by the webhooks avoids the connection refused errors returned by the API server
+// while the webhook pods are starting. Webhooks configured with a URL are not checked.
+func (c *Condition) WebhookReady(config k8s.Object) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		services, err := c.webhookServices(ctx, config)
+		if err != nil {
+			return false, err
+		}
+		for _, svc := range services {
+			ready, err := c.readyEndpoints(ctx, svc.Name, svc.Namespace)
+			if err != nil {
+				return false, err
+			}
+			if ready == 0 {
+				log.V(4).InfoS("Webhook service has no ready endpoints yet", "service", svc.Namespace+"/"+svc.Name)
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// EndpointSlicesReady is a helper function used to check if the Service has at least minReady ready endpoints,
+// aggregated across all the EndpointSlices of the Service. Unlike the legacy Endpoints object, this accounts for
+// the services whose endpoints are split across several slices, such as large or dual-stack services. An endpoint
+// listed in several slices, such as a pod with an IPv4 and an IPv6 address, is only counted once.
+func (c *Condition) EndpointSlicesReady(svc k8s.Object, minReady int) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		ready, err := c.readyEndpoints(ctx, svc.GetName(), svc.GetNamespace())
+		if err != nil {
+			return false, err
+		}
+		log.V(4).InfoS("Checking ready endpoints of service", "service", svc.GetNamespace()+"/"+svc.GetName(), "ready", ready, "minReady", minReady)
+		return ready >= minReady, nil
+	}
+}
+
+// readyEndpoints returns the number of distinct ready endpoints of the EndpointSlices of the service. An endpoint
+// without a ready condition is considered ready, as documented by the EndpointSlice API.
+func (c *Condition) readyEndpoints(ctx context.Context, name, namespace string) (int, error) {
+	var slices discoveryv1.EndpointSliceList
+	selector := resources.WithLabelSelector(discoveryv1.LabelServiceName + "=" + name)
+	if err := c.resources.ListAcrossNamespaces(ctx, &slices, []string{namespace}, selector); err != nil {
+		return 0, err
+	}
+	ready := make(map[string]bool)
+	for _, slice := range slices.Items {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			switch {
+			case endpoint.TargetRef != nil:
+				ready[endpoint.TargetRef.Kind+"/"+endpoint.TargetRef.Namespace+"/"+endpoint.TargetRef.Name] = true
+			case len(endpoint.Addresses) > 0:
+				ready[endpoint.Addresses[0]] = true
+			}
+		}
+	}
+	return len(ready), nil
+}
+
+// WebhookServing is a helper function used to check if all the webhooks of a ValidatingWebhookConfiguration or a
+// MutatingWebhookConfiguration backed by a service are serving requests. In addition to the checks performed by
+// WebhookReady, each webhook is probed through the API server service proxy. Any response from the webhook server,
+// including an error status for the probe request, indicates that it is serving.
+func (c *Condition) WebhookServing(config k8s.Object) apimachinerywait.ConditionWithContextFunc {
+	endpointsReady := c.WebhookReady(config)
+	return func(ctx context.Context) (done bool, err error) {
+		if done, err := endpointsReady(ctx); !done || err != nil {
+			return done, err
+		}
+		services, err := c.webhookServices(ctx, config)
+		if err != nil {
+			return false, err
+		}
+		clientset, err := kubernetes.NewForConfig(c.resources.GetConfig())
+		if err != nil {
+			return false, err
+		}
+		for _, svc := range services {
+			port := "443"
+			if svc.Port != nil {
+				port = fmt.Sprint(*svc.Port)
+			}
+			path := ""
+			if svc.Path != nil {
+				path = *svc.Path
+			}
+			_, err := clientset.CoreV1().Services(svc.Namespace).ProxyGet("https", svc.Name, port, path, nil).DoRaw(ctx)
+			var statusErr *errors.StatusError
+			if err != nil && (!stderrors.As(err, &statusErr) || errors.IsServiceUnavailable(err) || statusErr.ErrStatus.Code == http.StatusBadGateway) {
+				log.V(4).InfoS("Webhook is not serving yet", "service", svc.Namespace+"/"+svc.Name, "error", err)
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// webhookServices fetches the webhook configuration and returns the references to the services backing its webhooks
+func (c *Condition) webhookServices(ctx context.Context, config k8s.Object) ([]*admissionregistrationv1.ServiceReference, error) {
+	if err := c.resources.Get(ctx, config.GetName(), "", config); err != nil {
+		return nil, err
+	}
+	var services []*admissionregistrationv1.ServiceReference
+	switch cfg := config.(type) {
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		for _, w := range cfg.Webhooks {
+			if w.ClientConfig.Service != nil {
+				services = append(services, w.ClientConfig.Service)
+			}
+		}
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		for _, w := range cfg.Webhooks {
+			if w.ClientConfig.Service != nil {
+				services = append(services, w.ClientConfig.Service)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("condition: unexpected type %T, expecting a ValidatingWebhookConfiguration or a MutatingWebhookConfiguration", config)
+	}
+	return services, nil
+}
+
+// NodeHasTaint is a helper function used to check if the node has the taint in its .spec.taints, for instance once
+// it has been cordoned or a controller has reacted to a disruption. Taints are matched on their key and effect, and
+// on their value only when the value of the expected taint is not empty.
+func (c *Condition) NodeHasTaint(name string, taint v1.Taint) apimachinerywait.ConditionWithContextFunc {
+	return c.nodeTaintMatch(name, taint, true)
+}
+
+// NodeLacksTaint is a helper function used to check if the taint has been removed from the .spec.taints of the
+// node, using the same matching as NodeHasTaint.
+func (c *Condition) NodeLacksTaint(name string, taint v1.Taint) apimachinerywait.ConditionWithContextFunc {
+	return c.nodeTaintMatch(name, taint, false)
+}
+
+func (c *Condition) nodeTaintMatch(name string, taint v1.Taint, present bool) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		node := &v1.Node{}
+		log.V(4).InfoS("Checking for node taint", "node", name, "key", taint.Key, "effect", taint.Effect, "present", present)
+		if err := c.resources.Get(ctx, name, "", node); err != nil {
+			return false, err
+		}
+		found := false
+		for _, t := range node.Spec.Taints {
+			if t.Key == taint.Key && t.Effect == taint.Effect && (taint.Value == "" || t.Value == taint.Value) {
+				found = true
+				break
+			}
+		}
+		return found == present, nil
+	}
+}
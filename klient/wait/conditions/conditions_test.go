@@ -0,0 +1,252 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+)
+
+// newFakeResources returns Resources reading objs through the fake client of controller runtime
+func newFakeResources(t *testing.T, objs ...k8s.Object) *resources.Resources {
+	t.Helper()
+	return newFakeResourcesWithClient(t, fake.NewClientBuilder().WithScheme(scheme.Scheme), objs...)
+}
+
+// newFakeResourcesWithClient returns Resources reading objs through the fake client built by builder
+func newFakeResourcesWithClient(t *testing.T, builder *fake.ClientBuilder, objs ...k8s.Object) *resources.Resources {
+	t.Helper()
+	for _, obj := range objs {
+		builder = builder.WithObjects(obj)
+	}
+	res, err := resources.New(&rest.Config{}, resources.WithClient(builder.Build()))
+	if err != nil {
+		t.Fatalf("unexpected error creating resources: %s", err)
+	}
+	return res
+}
+
+// failingListResources returns Resources whose List calls fail with err
+func failingListResources(t *testing.T, err error) *resources.Resources {
+	t.Helper()
+	return newFakeResourcesWithClient(t, fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, client cr.WithWatch, list cr.ObjectList, opts ...cr.ListOption) error {
+			return err
+		},
+	}))
+}
+
+func TestResourceListMatchN(t *testing.T) {
+	res := newFakeResources(t,
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p1", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "node-1"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p2", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "node-2"}},
+	)
+	tests := []struct {
+		name  string
+		node  string
+		n     int
+		match bool
+	}{
+		{name: "matching predicate", node: "node-1", n: 1, match: true},
+		{name: "too few matches", node: "node-1", n: 2, match: false},
+		{name: "non-matching predicate", node: "no-such-node", n: 1, match: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			done, err := conditions.New(res).ResourceListMatchN(&v1.PodList{}, tc.n, func(object k8s.Object) bool {
+				return object.(*v1.Pod).Spec.NodeName == tc.node
+			})(context.TODO())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if done != tc.match {
+				t.Errorf("expected the condition to be met %t, got %t", tc.match, done)
+			}
+		})
+	}
+}
+
+func TestAllDaemonSetsReady(t *testing.T) {
+	res := newFakeResources(t,
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "kube-system"}, Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, NumberReady: 2}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "starting", Namespace: "other"}, Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, NumberReady: 1, NumberUnavailable: 1}},
+	)
+	if done, err := conditions.New(res).AllDaemonSetsReady("kube-system")(context.TODO()); err != nil || !done {
+		t.Errorf("expected the daemonsets of kube-system to be ready, got %t, %v", done, err)
+	}
+	if done, err := conditions.New(res).AllDaemonSetsReady("other")(context.TODO()); err != nil || done {
+		t.Errorf("expected the daemonsets of other not to be ready, got %t, %v", done, err)
+	}
+
+	errList := errors.New("forbidden")
+	if _, err := conditions.New(failingListResources(t, errList)).AllDaemonSetsReady("kube-system")(context.TODO()); !errors.Is(err, errList) {
+		t.Errorf("expected the list error to be returned, got: %v", err)
+	}
+}
+
+func TestAllDeploymentsAvailable(t *testing.T) {
+	replicas := int32(2)
+	deployment := func(name string, available int32, condition v1.ConditionStatus) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "release"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{
+				AvailableReplicas: available,
+				Conditions:        []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: condition}},
+			},
+		}
+	}
+
+	done, err := conditions.New(newFakeResources(t, deployment("api", 2, v1.ConditionTrue))).AllDeploymentsAvailable("release")(context.TODO())
+	if err != nil || !done {
+		t.Errorf("expected the deployments to be available, got done=%v err=%v", done, err)
+	}
+
+	done, err = conditions.New(newFakeResources(t, deployment("api", 2, v1.ConditionTrue), deployment("worker", 1, v1.ConditionTrue))).AllDeploymentsAvailable("release")(context.TODO())
+	if err != nil || done {
+		t.Errorf("expected a deployment to be unavailable, got done=%v err=%v", done, err)
+	}
+
+	errList := errors.New("forbidden")
+	if _, err := conditions.New(failingListResources(t, errList)).AllDeploymentsAvailable("release")(context.TODO()); !errors.Is(err, errList) {
+		t.Errorf("expected the list error to be returned, got: %v", err)
+	}
+}
+
+func TestNoPodsCrashLooping(t *testing.T) {
+	running := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "default"},
+		Status:     v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{Name: "app", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}}}},
+	}
+	done, err := conditions.New(newFakeResources(t, running)).NoPodsCrashLooping()(context.TODO())
+	if err != nil || !done {
+		t.Errorf("expected no crash looping pod to be found, got done=%v err=%v", done, err)
+	}
+
+	crashing := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "crashing", Namespace: "default"},
+		Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+			Name:         "app",
+			RestartCount: 3,
+			State:        v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		}}},
+	}
+	_, err = conditions.New(newFakeResources(t, running, crashing)).NoPodsCrashLooping()(context.TODO())
+	if err == nil || !strings.Contains(err.Error(), "container app of pod default/crashing") {
+		t.Errorf("expected the crash looping container to be reported, got: %v", err)
+	}
+
+	errList := errors.New("forbidden")
+	if _, err := conditions.New(failingListResources(t, errList)).NoPodsCrashLooping()(context.TODO()); !errors.Is(err, errList) {
+		t.Errorf("expected the list error to be returned, got: %v", err)
+	}
+}
+
+// statefulSetPods returns a StatefulSet with a pod per readiness time, the pod of ordinal i becoming ready at
+// readyAt[i], or not being ready if readyAt[i] is zero
+func statefulSetPods(readyAt ...time.Time) []k8s.Object {
+	replicas := int32(len(readyAt))
+	controller := true
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "default", UID: "ss-uid"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "ss"}},
+		},
+	}
+	objs := []k8s.Object{sts}
+	for i, at := range readyAt {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("ss-%d", i),
+			Namespace:       "default",
+			Labels:          map[string]string{"app": "ss"},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "ss", UID: "ss-uid", Controller: &controller}},
+		}}
+		if !at.IsZero() {
+			pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue, LastTransitionTime: metav1.NewTime(at)}}
+		}
+		objs = append(objs, pod)
+	}
+	return objs
+}
+
+func TestStatefulSetPodsReadyInOrder(t *testing.T) {
+	t0 := time.Now().Truncate(time.Second)
+	tests := []struct {
+		name    string
+		readyAt []time.Time
+		done    bool
+		err     string
+		unknown bool
+	}{
+		{
+			name:    "in order",
+			readyAt: []time.Time{t0, t0.Add(time.Second), t0.Add(2 * time.Second)},
+			done:    true,
+		},
+		{
+			name:    "not all ready",
+			readyAt: []time.Time{t0, t0.Add(time.Second), {}},
+		},
+		{
+			name:    "out of order",
+			readyAt: []time.Time{t0.Add(time.Second), t0, t0.Add(2 * time.Second)},
+			err:     "became ready before pod ss-0, observed readiness sequence: [1]",
+		},
+		{
+			name:    "ready within the same second",
+			readyAt: []time.Time{t0, t0.Add(time.Second), t0.Add(time.Second)},
+			err:     "became ready within the same second, observed readiness sequence: [0 1 2]",
+			unknown: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			objs := statefulSetPods(tc.readyAt...)
+			done, err := conditions.New(newFakeResources(t, objs...)).StatefulSetPodsReadyInOrder(objs[0])(context.TODO())
+			if tc.err == "" {
+				if err != nil || done != tc.done {
+					t.Errorf("expected the condition to be met %t, got %t, %v", tc.done, done, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected an error containing %q, got: %v", tc.err, err)
+			}
+			if errors.Is(err, conditions.ErrReadinessOrderUnknown) != tc.unknown {
+				t.Errorf("expected the readiness order to be unknown %t, got: %v", tc.unknown, err)
+			}
+		})
+	}
+}
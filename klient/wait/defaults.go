@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	log "k8s.io/klog/v2"
+)
+
+// TimeoutEnvVar is the environment variable that can be set to the default timeout of the waits, as a
+// duration such as 10m, for instance to give more time to the waits on slow CI runners without changing
+// the tests.
+//
+// The timeout of a wait is the one configured using WithTimeout, if any, otherwise the one set by this
+// environment variable, if any, otherwise the package default set using SetDefaultTimeout.
+const TimeoutEnvVar = "E2E_WAIT_TIMEOUT"
+
+// defaults are the timeout and interval the waits use when they are not configured per call
+var defaults = struct {
+	sync.RWMutex
+	timeout    time.Duration
+	envTimeout time.Duration
+	interval   time.Duration
+}{timeout: defaultPollTimeout, interval: defaultPollInterval}
+
+func init() {
+	if value := os.Getenv(TimeoutEnvVar); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			log.Warningf("ignoring invalid %s value %q: must be a positive duration", TimeoutEnvVar, value)
+		} else {
+			defaults.envTimeout = timeout
+		}
+	}
+}
+
+// SetDefaultTimeout sets the timeout used by the waits that are not configured using WithTimeout, unless
+// the TimeoutEnvVar environment variable is set. The package default is 5 minutes.
+func SetDefaultTimeout(timeout time.Duration) {
+	defaults.Lock()
+	defer defaults.Unlock()
+	defaults.timeout = timeout
+}
+
+// SetDefaultInterval sets the poll interval used by the waits that are not configured using WithInterval.
+// The package default is 5 seconds.
+func SetDefaultInterval(interval time.Duration) {
+	defaults.Lock()
+	defer defaults.Unlock()
+	defaults.interval = interval
+}
+
+// newOptions returns the options of a wait initialized with the default timeout and interval
+func newOptions() *Options {
+	defaults.RLock()
+	defer defaults.RUnlock()
+	timeout := defaults.timeout
+	if defaults.envTimeout > 0 {
+		timeout = defaults.envTimeout
+	}
+	return &Options{Interval: defaults.interval, Timeout: timeout}
+}
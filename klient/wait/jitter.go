@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// jitterRand is the random source the jitter of the poll intervals is drawn from
+var jitterRand = struct {
+	sync.Mutex
+	rand *rand.Rand
+}{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// SetJitterSeed seeds the random source the jitter configured using WithJitter is drawn from. The source is
+// seeded using the current time by default.
+func SetJitterSeed(seed int64) {
+	jitterRand.Lock()
+	defer jitterRand.Unlock()
+	jitterRand.rand = rand.New(rand.NewSource(seed))
+}
+
+// jittered returns the interval randomized by up to ±fraction of the interval
+func jittered(interval time.Duration, fraction float64) time.Duration {
+	jitterRand.Lock()
+	factor := 1 + fraction*(2*jitterRand.rand.Float64()-1)
+	jitterRand.Unlock()
+	return time.Duration(float64(interval) * factor)
+}
+
+// poll checks the condition on each interval until it is met, returns an error or ctx is done, the same way
+// apimachinerywait.PollUntilContextCancel does, randomizing the intervals when a jitter is configured
+func poll(ctx context.Context, options *Options, condition apimachinerywait.ConditionWithContextFunc) error {
+	if options.Jitter <= 0 {
+		return apimachinerywait.PollUntilContextCancel(ctx, options.Interval, options.Immediate, condition)
+	}
+	if options.Immediate {
+		if done, err := condition(ctx); err != nil || done {
+			return err
+		}
+	}
+	for {
+		timer := time.NewTimer(jittered(options.Interval, options.Jitter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if done, err := condition(ctx); err != nil || done {
+			return err
+		}
+	}
+}
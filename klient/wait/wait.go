@@ -18,6 +18,8 @@ package wait
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"time"
 
 	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
@@ -39,16 +41,24 @@ type Options struct {
 	// Immediate is used to indicate if the apimachinerywait's immediate wait method are to be
 	// called instead of the regular one
 	Immediate bool
+	// Jitter is the fraction by which each poll interval is randomized, in [0, 1]
+	Jitter float64
+
+	// timeoutSet reports whether the timeout was configured using WithTimeout
+	timeoutSet bool
 }
 
 type Option func(*Options)
 
 // WithTimeout sets the max timeout that the Wait checks will run trying to see if the resource under
 // question has reached a final expected state. An error will be raised if the resource has not reached
-// the final expected state within the time defined by this configuration
+// the final expected state within the time defined by this configuration. It takes precedence over the
+// default timeout set using TimeoutEnvVar or SetDefaultTimeout. When the context of the wait has a deadline,
+// the earlier of the deadline and the timeout applies.
 func WithTimeout(timeout time.Duration) Option {
 	return func(options *Options) {
 		options.Timeout = timeout
+		options.timeoutSet = true
 	}
 }
 
@@ -62,7 +72,9 @@ func WithInterval(interval time.Duration) Option {
 
 // WithContext provides a way to configure a context that can be used to cancel the wait condition checks. This will enable
 // end users to write test in cases where the max timeout is not really predictable or is a factor of a different
-// configuration or event.
+// configuration or event. The deadline of the context, if any, is the timeout of the wait unless a timeout is
+// configured using WithTimeout, in which case the earlier of the two applies. Without a deadline nor a timeout
+// configured using WithTimeout, the wait only stops when the context is done.
 func WithContext(ctx context.Context) Option {
 	return func(options *Options) {
 		options.Ctx = ctx
@@ -71,13 +83,24 @@ func WithContext(ctx context.Context) Option {
 
 // WithImmediate configures the way the Wait Checks are invoked. Setting this will invoke the condition check
 // right away before the first wait for the interval kicks in. If not configured, the first check of the
-// condition match will be triggered after the value configured by the WithInterval or defaultPollInterval
+// condition match will be triggered after the value configured by the WithInterval or SetDefaultInterval
 func WithImmediate() Option {
 	return func(options *Options) {
 		options.Immediate = true
 	}
 }
 
+// WithJitter randomizes each poll interval by up to ±fraction of the interval, so that many waits polling in
+// parallel on the same interval do not synchronize and hit the API server all at once. For instance, a fraction
+// of 0.2 spreads a 5s interval between 4s and 6s. The fraction is clamped to [0, 1] and there is no jitter by
+// default. The jitter is drawn from a random source seeded using SetJitterSeed, which the environments launched
+// using Run do with the seed of the random names, so that a run can be reproduced.
+func WithJitter(fraction float64) Option {
+	return func(options *Options) {
+		options.Jitter = math.Max(0, math.Min(1, fraction))
+	}
+}
+
 // For provides a way to perform poll checks against the kubernetes resource to make sure the resource under
 // test has reached a suitable state before moving to the next action or fail with an error message.
 //
@@ -85,23 +108,78 @@ func WithImmediate() Option {
 // or a custom wait function can be passed as an argument to get a similar functionality if the check required
 // for your test is not already provided by the helper utility.
 func For(conditionFunc apimachinerywait.ConditionWithContextFunc, opts ...Option) error {
-	options := &Options{
-		Interval:  defaultPollInterval,
-		Timeout:   defaultPollTimeout,
-		Ctx:       nil,
-		Immediate: false,
-	}
-	var cancel context.CancelFunc
+	options := newOptions()
 	for _, fn := range opts {
 		fn(options)
 	}
 
-	if options.Ctx == nil {
-		options.Ctx, cancel = context.WithTimeout(context.Background(), options.Timeout)
-		defer cancel()
+	ctx, cancel := options.context(context.Background())
+	defer cancel()
+	return poll(ctx, options, conditionFunc)
+}
+
+// ForFunc polls fn until it reports that it is done, using the same interval and timeout options as For.
+// This makes it possible to wait for arbitrary state, such as an HTTP endpoint or a file, without writing
+// a polling loop. Polling stops as soon as fn returns an error, which is returned as is, or when ctx is
+// done. When ctx has a deadline, it is the timeout of the wait unless a timeout is configured using
+// WithTimeout, in which case the earlier of the two applies. The context configured using WithContext,
+// if any, takes precedence over ctx.
+func ForFunc(ctx context.Context, fn func(ctx context.Context) (done bool, err error), opts ...Option) error {
+	options := newOptions()
+	for _, opt := range opts {
+		opt(options)
 	}
-	if options.Immediate {
-		return apimachinerywait.PollUntilContextCancel(options.Ctx, options.Interval, true, conditionFunc)
+
+	ctx, cancel := options.context(ctx)
+	defer cancel()
+	return poll(ctx, options, fn)
+}
+
+// context returns the context bounding the wait, derived from the context configured using WithContext or from
+// parent otherwise. The deadline of the context is the timeout of the wait unless a timeout was configured using
+// WithTimeout, in which case the earlier of the two applies. The default timeout only applies when there is
+// neither a deadline nor a context configured using WithContext.
+func (o *Options) context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx := parent
+	if o.Ctx != nil {
+		ctx = o.Ctx
+	}
+	_, hasDeadline := ctx.Deadline()
+	if !o.timeoutSet && (hasDeadline || o.Ctx != nil) {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.Timeout)
+}
+
+// ForStable polls getCount until the count it returns has been equal to want for at least stableFor, using the same
+// interval and timeout options as ForFunc. Any deviation from want restarts the stability period, which catches
+// flapping and leaks that a single check would miss, for instance after repeated create and delete cycles. The
+// count is only sampled at each poll, so the interval bounds the deviations that can be detected. Polling stops as
+// soon as getCount returns an error, which is returned as is. The timeout must be longer than stableFor.
+func ForStable(ctx context.Context, getCount func() (int, error), want int, stableFor time.Duration, opts ...Option) error {
+	var stableSince time.Time
+	var countErr error
+	count := -1
+	err := ForFunc(ctx, func(ctx context.Context) (bool, error) {
+		count, countErr = getCount()
+		if countErr != nil {
+			return false, countErr
+		}
+		if count != want {
+			stableSince = time.Time{}
+			return false, nil
+		}
+		if stableSince.IsZero() {
+			stableSince = time.Now()
+		}
+		return time.Since(stableSince) >= stableFor, nil
+	}, opts...)
+	if err != nil && countErr == nil && count >= 0 {
+		stable := time.Duration(0)
+		if !stableSince.IsZero() {
+			stable = time.Since(stableSince)
+		}
+		return fmt.Errorf("count %d, expected %d, stable for %s of %s: %w", count, want, stable.Round(time.Millisecond), stableFor, err)
 	}
-	return apimachinerywait.PollUntilContextCancel(options.Ctx, options.Interval, false, conditionFunc)
+	return err
 }
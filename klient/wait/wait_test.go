@@ -18,13 +18,20 @@ package wait_test
 
 import (
 	"context"
+	"errors"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	log "k8s.io/klog/v2"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 
@@ -32,6 +39,7 @@ import (
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/klient/wait"
 	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/klient/wait/waittest"
 )
 
 func TestPodRunning(t *testing.T) {
@@ -247,3 +255,514 @@ func TestDeploymentAvailable(t *testing.T) {
 		t.Error("failed waiting for deployment to become available")
 	}
 }
+
+func TestAPIServerReady(t *testing.T) {
+	err := wait.For(conditions.New(getResourceManager()).APIServerReady(), wait.WithImmediate(), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("failed waiting for api server to be ready", err)
+	}
+}
+
+func TestNodesReady(t *testing.T) {
+	err := wait.For(conditions.New(getResourceManager()).NodesReady(), wait.WithImmediate(), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("failed waiting for nodes to be ready", err)
+	}
+}
+
+func TestWebhookReady(t *testing.T) {
+	createPod("p13", t)
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-svc", Namespace: namespace},
+		Spec: v1.ServiceSpec{
+			Selector: map[string]string{"app": "p13"},
+			Ports:    []v1.ServicePort{{Port: 80}},
+		},
+	}
+	if err := getResourceManager().Create(context.TODO(), svc); err != nil {
+		t.Fatal("failed to create webhook service", err)
+	}
+
+	port := int32(80)
+	path := "/validate"
+	failurePolicy := admissionregistrationv1.Ignore
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "e2e-webhook-ready"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: "validate.e2e.example.com",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Name: svc.Name, Namespace: namespace, Port: &port, Path: &path},
+			},
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+				Rule:       admissionregistrationv1.Rule{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"configmaps"}},
+			}},
+			ObjectSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"e2e-webhook-test": "true"}},
+			FailurePolicy:           &failurePolicy,
+			SideEffects:             &sideEffects,
+			AdmissionReviewVersions: []string{"v1"},
+		}},
+	}
+	if err := getResourceManager().Create(context.TODO(), config); err != nil {
+		t.Fatal("failed to create webhook configuration", err)
+	}
+
+	err := wait.For(conditions.New(getResourceManager()).WebhookReady(config), wait.WithImmediate(), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Error("failed waiting for webhook to be ready", err)
+	}
+}
+
+func TestRolloutComplete(t *testing.T) {
+	deployment := createDeployment("d8", 2, t)
+	err := wait.For(conditions.New(getResourceManager()).RolloutComplete(deployment), wait.WithImmediate(), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Fatal("failed waiting for initial rollout to complete", err)
+	}
+	generation := deployment.Generation
+
+	if err := getResourceManager().RolloutRestart(context.TODO(), deployment); err != nil {
+		t.Fatal("failed to restart rollout", err)
+	}
+	if deployment.Generation <= generation {
+		t.Errorf("expected the generation to be bumped by the restart, got %d", deployment.Generation)
+	}
+	if deployment.Spec.Template.Annotations[resources.RestartedAtAnnotation] == "" {
+		t.Error("expected the pod template to have the restart annotation")
+	}
+	err = wait.For(conditions.New(getResourceManager()).RolloutComplete(deployment), wait.WithImmediate(), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Error("failed waiting for restarted rollout to complete", err)
+	}
+
+	if err := getResourceManager().RolloutRestart(context.TODO(), &v1.Pod{}); err == nil {
+		t.Error("expected rollout restart of a pod to fail")
+	}
+}
+
+func TestGenerationObserved(t *testing.T) {
+	deployment := createDeployment("d12", 1, t)
+	err := wait.For(conditions.New(getResourceManager()).GenerationObserved(deployment), wait.WithImmediate(), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatal("failed waiting for the generation to be observed", err)
+	}
+	if deployment.Status.ObservedGeneration != deployment.Generation {
+		t.Errorf("expected the observed generation %d to match the generation %d", deployment.Status.ObservedGeneration, deployment.Generation)
+	}
+}
+
+func TestHPAReplicas(t *testing.T) {
+	deployment := createDeployment("d9", 1, t)
+	minReplicas := int32(2)
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "d9", Namespace: namespace},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: deployment.Name},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    3,
+		},
+	}
+	if err := getResourceManager().Create(context.TODO(), hpa); err != nil {
+		t.Fatal("failed to create horizontal pod autoscaler", err)
+	}
+	// the autoscaler scales the deployment up to its minimum number of replicas, even without metrics
+	err := wait.For(conditions.New(getResourceManager()).HPAReplicas(hpa, minReplicas), wait.WithImmediate(), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Error("failed waiting for horizontal pod autoscaler to scale the deployment", err)
+	}
+}
+
+func TestAllDeploymentsAvailable(t *testing.T) {
+	createDeployment("d10", 2, t)
+	createDeployment("d11", 1, t)
+	err := wait.For(conditions.New(getResourceManager()).AllDeploymentsAvailable(namespace, resources.WithLabelSelector("app in (d10,d11)")), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Error("failed waiting for all the deployments to become available", err)
+	}
+	log.Info("Done")
+}
+
+func TestAllDaemonSetsReady(t *testing.T) {
+	// the kube-proxy and kindnet daemonsets of kind run in kube-system
+	err := wait.For(conditions.New(getResourceManager()).AllDaemonSetsReady("kube-system"), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Error("failed waiting for all the daemonsets to become ready", err)
+	}
+}
+
+func TestEndpointSlicesReady(t *testing.T) {
+	deployment := createDeployment("d13", 2, t)
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "d13", Namespace: namespace},
+		Spec:       v1.ServiceSpec{Selector: deployment.Spec.Selector.MatchLabels, Ports: []v1.ServicePort{{Port: 80}}},
+	}
+	if err := getResourceManager().Create(context.TODO(), svc); err != nil {
+		t.Fatal("failed to create service", err)
+	}
+	err := wait.For(conditions.New(getResourceManager()).EndpointSlicesReady(svc, 2), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Error("failed waiting for the endpoints of the service to become ready", err)
+	}
+	err = wait.For(conditions.New(getResourceManager()).EndpointSlicesReady(svc, 3), wait.WithTimeout(10*time.Second))
+	if err == nil {
+		t.Error("expected the service to have less than 3 ready endpoints")
+	}
+}
+
+func TestLeaseHeld(t *testing.T) {
+	// the controller manager of kind holds its leader election lease in kube-system
+	err := wait.For(conditions.New(getResourceManager()).LeaseHeld("kube-controller-manager", "kube-system"), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Error("failed waiting for the controller manager lease to be held", err)
+	}
+
+	holder, duration := "replica-1", int32(60)
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "l1", Namespace: namespace},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			RenewTime:            &metav1.MicroTime{Time: time.Now()},
+		},
+	}
+	if err := getResourceManager().Create(context.TODO(), lease); err != nil {
+		t.Fatal("failed to create lease", err)
+	}
+	cond := conditions.New(getResourceManager())
+	if err := wait.For(cond.LeaseHeld(lease.Name, namespace), wait.WithImmediate(), wait.WithTimeout(time.Minute)); err != nil {
+		t.Error("failed waiting for the lease to be held", err)
+	}
+	if err := wait.For(cond.LeaseHolderChanged(lease.Name, namespace, holder), wait.WithImmediate(), wait.WithTimeout(3*time.Second)); err == nil {
+		t.Error("expected the holder of the lease not to have changed")
+	}
+
+	newHolder := "replica-2"
+	lease.Spec.HolderIdentity = &newHolder
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+	if err := getResourceManager().Update(context.TODO(), lease); err != nil {
+		t.Fatal("failed to update lease", err)
+	}
+	if err := wait.For(cond.LeaseHolderChanged(lease.Name, namespace, holder), wait.WithImmediate(), wait.WithTimeout(time.Minute)); err != nil {
+		t.Error("failed waiting for the holder of the lease to change", err)
+	}
+}
+
+func TestStatefulSetPodsReadyInOrder(t *testing.T) {
+	replicas := int32(3)
+	labels := map[string]string{"app": "ss1"}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "ss1", Namespace: namespace},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: "ss1",
+			Selector:    &metav1.LabelSelector{MatchLabels: labels},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{
+						Name:    "busybox",
+						Image:   "busybox",
+						Command: []string{"sh", "-c", "sleep 3600"},
+						ReadinessProbe: &v1.Probe{
+							ProbeHandler:  v1.ProbeHandler{Exec: &v1.ExecAction{Command: []string{"true"}}},
+							PeriodSeconds: 1,
+						},
+					}},
+				},
+			},
+		},
+	}
+	if err := getResourceManager().Create(context.TODO(), sts); err != nil {
+		t.Fatal("failed to create statefulset", err)
+	}
+	err := wait.For(conditions.New(getResourceManager()).StatefulSetPodsReadyInOrder(sts), wait.WithImmediate(), wait.WithTimeout(5*time.Minute))
+	if err != nil {
+		t.Error("failed waiting for the pods of the statefulset to be ready in order", err)
+	}
+}
+
+func TestSecretAndConfigMapHasKeys(t *testing.T) {
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "s1", Namespace: namespace}, Data: map[string][]byte{"tls.crt": []byte("cert")}}
+	configMap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: namespace}, Data: map[string]string{"ready": ""}}
+	go func() {
+		// simulate a controller populating the objects asynchronously
+		time.Sleep(3 * time.Second)
+		secret := secret.DeepCopy()
+		secret.Data["tls.key"] = []byte("key")
+		if err := getResourceManager().Create(context.TODO(), secret); err != nil {
+			log.ErrorS(err, "failed to create secret")
+		}
+		configMap := configMap.DeepCopy()
+		if err := getResourceManager().Create(context.TODO(), configMap); err != nil {
+			log.ErrorS(err, "failed to create config map")
+		}
+		time.Sleep(3 * time.Second)
+		configMap.Data["ready"] = "true"
+		if err := getResourceManager().Update(context.TODO(), configMap); err != nil {
+			log.ErrorS(err, "failed to update config map")
+		}
+	}()
+
+	err := wait.For(conditions.New(getResourceManager()).SecretHasKeys(secret, "tls.crt", "tls.key"), wait.WithInterval(time.Second), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("failed waiting for secret to be populated", err)
+	}
+	err = wait.For(conditions.New(getResourceManager()).ConfigMapHasKeys(configMap, "ready"), wait.WithInterval(time.Second), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("failed waiting for config map to be populated", err)
+	}
+	if configMap.Data["ready"] != "true" {
+		t.Errorf("expected the config map to be fetched with its populated keys, got: %v", configMap.Data)
+	}
+}
+
+func TestNodeTaint(t *testing.T) {
+	var nodes v1.NodeList
+	if err := getResourceManager().List(context.TODO(), &nodes); err != nil || len(nodes.Items) == 0 {
+		t.Fatal("failed to list nodes", err)
+	}
+	node := &nodes.Items[0]
+	// PreferNoSchedule keeps the node usable by the other tests
+	taint := v1.Taint{Key: "e2e-framework.sigs.k8s.io/test", Value: "tainted", Effect: v1.TaintEffectPreferNoSchedule}
+	setTaints := func(taints func([]v1.Taint) []v1.Taint) {
+		err := getResourceManager().UpdateWithRetryOnConflict(context.TODO(), node, func() error {
+			node.Spec.Taints = taints(node.Spec.Taints)
+			return nil
+		})
+		if err != nil {
+			t.Fatal("failed to update node taints", err)
+		}
+	}
+
+	setTaints(func(taints []v1.Taint) []v1.Taint { return append(taints, taint) })
+	err := wait.For(conditions.New(getResourceManager()).NodeHasTaint(node.Name, v1.Taint{Key: taint.Key, Effect: taint.Effect}), wait.WithImmediate(), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("failed waiting for node to be tainted", err)
+	}
+
+	setTaints(func(taints []v1.Taint) []v1.Taint {
+		var kept []v1.Taint
+		for _, t := range taints {
+			if t.Key != taint.Key {
+				kept = append(kept, t)
+			}
+		}
+		return kept
+	})
+	err = wait.For(conditions.New(getResourceManager()).NodeLacksTaint(node.Name, taint), wait.WithImmediate(), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("failed waiting for node taint to be removed", err)
+	}
+}
+
+func TestSchedulableCapacity(t *testing.T) {
+	cpu, mem, err := getResourceManager().NodeAllocatable(context.TODO())
+	if err != nil {
+		t.Fatal("failed to compute node allocatable", err)
+	}
+	if cpu.IsZero() || mem.IsZero() {
+		t.Fatalf("expected ready nodes to have allocatable resources, got cpu %s and memory %s", cpu.String(), mem.String())
+	}
+
+	err = wait.For(conditions.New(getResourceManager()).SchedulableCapacity(resource.MustParse("100m"), resource.MustParse("64Mi")), wait.WithImmediate(), wait.WithTimeout(10*time.Second))
+	if err != nil {
+		t.Error("expected the cluster to fit a small workload", err)
+	}
+
+	tooMuch := cpu.DeepCopy()
+	tooMuch.Add(resource.MustParse("1"))
+	err = wait.For(conditions.New(getResourceManager()).SchedulableCapacity(tooMuch, mem), wait.WithImmediate(), wait.WithTimeout(5*time.Second))
+	if err == nil {
+		t.Error("expected the cluster not to fit a workload requesting more than the allocatable cpu")
+	}
+}
+
+func TestNoPodsCrashLooping(t *testing.T) {
+	healthy := createPod("p15", t)
+	err := wait.For(conditions.New(getResourceManager()).PodRunning(healthy), wait.WithImmediate())
+	if err != nil {
+		t.Fatal("failed waiting for pod to be running", err)
+	}
+	err = wait.For(conditions.New(getResourceManager()).NoPodsCrashLooping(resources.WithLabelSelector("app=p15")), wait.WithImmediate())
+	if err != nil {
+		t.Error("expected no pods to be crash looping", err)
+	}
+
+	crashing := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p16", Namespace: namespace, Labels: map[string]string{"app": "p16"}},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "crash", Image: "busybox", Command: []string{"sh", "-c", "exit 1"}}},
+		},
+	}
+	if err := getResourceManager().Create(context.TODO(), crashing); err != nil {
+		t.Fatal("failed to create pod", err)
+	}
+	var crashErr error
+	err = wait.For(func(ctx context.Context) (bool, error) {
+		_, crashErr = conditions.New(getResourceManager()).NoPodsCrashLooping(resources.WithLabelSelector("app=p16"))(ctx)
+		return crashErr != nil, nil
+	}, wait.WithImmediate(), wait.WithInterval(2*time.Second), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Fatal("expected the pod to be reported as crash looping", err)
+	}
+	if !strings.Contains(crashErr.Error(), "container crash of pod "+namespace+"/p16") {
+		t.Errorf("expected the error to name the crashing container, got: %s", crashErr)
+	}
+}
+
+func TestHasConditionOfType(t *testing.T) {
+	pod := createPod("p14", t)
+	err := wait.For(conditions.New(getResourceManager()).HasConditionOfType(pod, string(v1.PodReady), metav1.ConditionTrue), wait.WithImmediate())
+	if err != nil {
+		t.Error("failed waiting for pod to have the Ready condition", err)
+	}
+}
+
+func TestForOrFail(t *testing.T) {
+	pod := createPod("p12", t)
+	waittest.ForOrFail(t, conditions.New(getResourceManager()).PodRunning(pod), waittest.WithWaitOptions(wait.WithImmediate()), waittest.WithDiagnosticObject(getResourceManager(), pod))
+}
+
+func TestForFunc(t *testing.T) {
+	polls := 0
+	err := wait.ForFunc(context.TODO(), func(ctx context.Context) (bool, error) {
+		polls++
+		return polls == 3, nil
+	}, wait.WithImmediate(), wait.WithInterval(10*time.Millisecond))
+	if err != nil {
+		t.Error("failed waiting for the function to be done", err)
+	}
+	if polls != 3 {
+		t.Errorf("expected the function to be polled 3 times, got %d", polls)
+	}
+
+	errStop := errors.New("stop")
+	polls = 0
+	err = wait.ForFunc(context.TODO(), func(ctx context.Context) (bool, error) {
+		polls++
+		return false, errStop
+	}, wait.WithImmediate(), wait.WithInterval(10*time.Millisecond))
+	if !errors.Is(err, errStop) || polls != 1 {
+		t.Errorf("expected polling to stop on the first error, got %v after %d polls", err, polls)
+	}
+
+	err = wait.ForFunc(context.TODO(), func(ctx context.Context) (bool, error) {
+		return false, nil
+	}, wait.WithInterval(10*time.Millisecond), wait.WithTimeout(50*time.Millisecond))
+	if err == nil {
+		t.Error("expected an error when the function is never done")
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	wait.SetJitterSeed(1)
+	interval := 20 * time.Millisecond
+	var polls []time.Time
+	err := wait.ForFunc(context.TODO(), func(ctx context.Context) (bool, error) {
+		polls = append(polls, time.Now())
+		return len(polls) == 6, nil
+	}, wait.WithImmediate(), wait.WithInterval(interval), wait.WithJitter(0.5))
+	if err != nil {
+		t.Fatal("failed waiting for the function to be done", err)
+	}
+	for i := 1; i < len(polls); i++ {
+		if elapsed := polls[i].Sub(polls[i-1]); elapsed < interval/2 {
+			t.Errorf("expected the jittered interval to be at least %s, got %s", interval/2, elapsed)
+		}
+	}
+
+	err = wait.ForFunc(context.TODO(), func(ctx context.Context) (bool, error) {
+		return false, nil
+	}, wait.WithInterval(10*time.Millisecond), wait.WithTimeout(50*time.Millisecond), wait.WithJitter(0.2))
+	if err == nil {
+		t.Error("expected an error when the function is never done")
+	}
+}
+
+func TestForFuncContextDeadline(t *testing.T) {
+	never := func(ctx context.Context) (bool, error) { return false, nil }
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := wait.ForFunc(ctx, never, wait.WithInterval(10*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("expected the deadline of the context to be the timeout, got %v after %s", err, time.Since(start))
+	}
+
+	ctx, cancel = context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+	start = time.Now()
+	err = wait.For(never, wait.WithContext(ctx), wait.WithInterval(10*time.Millisecond), wait.WithTimeout(50*time.Millisecond))
+	if err == nil || time.Since(start) > time.Second {
+		t.Errorf("expected the timeout to apply when earlier than the deadline, got %v after %s", err, time.Since(start))
+	}
+
+	ctx, cancel = context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err = wait.For(never, wait.WithContext(ctx), wait.WithInterval(10*time.Millisecond), wait.WithTimeout(time.Minute))
+	if err == nil || time.Since(start) > time.Second {
+		t.Errorf("expected the deadline to apply when earlier than the timeout, got %v after %s", err, time.Since(start))
+	}
+}
+
+func TestSetDefaultTimeout(t *testing.T) {
+	if os.Getenv(wait.TimeoutEnvVar) != "" {
+		t.Skipf("%s takes precedence over the package default", wait.TimeoutEnvVar)
+	}
+	wait.SetDefaultTimeout(100 * time.Millisecond)
+	wait.SetDefaultInterval(10 * time.Millisecond)
+	defer func() {
+		wait.SetDefaultTimeout(5 * time.Minute)
+		wait.SetDefaultInterval(5 * time.Second)
+	}()
+
+	never := func(ctx context.Context) (bool, error) { return false, nil }
+	start := time.Now()
+	if err := wait.For(never); err == nil {
+		t.Error("expected the wait to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the wait to use the default timeout, took %s", elapsed)
+	}
+
+	polls := 0
+	err := wait.For(func(ctx context.Context) (bool, error) {
+		polls++
+		return false, nil
+	}, wait.WithTimeout(time.Second))
+	if err == nil || polls < 10 {
+		t.Errorf("expected the per call timeout to take precedence and the default interval to be used, got %v after %d polls", err, polls)
+	}
+}
+
+func TestForStable(t *testing.T) {
+	counts := []int{3, 2, 2, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+	polls := 0
+	getCount := func() (int, error) {
+		count := counts[len(counts)-1]
+		if polls < len(counts) {
+			count = counts[polls]
+		}
+		polls++
+		return count, nil
+	}
+	err := wait.ForStable(context.TODO(), getCount, 2, 50*time.Millisecond, wait.WithImmediate(), wait.WithInterval(10*time.Millisecond), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("failed waiting for the count to be stable", err)
+	}
+	if polls < 10 {
+		t.Errorf("expected the deviation to restart the stability period, got %d polls", polls)
+	}
+
+	flapping := 0
+	err = wait.ForStable(context.TODO(), func() (int, error) {
+		flapping++
+		return flapping % 2, nil
+	}, 1, 50*time.Millisecond, wait.WithImmediate(), wait.WithInterval(10*time.Millisecond), wait.WithTimeout(300*time.Millisecond))
+	if err == nil {
+		t.Error("expected a flapping count not to be stable")
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package waittest provides the helpers of the wait package that fail the test using them, which are kept
+// apart so that the wait package does not depend on the testing package.
+package waittest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+const diagnosticTimeout = 30 * time.Second
+
+// DiagnosticObject is an object whose current state is reported when a condition is not met, along with
+// the resources client used to fetch it
+type DiagnosticObject struct {
+	Resources *resources.Resources
+	Object    k8s.Object
+}
+
+// Options configures ForOrFail
+type Options struct {
+	// WaitOptions are the options of the wait, passed as is to wait.For
+	WaitOptions []wait.Option
+	// DiagnosticObjects are the objects whose current state is reported when the condition is not met
+	DiagnosticObjects []DiagnosticObject
+}
+
+type Option func(*Options)
+
+// WithWaitOptions configures the options of the wait, such as its interval and timeout, which are passed as is
+// to wait.For
+func WithWaitOptions(opts ...wait.Option) Option {
+	return func(options *Options) {
+		options.WaitOptions = append(options.WaitOptions, opts...)
+	}
+}
+
+// WithDiagnosticObject configures an object whose current state is fetched using r and reported when
+// the condition waited upon by ForOrFail is not met. This option can be provided multiple times in order
+// to report the state of more than one object, each fetched using its own r.
+func WithDiagnosticObject(r *resources.Resources, obj k8s.Object) Option {
+	return func(options *Options) {
+		options.DiagnosticObjects = append(options.DiagnosticObjects, DiagnosticObject{Resources: r, Object: obj})
+	}
+}
+
+// ForOrFail works the same way as wait.For, configured using WithWaitOptions, but fails the test when the
+// condition is not met. Before failing the test, the current state of the objects configured using
+// WithDiagnosticObject is fetched and reported along with their most recent event, so that the failure
+// message shows what the resources actually looked like instead of only reporting that the condition was
+// not met. The diagnostic objects are only fetched when the condition is not met in order to avoid any
+// overhead on success.
+func ForOrFail(t testing.TB, conditionFunc apimachinerywait.ConditionWithContextFunc, opts ...Option) {
+	t.Helper()
+	options := &Options{}
+	for _, fn := range opts {
+		fn(options)
+	}
+	err := wait.For(conditionFunc, options.WaitOptions...)
+	if err == nil {
+		return
+	}
+
+	var report strings.Builder
+	for _, diagnostic := range options.DiagnosticObjects {
+		report.WriteString("\n")
+		report.WriteString(describeObject(diagnostic.Resources, diagnostic.Object))
+	}
+	t.Fatalf("condition not met: %s%s", err, report.String())
+}
+
+// describeObject returns the current state of the object as YAML, without its managed fields, followed
+// by the most recent event recorded against it.
+func describeObject(r *resources.Resources, obj k8s.Object) string {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticTimeout)
+	defer cancel()
+
+	name := fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
+	if r == nil {
+		return fmt.Sprintf("%s: no resources client provided to fetch its state", name)
+	}
+	if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+		return fmt.Sprintf("%s: failed to fetch current state: %s", name, err)
+	}
+	obj.SetManagedFields(nil)
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Sprintf("%s: failed to marshal current state: %s", name, err)
+	}
+
+	lastEvent := "none"
+	if clientset, err := kubernetes.NewForConfig(r.GetConfig()); err == nil {
+		selector := fmt.Sprintf("involvedObject.name=%s,involvedObject.uid=%s", obj.GetName(), obj.GetUID())
+		events, err := clientset.CoreV1().Events(obj.GetNamespace()).List(ctx, metav1.ListOptions{FieldSelector: selector})
+		if err == nil && len(events.Items) > 0 {
+			sort.Slice(events.Items, func(i, j int) bool {
+				return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+			})
+			e := events.Items[len(events.Items)-1]
+			lastEvent = fmt.Sprintf("%s %s: %s", e.Type, e.Reason, e.Message)
+		}
+	}
+	return fmt.Sprintf("%s current state:\n%s\nlast event: %s", name, string(out), lastEvent)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package waittest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+// fakeT records the failure of the test instead of failing it
+type fakeT struct {
+	testing.TB
+	failure string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.failure = fmt.Sprintf(format, args...)
+}
+
+// newFakeResources returns Resources reading obj through the fake client of controller runtime
+func newFakeResources(t *testing.T, obj k8s.Object) *resources.Resources {
+	t.Helper()
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(obj).Build()
+	// nothing listens on the host, so the events of the objects cannot be listed
+	res, err := resources.New(&rest.Config{Host: "https://127.0.0.1:1"}, resources.WithClient(client))
+	if err != nil {
+		t.Fatalf("unexpected error creating resources: %s", err)
+	}
+	return res
+}
+
+func TestForOrFail(t *testing.T) {
+	never := func(ctx context.Context) (bool, error) { return false, nil }
+	opts := []wait.Option{wait.WithImmediate(), wait.WithInterval(10 * time.Millisecond), wait.WithTimeout(50 * time.Millisecond)}
+
+	ft := &fakeT{}
+	ForOrFail(ft, func(ctx context.Context) (bool, error) { return true, nil }, WithWaitOptions(opts...))
+	if ft.failure != "" {
+		t.Errorf("expected the test not to fail when the condition is met, got: %s", ft.failure)
+	}
+
+	// each diagnostic object is fetched using its own resources client, which only knows about that object
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p1", Namespace: "ns1"}, Spec: v1.PodSpec{NodeName: "node-1"}}
+	configMap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns2"}, Data: map[string]string{"key": "value"}}
+	ft = &fakeT{}
+	ForOrFail(ft, never,
+		WithWaitOptions(opts...),
+		WithDiagnosticObject(newFakeResources(t, pod), &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p1", Namespace: "ns1"}}),
+		WithDiagnosticObject(newFakeResources(t, configMap), &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns2"}}),
+		WithDiagnosticObject(nil, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p2", Namespace: "ns1"}}),
+	)
+	for _, expected := range []string{
+		"condition not met: ",
+		"ns1/p1 current state:\n",
+		"nodeName: node-1",
+		"ns2/cm1 current state:\n",
+		"key: value",
+		"last event: none",
+		"ns1/p2: no resources client provided to fetch its state",
+	} {
+		if !strings.Contains(ft.failure, expected) {
+			t.Errorf("expected the failure to contain %q, got: %s", expected, ft.failure)
+		}
+	}
+}
@@ -35,6 +35,7 @@ const (
 	roleAfterFeature
 	roleAfterTest
 	roleFinish
+	roleFinishLast
 )
 
 func (r actionRole) String() string {
@@ -51,6 +52,8 @@ func (r actionRole) String() string {
 		return "AfterEachTest"
 	case roleFinish:
 		return "Finish"
+	case roleFinishLast:
+		return "FinishLast"
 	default:
 		panic("unknown role") // this should never happen
 	}
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// featureNode is a feature tested by a call to Test or TestInParallel along with the features it depends on. The
+// outcome of the feature is recorded once it is done, so that the features depending on it, which wait for it
+// when tested in parallel, are skipped when it did not pass.
+type featureNode struct {
+	name         string
+	feature      types.Feature
+	dependencies []*featureNode
+
+	once    sync.Once
+	done    chan struct{}
+	failed  bool
+	skipped bool
+}
+
+// dependenciesOf returns the names of the features the feature depends on
+func dependenciesOf(f types.Feature) []string {
+	if df, ok := f.(types.DependentFeature); ok {
+		return df.Dependencies()
+	}
+	return nil
+}
+
+// featureNames returns the names the features are tested under, which default to their position when unnamed
+func featureNames(features []types.Feature) []string {
+	names := make([]string, len(features))
+	for i, feature := range features {
+		names[i] = feature.Name()
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("Feature-%d", i+1)
+		}
+	}
+	return names
+}
+
+// RegisterFeatures registers features tested together by a call to Test or TestInParallel and validates the
+// dependencies they declare using DependsOn. An unknown dependency or a dependency cycle makes Run fail before
+// any of the Setup funcs is executed, rather than when the features are tested.
+func (e *testEnv) RegisterFeatures(features ...types.Feature) types.Environment {
+	if _, err := orderFeatures(featureNames(features), features); err != nil {
+		e.dependencyErrs = append(e.dependencyErrs, err)
+	}
+	return e
+}
+
+// orderFeatures returns the features sorted so that each of them comes after the features it depends on, keeping
+// the order they were passed in otherwise. names holds the names the features are tested under. An error is
+// returned when a dependency is not one of the features or when the dependencies form a cycle.
+func orderFeatures(names []string, features []types.Feature) ([]*featureNode, error) {
+	nodes := make([]*featureNode, len(features))
+	byName := make(map[string][]*featureNode)
+	for i, f := range features {
+		nodes[i] = &featureNode{name: names[i], feature: f, done: make(chan struct{})}
+		byName[names[i]] = append(byName[names[i]], nodes[i])
+	}
+	for _, node := range nodes {
+		for _, dependency := range dependenciesOf(node.feature) {
+			dependencies, ok := byName[dependency]
+			if !ok {
+				return nil, fmt.Errorf("feature %q depends on unknown feature %q", node.name, dependency)
+			}
+			node.dependencies = append(node.dependencies, dependencies...)
+		}
+	}
+
+	ordered := make([]*featureNode, 0, len(nodes))
+	placed := make(map[*featureNode]bool)
+	for len(ordered) < len(nodes) {
+		progress := false
+		for _, node := range nodes {
+			if !placed[node] && node.ready(placed) {
+				ordered = append(ordered, node)
+				placed[node] = true
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			return nil, fmt.Errorf("feature dependency cycle: %s", cycleOf(nodes, placed))
+		}
+	}
+	return ordered, nil
+}
+
+// ready returns true when all the dependencies of the node are placed
+func (n *featureNode) ready(placed map[*featureNode]bool) bool {
+	for _, dependency := range n.dependencies {
+		if !placed[dependency] {
+			return false
+		}
+	}
+	return true
+}
+
+// cycleOf returns a dependency cycle among the nodes that could not be placed, as the names of the features
+// joined with arrows
+func cycleOf(nodes []*featureNode, placed map[*featureNode]bool) string {
+	var path []*featureNode
+	visited := make(map[*featureNode]int)
+	for _, node := range nodes {
+		if !placed[node] {
+			path = append(path, node)
+			break
+		}
+	}
+	for {
+		current := path[len(path)-1]
+		visited[current] = len(path) - 1
+		for _, dependency := range current.dependencies {
+			if placed[dependency] {
+				continue
+			}
+			if start, ok := visited[dependency]; ok {
+				names := make([]string, 0, len(path)-start+1)
+				for _, node := range path[start:] {
+					names = append(names, node.name)
+				}
+				return strings.Join(append(names, dependency.name), " -> ")
+			}
+			path = append(path, dependency)
+			break
+		}
+	}
+}
+
+// finish records the outcome of the feature, only the first outcome recorded is kept
+func (n *featureNode) finish(failed, skipped bool) {
+	n.once.Do(func() {
+		n.failed, n.skipped = failed, skipped
+		close(n.done)
+	})
+}
+
+// waitDependencies waits for the features the feature depends on to be done and returns the reason to skip the
+// feature when one of them did not pass, or an empty string
+func (n *featureNode) waitDependencies() string {
+	for _, dependency := range n.dependencies {
+		<-dependency.done
+		switch {
+		case dependency.failed:
+			return fmt.Sprintf("skipped: dependency %s failed", dependency.name)
+		case dependency.skipped:
+			return fmt.Sprintf("skipped: dependency %s was skipped", dependency.name)
+		}
+	}
+	return ""
+}
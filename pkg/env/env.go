@@ -20,30 +20,56 @@ package env
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"regexp"
 	"runtime/debug"
 	"sync"
 	"testing"
+	"time"
 
 	"k8s.io/klog/v2"
 
+	"sigs.k8s.io/e2e-framework/klient/wait"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+	"sigs.k8s.io/e2e-framework/support/utils"
 )
 
 type (
-	Environment = types.Environment
-	Func        = types.EnvFunc
-	FeatureFunc = types.FeatureEnvFunc
-	TestFunc    = types.TestEnvFunc
+	Environment                  = types.Environment
+	FinishLastEnvironment        = types.FinishLastEnvironment
+	DependentFeaturesEnvironment = types.DependentFeaturesEnvironment
+	Func                         = types.EnvFunc
+	FeatureFunc                  = types.FeatureEnvFunc
+	TestFunc                     = types.TestEnvFunc
 )
 
 type testEnv struct {
 	ctx     context.Context
 	cfg     *envconf.Config
 	actions []action
+
+	// runCtx is the latest context of the suite launched by Run, guarded by runCtxMu
+	// as it is read by the signal handler while the suite is running
+	runCtxMu   sync.Mutex
+	runCtx     context.Context
+	finishOnce sync.Once
+
+	// report records the outcome of the features tested in this environment
+	report *runReport
+
+	// observers are notified of the lifecycle events of the test suite
+	observers []Observer
+
+	// fixtures records the fixtures set up by the features tested in this environment
+	fixtures *fixtureSet
+
+	// dependencyErrs are the errors of the dependencies of the features registered using RegisterFeatures
+	dependencyErrs []error
 }
 
 // New creates a test environment with no config attached.
@@ -97,20 +123,24 @@ func NewWithContext(ctx context.Context, cfg *envconf.Config) (types.Environment
 	if cfg == nil {
 		return nil, fmt.Errorf("environment config is nil")
 	}
-	return &testEnv{ctx: ctx, cfg: cfg}, nil
+	return &testEnv{ctx: ctx, cfg: cfg, report: &runReport{}, fixtures: &fixtureSet{}}, nil
 }
 
 func newTestEnv() *testEnv {
 	return &testEnv{
-		ctx: context.Background(),
-		cfg: envconf.New(),
+		ctx:      context.Background(),
+		cfg:      envconf.New(),
+		report:   &runReport{},
+		fixtures: &fixtureSet{},
 	}
 }
 
 func newTestEnvWithParallel() *testEnv {
 	return &testEnv{
-		ctx: context.Background(),
-		cfg: envconf.New().WithParallelTestEnabled(),
+		ctx:      context.Background(),
+		cfg:      envconf.New().WithParallelTestEnabled(),
+		report:   &runReport{},
+		fixtures: &fixtureSet{},
 	}
 }
 
@@ -121,10 +151,13 @@ func (e *testEnv) WithContext(ctx context.Context) types.Environment {
 		panic("nil context") // this should never happen
 	}
 	env := &testEnv{
-		ctx: ctx,
-		cfg: e.cfg,
+		ctx:      ctx,
+		cfg:      e.cfg,
+		report:   e.report,
+		fixtures: e.fixtures,
 	}
 	env.actions = append(env.actions, e.actions...)
+	env.observers = append(env.observers, e.observers...)
 	return env
 }
 
@@ -203,19 +236,31 @@ func (e *testEnv) processTestActions(ctx context.Context, t *testing.T, actions
 // processTestFeature is used to trigger the execution of the actual feature. This function wraps the entire
 // workflow of orchestrating the feature execution be running the action configured by BeforeEachFeature /
 // AfterEachFeature.
-func (e *testEnv) processTestFeature(ctx context.Context, t *testing.T, featureName string, feature types.Feature) context.Context {
+func (e *testEnv) processTestFeature(ctx context.Context, t *testing.T, node *featureNode) context.Context {
+	feature := node.feature
+	// the feature is reported as skipped to the features depending on it when it is not tested
+	defer node.finish(false, true)
+
 	skipped, message := e.requireFeatureProcessing(feature)
 	if skipped {
+		e.releaseFixtures(t, feature)
+		e.report.addFeature(&featureResult{name: node.name, start: time.Now(), skipped: true, skipReason: message})
 		t.Skipf(message)
 	}
+	// scope a new store to the feature being tested
+	ctx = features.ContextWithStore(ctx)
+
 	// execute beforeEachFeature actions
 	ctx = e.processFeatureActions(ctx, t, feature, e.getBeforeFeatureActions())
 
 	// execute feature test
-	ctx = e.execFeature(ctx, t, featureName, feature)
+	ctx = e.execFeature(ctx, t, node)
 
 	// execute afterEachFeature actions
-	return e.processFeatureActions(ctx, t, feature, e.getAfterFeatureActions())
+	ctx = e.processFeatureActions(ctx, t, feature, e.getAfterFeatureActions())
+
+	// make sure the data stored by the feature does not leak to other features
+	return features.ReleaseStore(ctx)
 }
 
 // processFeatureActions is used to run a series of feature action that were configured as
@@ -258,23 +303,25 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 		klog.V(4).Info("Running test features in parallel")
 	}
 
+	// the features are tested after the features they depend on
+	names := featureNames(testFeatures)
+	nodes, err := orderFeatures(names, testFeatures)
+	if err != nil {
+		t.Fatalf("invalid feature dependencies: %s", err)
+	}
+
 	ctx = e.processTestActions(ctx, t, beforeTestActions)
 
 	var wg sync.WaitGroup
-	for i, feature := range testFeatures {
-		featureCopy := feature
-		featName := feature.Name()
-		if featName == "" {
-			featName = fmt.Sprintf("Feature-%d", i+1)
-		}
+	for _, node := range nodes {
 		if runInParallel {
 			wg.Add(1)
-			go func(ctx context.Context, w *sync.WaitGroup, featName string, f types.Feature) {
+			go func(ctx context.Context, w *sync.WaitGroup, node *featureNode) {
 				defer w.Done()
-				_ = e.processTestFeature(ctx, t, featName, f)
-			}(ctx, &wg, featName, featureCopy)
+				_ = e.processTestFeature(ctx, t, node)
+			}(ctx, &wg, node)
 		} else {
-			ctx = e.processTestFeature(ctx, t, featName, featureCopy)
+			ctx = e.processTestFeature(ctx, t, node)
 			// In case if the feature under test has failed, skip reset of the features
 			// that are part of the same test
 			if e.cfg.FailFast() && t.Failed() {
@@ -305,7 +352,8 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 // BeforeTest and AfterTest operations are run in series of the entire
 // set of features being passed to this call while the feature themselves
 // are executed in parallel to avoid duplication of action that might happen
-// in BeforeTest and AfterTest actions
+// in BeforeTest and AfterTest actions. Features declaring dependencies
+// using FeatureBuilder.DependsOn wait for the features they depend on.
 func (e *testEnv) TestInParallel(t *testing.T, testFeatures ...types.Feature) context.Context {
 	return e.processTests(e.ctx, t, true, testFeatures...)
 }
@@ -322,12 +370,17 @@ func (e *testEnv) TestInParallel(t *testing.T, testFeatures ...types.Feature) co
 //
 // BeforeTest and AfterTest operations are executed before and after
 // the feature is tested respectively.
+//
+// Features declaring dependencies using FeatureBuilder.DependsOn are
+// tested after the features they depend on, and skipped when one of
+// them failed or was skipped.
 func (e *testEnv) Test(t *testing.T, testFeatures ...types.Feature) context.Context {
 	return e.processTests(e.ctx, t, false, testFeatures...)
 }
 
 // Finish registers funcs that are executed at the end of the
-// test suite.
+// test suite, in registration order, before the funcs registered
+// using FinishLast.
 func (e *testEnv) Finish(funcs ...Func) types.Environment {
 	if len(funcs) == 0 {
 		return e
@@ -337,6 +390,19 @@ func (e *testEnv) Finish(funcs ...Func) types.Environment {
 	return e
 }
 
+// FinishLast registers funcs that are executed at the end of the
+// test suite after all the funcs registered using Finish, whatever
+// the order they were registered in, such as the destruction of the
+// cluster which must happen after the cleanups needing the API server.
+func (e *testEnv) FinishLast(funcs ...Func) types.Environment {
+	if len(funcs) == 0 {
+		return e
+	}
+
+	e.actions = append(e.actions, action{role: roleFinishLast, funcs: funcs})
+	return e
+}
+
 // Run is to launch the test suite from a TestMain function.
 // It will run m.Run() and exercise all test functions in the
 // package.  This method will all Env.Setup operations prior to
@@ -346,6 +412,35 @@ func (e *testEnv) Run(m *testing.M) (exitCode int) {
 	e.panicOnMissingContext()
 	ctx := e.ctx
 
+	// the dependencies of the registered features are validated before any func is executed
+	if len(e.dependencyErrs) > 0 {
+		klog.Errorf("invalid feature dependencies: %s", errors.Join(e.dependencyErrs...))
+		return 1
+	}
+
+	if level, ok := e.cfg.LogVerbosity(); ok {
+		if err := setLogVerbosity(level); err != nil {
+			klog.ErrorS(err, "Failed to set the log verbosity", "level", level)
+		}
+	}
+	seed := envconf.ProcessRandSeed()
+	klog.Infof("Random names are generated using seed %d, set %s=%d to reproduce them", seed, envconf.RandSeedEnvVar, seed)
+	wait.SetJitterSeed(seed)
+
+	if signals := e.cfg.SignalHandling(); len(signals) > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		e.setRunContext(ctx)
+		sigCh := make(chan os.Signal, 2)
+		signal.Notify(sigCh, signals...)
+		stop := e.handleSignals(sigCh, cancel)
+		defer func() {
+			signal.Stop(sigCh)
+			stop()
+			cancel()
+		}()
+	}
+
 	setups := e.getSetupActions()
 	// fail fast on setup, upon err exit
 	var err error
@@ -354,6 +449,8 @@ func (e *testEnv) Run(m *testing.M) (exitCode int) {
 		// Recover and see if the panic handler is disabled. If it is disabled, panic and stop the workflow.
 		// Otherwise, log and continue with running the Finish steps of the Test suite
 		rErr := recover()
+		// the tests are done, the messages they logged are all recorded for the report
+		e.report.output.stop()
 		if rErr != nil {
 			if e.cfg.DisableGracefulTeardown() {
 				panic(rErr)
@@ -364,30 +461,91 @@ func (e *testEnv) Run(m *testing.M) (exitCode int) {
 			exitCode = 1
 		}
 
-		finishes := e.getFinishActions()
-		// attempt to gracefully clean up.
-		// Upon error, log and continue.
-		for _, fin := range finishes {
-			// context passed down to each finish step
-			if ctx, err = fin.run(ctx, e.cfg); err != nil {
-				klog.V(2).ErrorS(err, "Cleanup failed", "action", fin.role)
-			}
+		if finished, ok := e.runFinishActions(ctx, exitCode != 0); ok {
+			e.ctx = finished
 		}
-		e.ctx = ctx
+		utils.RunExitHooks()
 	}()
 
 	for _, setup := range setups {
 		// context passed down to each setup
 		if ctx, err = setup.run(ctx, e.cfg); err != nil {
+			e.notify(func(o Observer) { o.OnSetup(ctx, err) })
 			klog.Fatalf("%s failure: %s", setup.role, err)
 		}
+		e.setRunContext(ctx)
 	}
 	e.ctx = ctx
+	e.notify(func(o Observer) { o.OnSetup(ctx, nil) })
+
+	// the messages logged by the tests are only known from their output, which is captured to include
+	// them in the report
+	if e.cfg.JUnitReportPath() != "" {
+		if output, err := captureTestOutput(); err != nil {
+			klog.ErrorS(err, "Failed to capture the test output, the JUnit report will not include the messages of the failed steps")
+		} else {
+			e.report.setOutput(output)
+		}
+	}
 
 	// Execute the test suite
 	return m.Run()
 }
 
+// runFinishActions executes the Finish actions with the outcome of the test suite made available
+// to them through ctx. The actions are only executed once, whether the suite completed or was
+// interrupted by a signal, and ok reports whether this call was the one executing them.
+func (e *testEnv) runFinishActions(ctx context.Context, failed bool) (finished context.Context, ok bool) {
+	e.finishOnce.Do(func() {
+		// make the outcome of the test suite available to the finish actions
+		ctx = context.WithValue(ctx, suiteFailedContextKey{}, failed)
+
+		// tear down the fixtures left behind before the finish actions, which may delete the cluster
+		e.closeFixtures()
+
+		// attempt to gracefully clean up.
+		// Upon error, log and continue.
+		var err error
+		for _, fin := range e.getFinishActions() {
+			// context passed down to each finish step
+			if ctx, err = fin.run(ctx, e.cfg); err != nil {
+				klog.V(2).ErrorS(err, "Cleanup failed", "action", fin.role)
+			}
+		}
+
+		if path := e.cfg.JUnitReportPath(); path != "" {
+			if err := e.report.writeJUnit(path); err != nil {
+				klog.ErrorS(err, "Failed to write JUnit report", "path", path)
+			}
+		}
+		e.notify(func(o Observer) { o.OnTeardown(ctx, failed) })
+		finished, ok = ctx, true
+	})
+	return finished, ok
+}
+
+func (e *testEnv) setRunContext(ctx context.Context) {
+	e.runCtxMu.Lock()
+	defer e.runCtxMu.Unlock()
+	e.runCtx = ctx
+}
+
+func (e *testEnv) runContext() context.Context {
+	e.runCtxMu.Lock()
+	defer e.runCtxMu.Unlock()
+	return e.runCtx
+}
+
+type suiteFailedContextKey struct{}
+
+// SuiteFailed reports if the test suite launched by Environment.Run has failed. The outcome of
+// the test suite is only known once all the tests have been executed, so this is meant to be used
+// from within the Finish actions.
+func SuiteFailed(ctx context.Context) bool {
+	failed, _ := ctx.Value(suiteFailedContextKey{}).(bool)
+	return failed
+}
+
 func (e *testEnv) getActionsByRole(r actionRole) []action {
 	if e.actions == nil {
 		return nil
@@ -424,26 +582,106 @@ func (e *testEnv) getAfterTestActions() []action {
 }
 
 func (e *testEnv) getFinishActions() []action {
-	return e.getActionsByRole(roleFinish)
+	return append(e.getActionsByRole(roleFinish), e.getActionsByRole(roleFinishLast)...)
 }
 
 func (e *testEnv) executeSteps(ctx context.Context, t *testing.T, steps []types.Step) context.Context {
 	if e.cfg.DryRunMode() {
 		return ctx
 	}
-	for _, setup := range steps {
-		ctx = setup.Func()(ctx, t, e.cfg)
+	for _, step := range steps {
+		if s, ok := step.(types.SubtestStep); ok && s.Subtest() {
+			ctx = e.executeSubtestStep(ctx, t, step)
+			continue
+		}
+		ctx = step.Func()(ctx, t, e.cfg)
+	}
+	return ctx
+}
+
+// executeSubtestStep executes the step as a subtest named after the step. If the step stops its subtest
+// using t.FailNow, t.Fatal or t.SkipNow, the parent test is stopped the same way it would have been if
+// the step had been executed directly.
+func (e *testEnv) executeSubtestStep(ctx context.Context, t *testing.T, step types.Step) context.Context {
+	completed, skipped := false, false
+	t.Run(step.Name(), func(subT *testing.T) {
+		defer func() { skipped = subT.Skipped() }()
+		ctx = step.Func()(ctx, subT, e.cfg)
+		completed = true
+	})
+	if !completed {
+		if skipped {
+			t.SkipNow()
+		}
+		t.FailNow()
 	}
 	return ctx
 }
 
-func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string, f types.Feature) context.Context {
+func (e *testEnv) execFeature(ctx context.Context, t *testing.T, node *featureNode) context.Context {
+	featName, f := node.name, node.feature
 	// feature-level subtest
+	featResult := &featureResult{name: featName, start: time.Now()}
+	defer e.report.addFeature(featResult)
+
+	var featT *testing.T
+	defer func() {
+		// the outcome is final once t.Run returns, after the parallel assessments are done
+		if featT != nil {
+			node.finish(featT.Failed(), featT.Skipped())
+		}
+	}()
 	t.Run(featName, func(newT *testing.T) {
+		featT = newT
+		featResult.testName = newT.Name()
+		defer func() {
+			featResult.finish(time.Since(featResult.start), newT.Failed(), newT.Skipped())
+			result := featResult.result(newT.Skipped())
+			e.notify(func(o Observer) { o.OnFeatureFinish(ctx, f, result) })
+		}()
+		e.notify(func(o Observer) { o.OnFeatureStart(ctx, f) })
+
 		if fDescription, ok := f.(types.DescribableFeature); ok && fDescription.Description() != "" {
 			t.Logf("Processing Feature: %s", fDescription.Description())
 		}
 
+		// the fixtures are released even when the feature stops early, so that the last feature depending
+		// on a fixture tears it down
+		defer e.releaseFixtures(newT, f)
+
+		// wait for the features the feature depends on, and skip it when one of them did not pass
+		if reason := node.waitDependencies(); reason != "" {
+			featResult.setSkipReason(reason)
+			newT.Skip(reason)
+		}
+
+		// stop right away when a prerequisite of the feature is not met to avoid a cascade of failures
+		if err := e.checkFeatureRequirements(ctx, f); err != nil {
+			var skip *types.SkipError
+			if errors.As(err, &skip) {
+				featResult.setSkipReason(skip.Reason)
+				newT.Skipf("feature %q skipped: %s", featName, skip.Reason)
+			}
+			featResult.addFailure(fmt.Sprintf("precondition failed: %s", err))
+			newT.Fatalf("feature %q precondition failed: %s", featName, err)
+		}
+
+		// monitor the cluster health for the duration of the feature if enabled
+		if e.cfg.ClusterHealthMonitorInterval() > 0 && !e.cfg.DryRunMode() {
+			stop := e.startClusterHealthMonitor(ctx, newT, featResult)
+			defer stop()
+		}
+
+		// the fixtures are set up once for all the features depending on them
+		var err error
+		if ctx, err = e.acquireFixtures(ctx, f); err != nil {
+			featResult.addFailure(err.Error())
+			newT.Fatal(err)
+		}
+
+		// the tracked resources are compared once the assessments are done
+		snapshots := e.snapshotResources(ctx, newT, f)
+
 		// setups run at feature-level
 		setups := features.GetStepsByLevel(f.Steps(), types.LevelSetup)
 		ctx = e.executeSteps(ctx, newT, setups)
@@ -452,21 +690,24 @@ func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string
 		assessments := features.GetStepsByLevel(f.Steps(), types.LevelAssess)
 
 		failed := false
-		for i, assess := range assessments {
-			assessName := assess.Name()
-			if dAssess, ok := assess.(types.DescribableStep); ok && dAssess.Description() != "" {
-				t.Logf("Processing Assessment: %s", dAssess.Description())
-			}
-			if assessName == "" {
-				assessName = fmt.Sprintf("Assessment-%d", i+1)
-			}
-			newT.Run(assessName, func(internalT *testing.T) {
-				skipped, message := e.requireAssessmentProcessing(assess, i+1)
-				if skipped {
-					internalT.Skipf(message)
+		for i := 0; i < len(assessments); i++ {
+			if !isParallelStep(assessments[i]) {
+				ctx = e.runAssessment(ctx, t, newT, f, featName, featResult, assessments[i], i, false)
+			} else {
+				// the adjacent parallel assessments run as the subtests of a group subtest, which only
+				// completes once all of them are done
+				end := i
+				for end < len(assessments) && isParallelStep(assessments[end]) {
+					end++
 				}
-				ctx = e.executeSteps(ctx, internalT, []types.Step{assess})
-			})
+				groupCtx := ctx
+				newT.Run("parallel", func(groupT *testing.T) {
+					for j := i; j < end; j++ {
+						e.runAssessment(groupCtx, t, groupT, f, featName, featResult, assessments[j], j, true)
+					}
+				})
+				i = end - 1
+			}
 			// Check if the Test assessment under question performed a `t.Fail()` or `t.Failed()` invocation.
 			// We need to track that and stop the next set of assessment in the feature under test from getting
 			// executed
@@ -476,6 +717,8 @@ func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string
 			}
 		}
 
+		e.diffResources(ctx, newT, f, snapshots, featResult)
+
 		// Let us fail the test fast and not run the teardown in case if the framework specific fail-fast mode is
 		// invoked to make sure we leave the traces of the failed test behind to enable better debugging for the
 		// test developers
@@ -491,6 +734,69 @@ func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string
 	return ctx
 }
 
+// runAssessment executes the assessment as a subtest of featT and returns the context returned by the
+// assessment. A parallel assessment is marked using t.Parallel, so it is only executed once the function
+// running featT returns, and the context it returns is discarded.
+func (e *testEnv) runAssessment(ctx context.Context, t, featT *testing.T, f types.Feature, featName string, featResult *featureResult, assess types.Step, index int, parallel bool) context.Context {
+	assessName := assess.Name()
+	if dAssess, ok := assess.(types.DescribableStep); ok && dAssess.Description() != "" {
+		t.Logf("Processing Assessment: %s", dAssess.Description())
+	}
+	if assessName == "" {
+		assessName = fmt.Sprintf("Assessment-%d", index+1)
+	}
+	featT.Run(assessName, func(internalT *testing.T) {
+		if parallel {
+			internalT.Parallel()
+		}
+		assessResult := assessmentResult{name: assessName, testName: internalT.Name()}
+		start := time.Now()
+		defer func() {
+			assessResult.failed = internalT.Failed()
+			assessResult.skipped = internalT.Skipped()
+			assessResult.duration = time.Since(start)
+			if threshold := e.cfg.SlowThreshold(); threshold > 0 && assessResult.duration > threshold {
+				assessResult.slow = true
+				klog.Warningf("assessment %q of feature %q took %.2fs (slow)", assessName, featName, assessResult.duration.Seconds())
+			}
+			featResult.addAssessment(assessResult)
+			e.notify(func(o Observer) { o.OnAssessFinish(ctx, f, assessResult.result()) })
+		}()
+		e.notify(func(o Observer) { o.OnAssessStart(ctx, f, assess) })
+		skipped, message := e.requireAssessmentProcessing(assess, index+1)
+		if skipped {
+			assessResult.skipReason = message
+			internalT.Skipf(message)
+		}
+		next := e.executeSteps(ctx, internalT, []types.Step{assess})
+		if !parallel {
+			ctx = next
+		}
+	})
+	return ctx
+}
+
+// isParallelStep returns true if the step is to be executed as a parallel subtest
+func isParallelStep(step types.Step) bool {
+	s, ok := step.(types.ParallelStep)
+	return ok && s.Parallel()
+}
+
+// checkFeatureRequirements runs the checks of the prerequisites of the feature configured using Require,
+// if any, and returns the error of the first unmet one. No check is run in dry-run mode.
+func (e *testEnv) checkFeatureRequirements(ctx context.Context, f types.Feature) error {
+	rf, ok := f.(types.RequiringFeature)
+	if !ok || e.cfg.DryRunMode() {
+		return nil
+	}
+	for _, check := range rf.Requirements() {
+		if err := check(ctx, e.cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // requireFeatureProcessing is a wrapper around the requireProcessing function to process the feature level validation
 func (e *testEnv) requireFeatureProcessing(f types.Feature) (skip bool, message string) {
 	requiredRegexp := e.cfg.FeatureRegex()
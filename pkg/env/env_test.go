@@ -18,10 +18,22 @@ package env
 
 import (
 	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/internal/types"
 
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
@@ -665,6 +677,298 @@ func TestEnv_Context_Propagation(t *testing.T) {
 	}
 }
 
+func TestEnv_FeatureStore(t *testing.T) {
+	env := New()
+	f1 := features.New("store feature").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			features.StoreFromContext(ctx).Set("name", "created-in-setup")
+			return ctx
+		}).
+		Assess("read from store", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if name, ok := features.StoreFromContext(ctx).GetString("name"); !ok || name != "created-in-setup" {
+				t.Errorf("expected value set during setup to be available, got %q", name)
+			}
+			return ctx
+		})
+	f2 := features.New("other feature").
+		Assess("store is scoped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if _, ok := features.StoreFromContext(ctx).Get("name"); ok {
+				t.Error("expected value set by another feature to not be available")
+			}
+			return ctx
+		})
+
+	out := env.Test(t, f1.Feature(), f2.Feature())
+	if features.StoreFromContext(out) != nil {
+		t.Error("expected feature store to be released after the features are tested")
+	}
+}
+
+func TestEnv_ClusterHealthMonitor(t *testing.T) {
+	var checks atomic.Int32
+	oldCheck := clusterHealthCheck
+	clusterHealthCheck = func(ctx context.Context, cfg *envconf.Config) error {
+		checks.Add(1)
+		return nil
+	}
+	defer func() { clusterHealthCheck = oldCheck }()
+
+	env := NewWithConfig(envconf.New().WithClusterHealthMonitor(10 * time.Millisecond))
+	f := features.New("monitored feature").
+		Assess("long running", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			time.Sleep(100 * time.Millisecond)
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+
+	count := checks.Load()
+	if count == 0 {
+		t.Fatal("expected the cluster health check to be invoked while the feature was running")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if checks.Load() != count {
+		t.Error("expected the cluster health monitor to stop once the feature completed")
+	}
+}
+
+func TestEnv_SignalHandling(t *testing.T) {
+	exited := make(chan int, 1)
+	oldExit := exit
+	exit = func(code int) { exited <- code }
+	defer func() { exit = oldExit }()
+
+	env := newTestEnv()
+	var finishCtx context.Context
+	env.Finish(func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		finishCtx = ctx
+		return ctx, nil
+	})
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxRunsKeyString{}, 1))
+	env.setRunContext(ctx)
+
+	sigCh := make(chan os.Signal, 1)
+	stop := env.handleSignals(sigCh, cancel)
+	defer stop()
+	sigCh <- os.Interrupt
+
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Errorf("expected exit code 1, got %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the signal to be handled")
+	}
+	if ctx.Err() == nil {
+		t.Error("expected the run context to be cancelled")
+	}
+	if finishCtx == nil {
+		t.Fatal("expected the finish actions to be executed")
+	}
+	if finishCtx.Err() != nil || finishCtx.Value(ctxRunsKeyString{}) != 1 {
+		t.Error("expected the finish actions to get a live context carrying the values of the run")
+	}
+	if !SuiteFailed(finishCtx) {
+		t.Error("expected the interrupted suite to be reported as failed")
+	}
+	if _, ok := env.runFinishActions(ctx, false); ok {
+		t.Error("expected the finish actions to be executed only once")
+	}
+}
+
+func TestEnv_JUnitReport(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithAssessmentRegex("passing").WithSkipFeatureRegex("skipped")).(*testEnv)
+	f := features.New("reported feature").
+		Assess("passing assessment", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			return ctx
+		}).
+		Assess("filtered assessment", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+	// the skipped feature skips the test it is tested by
+	t.Run("skipped", func(t *testing.T) {
+		_ = env.Test(t, features.New("skipped feature").Assess("assessment", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			return ctx
+		}).Feature())
+	})
+
+	// failures cannot be produced from within this test, record one directly along with its output
+	env.report.addFeature(&featureResult{
+		name:        "failed feature",
+		testName:    "TestSuite/failed_feature",
+		start:       time.Now(),
+		failed:      true,
+		assessments: []assessmentResult{{name: "failed assessment", testName: "TestSuite/failed_feature/failed_assessment", failed: true}},
+		failures:    []string{"cluster went unhealthy"},
+	})
+	env.report.output = newTestOutput()
+	for _, line := range []string{
+		"--- FAIL: TestSuite (0.00s)",
+		"    --- FAIL: TestSuite/failed_feature (0.00s)",
+		"        --- FAIL: TestSuite/failed_feature/failed_assessment (0.00s)",
+		"            suite_test.go:42: expected 3 replicas, got 2",
+	} {
+		env.report.output.add(line)
+	}
+
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	if err := env.report.writeJUnit(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid junit report: %s", err)
+	}
+
+	if report.Tests != 4 || report.Failures != 1 || report.Skipped != 2 {
+		t.Errorf("unexpected totals: tests=%d failures=%d skipped=%d", report.Tests, report.Failures, report.Skipped)
+	}
+	if len(report.TestSuites) != 3 || report.TestSuites[0].Name != "reported feature" {
+		t.Fatalf("expected a testsuite per feature, got %+v", report.TestSuites)
+	}
+	cases := report.TestSuites[0].TestCases
+	if len(cases) != 2 || cases[0].Name != "passing assessment" || cases[0].Failure != nil || cases[1].Skipped == nil {
+		t.Errorf("unexpected testcases for the reported feature: %+v", cases)
+	}
+	if !strings.Contains(cases[1].Skipped.Message, "filtered assessment") {
+		t.Errorf("expected the reason of the assessment skip to be reported, got: %s", cases[1].Skipped.Message)
+	}
+	if skipped := report.TestSuites[1].TestCases; len(skipped) != 1 || skipped[0].Skipped == nil || !strings.Contains(skipped[0].Skipped.Message, "skipped feature") {
+		t.Errorf("expected the skipped feature to be reported as skipped, got %+v", skipped)
+	}
+	if failure := report.TestSuites[2].TestCases[0].Failure; failure == nil || !strings.Contains(failure.Message, "cluster went unhealthy") ||
+		!strings.Contains(failure.Message, "suite_test.go:42: expected 3 replicas, got 2") {
+		t.Errorf("expected the failed assessment to be reported with the recorded failure and the message of the step, got %+v", failure)
+	}
+}
+
+func TestTestOutput_Messages(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{
+			name: "verbose",
+			lines: []string{
+				"=== RUN   TestSuite",
+				"=== RUN   TestSuite/feature",
+				"=== RUN   TestSuite/feature/assessment",
+				"    suite_test.go:10: connection refused",
+				"        retrying",
+				"=== NAME  TestSuite/feature",
+				"    suite_test.go:20: teardown failed",
+				"I1015 10:00:00.000000 1 env.go:42] not a message",
+				"--- FAIL: TestSuite/feature/assessment (0.01s)",
+				"--- FAIL: TestSuite/feature (0.02s)",
+			},
+		},
+		{
+			name: "json",
+			lines: []string{
+				"\x16=== RUN   TestSuite/feature",
+				"\x16=== RUN   TestSuite/feature/assessment",
+				"\x0f    suite_test.go:10: connection refused",
+				"        retrying\x0e",
+				"\x16=== NAME  TestSuite/feature",
+				"\x0f    suite_test.go:20: teardown failed\x0e",
+				"\x16--- FAIL: TestSuite/feature (0.02s)",
+			},
+		},
+		{
+			name: "regular",
+			lines: []string{
+				"--- FAIL: TestSuite (0.02s)",
+				"    --- FAIL: TestSuite/feature (0.02s)",
+				"        suite_test.go:20: teardown failed",
+				"        --- FAIL: TestSuite/feature/assessment (0.01s)",
+				"            suite_test.go:10: connection refused",
+				"                retrying",
+				"FAIL",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			output := newTestOutput()
+			for _, line := range tc.lines {
+				output.add(line)
+			}
+			if got := output.messagesOf("TestSuite/feature/assessment"); len(got) != 1 || got[0] != "suite_test.go:10: connection refused\nretrying" {
+				t.Errorf("unexpected messages of the assessment: %q", got)
+			}
+			if got := output.messagesOf("TestSuite/feature", "TestSuite/feature/assessment"); len(got) != 1 || got[0] != "suite_test.go:20: teardown failed" {
+				t.Errorf("unexpected messages of the feature: %q", got)
+			}
+			if got := output.messagesOf("TestSuite"); len(got) != 2 {
+				t.Errorf("expected the messages of the subtests to be included, got: %q", got)
+			}
+		})
+	}
+}
+
+func TestEnv_SlowThreshold(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithSlowThreshold(20 * time.Millisecond)).(*testEnv)
+	f := features.New("timed feature").
+		Assess("fast", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			return ctx
+		}).
+		Assess("slow", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			time.Sleep(50 * time.Millisecond)
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+
+	if len(env.report.features) != 1 || len(env.report.features[0].assessments) != 2 {
+		t.Fatalf("expected the assessments to be recorded, got %+v", env.report.features)
+	}
+	fast, slow := env.report.features[0].assessments[0], env.report.features[0].assessments[1]
+	if fast.slow {
+		t.Error("expected the fast assessment not to be flagged as slow")
+	}
+	if !slow.slow || slow.duration < 50*time.Millisecond {
+		t.Errorf("expected the slow assessment to be flagged as slow with its duration recorded, got %+v", slow)
+	}
+}
+
+func TestEnv_SubtestSteps(t *testing.T) {
+	env := newTestEnv()
+	var names []string
+	record := func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		names = append(names, t.Name())
+		return context.WithValue(ctx, ctxRunsKeyString{}, len(names))
+	}
+	f := features.New("stepped feature").
+		WithSetupStep("create namespace", record).
+		WithSetupStep("deploy app", record).
+		Assess("app is deployed", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if ctx.Value(ctxRunsKeyString{}) != 2 {
+				t.Error("expected the context of the setup subtests to be passed to the assessment")
+			}
+			return ctx
+		}).
+		WithTeardownStep("delete namespace", record)
+	_ = env.Test(t, f.Feature())
+
+	expected := []string{
+		t.Name() + "/stepped_feature/create_namespace",
+		t.Name() + "/stepped_feature/deploy_app",
+		t.Name() + "/stepped_feature/delete_namespace",
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("expected the steps to run as subtests %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected step to run as subtest %s, got %s", expected[i], names[i])
+		}
+	}
+}
+
 func TestTestEnv_TestInParallel(t *testing.T) {
 	env := NewParallel()
 	beforeEachCallCount := 0
@@ -839,3 +1143,432 @@ func getFeaturesForTest() []features.Feature {
 		}).Feature()
 	return []features.Feature{f1, f2}
 }
+
+func TestSetLogVerbosity(t *testing.T) {
+	defer func() {
+		if err := setLogVerbosity(0); err != nil {
+			t.Error(err)
+		}
+	}()
+	if err := setLogVerbosity(5); err != nil {
+		t.Fatal(err)
+	}
+	if !klog.V(5).Enabled() || klog.V(6).Enabled() {
+		t.Error("expected klog verbosity to be set to 5")
+	}
+}
+
+func TestEnv_CheckFeatureRequirements(t *testing.T) {
+	checked := 0
+	feature := features.New("requiring").
+		Require(func(ctx context.Context, _ *envconf.Config) error {
+			checked++
+			return nil
+		}).
+		Require(func(ctx context.Context, _ *envconf.Config) error {
+			checked++
+			return errors.New("storage class fast-ssd not found")
+		}).
+		Require(func(ctx context.Context, _ *envconf.Config) error {
+			checked++
+			return nil
+		}).Feature()
+
+	env := newTestEnv()
+	err := env.checkFeatureRequirements(context.TODO(), feature)
+	if err == nil || err.Error() != "storage class fast-ssd not found" {
+		t.Errorf("expected the unmet requirement to be reported, got: %v", err)
+	}
+	if checked != 2 {
+		t.Errorf("expected the checks to stop at the first unmet requirement, got %d checks", checked)
+	}
+
+	env.cfg.WithDryRunMode()
+	if err := env.checkFeatureRequirements(context.TODO(), feature); err != nil {
+		t.Errorf("expected no requirement to be checked in dry-run mode, got: %v", err)
+	}
+}
+
+func TestEnv_Test_WithMetRequirements(t *testing.T) {
+	env := newTestEnv()
+	assessed := false
+	feature := features.New("requiring").
+		Require(func(ctx context.Context, _ *envconf.Config) error { return nil }).
+		Assess("assessed", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			assessed = true
+			return ctx
+		}).Feature()
+	env.Test(t, feature)
+	if !assessed {
+		t.Error("expected the feature to be assessed when its requirements are met")
+	}
+}
+
+func TestEnv_Test_AssessParallel(t *testing.T) {
+	env := newTestEnv()
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	parallel := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		if ctx.Value(ctxRunsKeyString{}) != "setup" {
+			t.Error("expected the context of the setup to be passed to the parallel assessment")
+		}
+		time.Sleep(100 * time.Millisecond)
+		record("parallel " + t.Name())
+		return context.WithValue(ctx, ctxRunsKeyString{}, "parallel")
+	}
+	f := features.New("parallel assessments").
+		Setup(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			return context.WithValue(ctx, ctxRunsKeyString{}, "setup")
+		}).
+		AssessParallel("first", parallel).
+		AssessParallel("second", parallel).
+		Assess("after", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			if ctx.Value(ctxRunsKeyString{}) != "setup" {
+				t.Error("expected the contexts returned by the parallel assessments to be discarded")
+			}
+			record("after")
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			record("teardown")
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+
+	if len(events) != 4 || events[2] != "after" || events[3] != "teardown" {
+		t.Fatalf("expected the parallel assessments to complete before the next steps, got %v", events)
+	}
+	prefix := "parallel " + t.Name() + "/parallel_assessments/parallel/"
+	for _, event := range events[:2] {
+		if event != prefix+"first" && event != prefix+"second" {
+			t.Errorf("expected the parallel assessments to run as subtests of the parallel group, got %s", event)
+		}
+	}
+}
+
+func TestEnv_WithObserver(t *testing.T) {
+	var events []string
+	env := newTestEnv()
+	observable, ok := Environment(env).(ObservableEnvironment)
+	if !ok {
+		t.Fatal("expected the environment to implement ObservableEnvironment")
+	}
+	observable.WithObserver(ObserverFuncs{
+		FeatureStart: func(_ context.Context, f types.Feature) {
+			events = append(events, "feature start "+f.Name())
+		},
+		AssessStart: func(_ context.Context, f types.Feature, assessment types.Step) {
+			events = append(events, "assess start "+assessment.Name())
+		},
+		AssessFinish: func(_ context.Context, f types.Feature, result AssessmentResult) {
+			events = append(events, fmt.Sprintf("assess finish %s skipped=%t", result.Name, result.Skipped))
+		},
+		FeatureFinish: func(_ context.Context, f types.Feature, result FeatureResult) {
+			events = append(events, fmt.Sprintf("feature finish %s assessments=%d", result.Name, len(result.Assessments)))
+		},
+		Teardown: func(_ context.Context, failed bool) {
+			events = append(events, fmt.Sprintf("teardown failed=%t", failed))
+		},
+	})
+	f := features.New("observed").
+		Assess("first", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			events = append(events, "first")
+			return ctx
+		}).
+		Assess("second", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			t.Skip("skipped")
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+	env.runFinishActions(context.TODO(), true)
+
+	expected := []string{
+		"feature start observed",
+		"assess start first",
+		"first",
+		"assess finish first skipped=false",
+		"assess start second",
+		"assess finish second skipped=true",
+		"feature finish observed assessments=2",
+		"teardown failed=true",
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected events:\n%s\nexpected:\n%s", strings.Join(events, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestEnv_Fixture(t *testing.T) {
+	type fixtureKey struct{}
+	var events []string
+	operator := features.NewFixture("operator",
+		func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			events = append(events, "operator setup")
+			return context.WithValue(ctx, fixtureKey{}, "installed"), nil
+		},
+		func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			events = append(events, fmt.Sprintf("operator teardown %v", ctx.Value(fixtureKey{})))
+			return ctx, nil
+		})
+	dependent := func(name string) types.Feature {
+		return features.New(name).WithFixture(operator).
+			Assess("uses operator", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				events = append(events, fmt.Sprintf("%s %v", name, ctx.Value(fixtureKey{})))
+				return ctx
+			}).Feature()
+	}
+	first, second, untested := dependent("first"), dependent("second"), dependent("untested")
+
+	env := newTestEnv()
+	_ = env.Test(t, first)
+	_ = env.Test(t, second)
+	events = append(events, "tests done")
+	env.runFinishActions(context.TODO(), false)
+
+	expected := []string{
+		"operator setup",
+		"first installed",
+		"second installed",
+		"tests done",
+		"operator teardown installed",
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected events:\n%s\nexpected:\n%s", strings.Join(events, "\n"), strings.Join(expected, "\n"))
+	}
+
+	// once all the features depending on the fixture are done, it is torn down right away and set up again
+	// if needed
+	events = nil
+	env = newTestEnv()
+	_ = env.Test(t, untested, first, second)
+	_ = env.Test(t, first)
+	expected = []string{
+		"operator setup",
+		"untested installed",
+		"first installed",
+		"second installed",
+		"operator teardown installed",
+		"operator setup",
+		"first installed",
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected events:\n%s\nexpected:\n%s", strings.Join(events, "\n"), strings.Join(expected, "\n"))
+	}
+	env.runFinishActions(context.TODO(), false)
+}
+
+func TestEnv_SkippedRequirement(t *testing.T) {
+	var result FeatureResult
+	env := newTestEnv()
+	env.WithObserver(ObserverFuncs{
+		FeatureFinish: func(_ context.Context, _ types.Feature, r FeatureResult) { result = r },
+	})
+	f := features.New("version specific").
+		Require(func(context.Context, *envconf.Config) error {
+			return &types.SkipError{Reason: "server version v1.26.0 is outside of the supported range >= 1.27"}
+		}).
+		Assess("never run", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			t.Error("the assessment of a skipped feature must not run")
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+	if !result.Skipped || result.Failed {
+		t.Errorf("expected the feature to be skipped, got skipped=%t failed=%t", result.Skipped, result.Failed)
+	}
+}
+
+func TestEnv_FinishLast(t *testing.T) {
+	var order []string
+	step := func(name string) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			order = append(order, name)
+			return ctx, nil
+		}
+	}
+	env := newTestEnv()
+	finishLast, ok := Environment(env).(FinishLastEnvironment)
+	if !ok {
+		t.Fatal("expected the environment to implement FinishLastEnvironment")
+	}
+	finishLast.FinishLast(step("destroy cluster"))
+	env.Finish(step("delete namespace"), step("uninstall operator"))
+	env.runFinishActions(context.TODO(), false)
+
+	expected := []string{"delete namespace", "uninstall operator", "destroy cluster"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the finish funcs to run in order %v, got %v", expected, order)
+	}
+}
+
+func TestEnv_DependsOn(t *testing.T) {
+	var events []string
+	feature := func(name string, dependencies ...string) *features.FeatureBuilder {
+		return features.New(name).DependsOn(dependencies...).
+			Assess("records", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				events = append(events, name)
+				return ctx
+			})
+	}
+	skipped := features.New("skipped").
+		Require(func(context.Context, *envconf.Config) error { return &types.SkipError{Reason: "not applicable"} }).
+		Feature()
+
+	env := newTestEnv()
+	_ = env.Test(t,
+		feature("verify", "migrate").Feature(),
+		feature("migrate", "install").Feature(),
+		feature("install").Feature(),
+		feature("unrelated").Feature(),
+		feature("after skipped", "skipped").Feature(),
+		skipped,
+	)
+	expected := []string{"install", "migrate", "verify", "unrelated"}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the features to be tested in the order %v, got %v", expected, events)
+	}
+}
+
+func TestEnv_RegisterFeaturesCycle(t *testing.T) {
+	setup := false
+	env := newTestEnv()
+	env.Setup(func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+		setup = true
+		return ctx, nil
+	})
+	dependent, ok := Environment(env).(DependentFeaturesEnvironment)
+	if !ok {
+		t.Fatal("expected the environment to implement DependentFeaturesEnvironment")
+	}
+	dependent.RegisterFeatures(
+		features.New("install").Feature(),
+		features.New("migrate").DependsOn("verify").Feature(),
+		features.New("verify").DependsOn("migrate").Feature(),
+	)
+	if len(env.dependencyErrs) != 1 || !strings.Contains(env.dependencyErrs[0].Error(), "feature dependency cycle: migrate -> verify -> migrate") {
+		t.Fatalf("expected the cycle to be reported at registration, got: %v", env.dependencyErrs)
+	}
+
+	// the suite fails before its Setup funcs are executed, without running the tests
+	if code := env.Run(nil); code != 1 {
+		t.Errorf("expected the suite to fail, got exit code %d", code)
+	}
+	if setup {
+		t.Error("expected the Setup funcs not to be executed")
+	}
+}
+
+func TestOrderFeatures(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies map[string][]string
+		order        []string
+		err          string
+	}{
+		{
+			name:  "registration order without dependencies",
+			order: []string{"a", "b", "c"},
+		},
+		{
+			name:         "dependencies first",
+			dependencies: map[string][]string{"a": {"c"}, "b": {"a"}},
+			order:        []string{"c", "a", "b"},
+		},
+		{
+			name:         "unknown dependency",
+			dependencies: map[string][]string{"b": {"d"}},
+			err:          `feature "b" depends on unknown feature "d"`,
+		},
+		{
+			name:         "cycle",
+			dependencies: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			err:          "feature dependency cycle: a -> b -> c -> a",
+		},
+		{
+			name:         "self dependency",
+			dependencies: map[string][]string{"b": {"b"}},
+			err:          "feature dependency cycle: b -> b",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := []string{"a", "b", "c"}
+			var feats []types.Feature
+			for _, name := range names {
+				feats = append(feats, features.New(name).DependsOn(test.dependencies[name]...).Feature())
+			}
+			nodes, err := orderFeatures(names, feats)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got: %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var order []string
+			for _, node := range nodes {
+				order = append(order, node.name)
+			}
+			if strings.Join(order, ",") != strings.Join(test.order, ",") {
+				t.Errorf("expected order %v, got %v", test.order, order)
+			}
+
+			nodes[0].finish(true, false)
+			for _, node := range nodes[1:] {
+				if len(node.dependencies) > 0 && node.dependencies[0] == nodes[0] {
+					if reason := node.waitDependencies(); reason != fmt.Sprintf("skipped: dependency %s failed", nodes[0].name) {
+						t.Errorf("unexpected skip reason: %q", reason)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEnv_TrackResources(t *testing.T) {
+	replicas := map[string]int{"default/web": 1}
+	oldSnapshot := resourceSnapshot
+	resourceSnapshot = func(_ context.Context, _ *envconf.Config, obj k8s.Object) (string, error) {
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		if _, ok := replicas[key]; !ok {
+			return "", nil
+		}
+		return fmt.Sprintf("kind: Deployment\nmetadata:\n  name: %s\nspec:\n  replicas: %d\n", obj.GetName(), replicas[key]), nil
+	}
+	defer func() { resourceSnapshot = oldSnapshot }()
+
+	var result FeatureResult
+	env := newTestEnv()
+	env.WithObserver(ObserverFuncs{
+		FeatureFinish: func(_ context.Context, _ types.Feature, r FeatureResult) { result = r },
+	})
+	web := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	untouched := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "untouched", Namespace: "default"}}
+	f := features.New("scale").
+		TrackResources(web, untouched).
+		Assess("scale up", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			replicas["default/web"] = 3
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			delete(replicas, "default/web")
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+
+	if len(result.ResourceDiffs) != 1 {
+		t.Fatalf("expected the change of a single resource, got %v", result.ResourceDiffs)
+	}
+	diff := result.ResourceDiffs[0]
+	if diff.Resource != "Deployment default/web" {
+		t.Errorf("unexpected resource %q", diff.Resource)
+	}
+	if !strings.Contains(diff.Diff, "-  replicas: 1\n+  replicas: 3\n") {
+		t.Errorf("expected the replica change in the diff, got:\n%s", diff.Diff)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// fixtureSet records the fixtures acquired by the features tested in an environment, so that the fixtures still
+// set up when the test suite finishes are torn down
+type fixtureSet struct {
+	mu       sync.Mutex
+	fixtures []types.Fixture
+}
+
+func (s *fixtureSet) add(fixture types.Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.fixtures {
+		if f == fixture {
+			return
+		}
+	}
+	s.fixtures = append(s.fixtures, fixture)
+}
+
+// fixturesOf returns the fixtures the feature depends on
+func fixturesOf(f types.Feature) []types.Fixture {
+	if ff, ok := f.(types.FixtureFeature); ok {
+		return ff.Fixtures()
+	}
+	return nil
+}
+
+// acquireFixtures sets up the fixtures the feature depends on which are not set up yet and returns the context
+// extended with the values added by their setup
+func (e *testEnv) acquireFixtures(ctx context.Context, f types.Feature) (context.Context, error) {
+	if e.cfg.DryRunMode() {
+		return ctx, nil
+	}
+	for _, fixture := range fixturesOf(f) {
+		e.fixtures.add(fixture)
+		var err error
+		if ctx, err = fixture.Acquire(ctx, e.cfg); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+// releaseFixtures records that the feature is done with its fixtures, which tears down the fixtures of which it
+// is the last dependent feature. A failed teardown fails t.
+func (e *testEnv) releaseFixtures(t *testing.T, f types.Feature) {
+	if e.cfg.DryRunMode() {
+		return
+	}
+	fixtures := fixturesOf(f)
+	for i := len(fixtures) - 1; i >= 0; i-- {
+		if err := fixtures[i].Release(e.cfg); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// closeFixtures tears down the fixtures still set up, for instance because some of the features depending on them
+// were not tested
+func (e *testEnv) closeFixtures() {
+	e.fixtures.mu.Lock()
+	defer e.fixtures.mu.Unlock()
+	for i := len(e.fixtures.fixtures) - 1; i >= 0; i-- {
+		fixture := e.fixtures.fixtures[i]
+		if err := fixture.Close(e.cfg); err != nil {
+			klog.ErrorS(err, "Failed to tear down fixture", "fixture", fixture.Name())
+		}
+	}
+	e.fixtures.fixtures = nil
+}
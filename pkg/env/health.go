@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// clusterHealthCheck is the check invoked by the cluster health monitor. It is defined
+// as a variable so that it can be replaced while unit testing the monitor workflow.
+var clusterHealthCheck = checkClusterHealth
+
+// checkClusterHealth verifies that the API server reports itself as ready and that all
+// the nodes of the cluster have the v1.NodeReady condition set to v1.ConditionTrue.
+func checkClusterHealth(ctx context.Context, cfg *envconf.Config) error {
+	client, err := cfg.NewClient()
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(client.RESTConfig())
+	if err != nil {
+		return err
+	}
+	if _, err := clientset.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err != nil {
+		return fmt.Errorf("api server is not ready: %w", err)
+	}
+
+	var nodes v1.NodeList
+	if err := client.Resources().List(ctx, &nodes); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	var notReady []string
+	for _, node := range nodes.Items {
+		ready := false
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == v1.NodeReady && cond.Status == v1.ConditionTrue {
+				ready = true
+				break
+			}
+		}
+		if !ready {
+			notReady = append(notReady, node.Name)
+		}
+	}
+	if len(notReady) > 0 {
+		return fmt.Errorf("nodes not ready: %s", strings.Join(notReady, ", "))
+	}
+	return nil
+}
+
+// startClusterHealthMonitor launches a background routine that checks the health of the cluster
+// at the configured interval while the feature of featResult is being tested. The first failed
+// check marks t as failed, records the failure in featResult and stops the monitor. The returned function stops the monitor
+// and blocks until the background routine has exited so that t is never used after the feature
+// has completed.
+func (e *testEnv) startClusterHealthMonitor(ctx context.Context, t *testing.T, featResult *featureResult) (stop func()) {
+	featName := featResult.name
+	interval := e.cfg.ClusterHealthMonitorInterval()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := clusterHealthCheck(ctx, e.cfg); err != nil {
+					// the feature may have just completed and cancelled the check mid-flight
+					if ctx.Err() != nil {
+						return
+					}
+					klog.V(2).ErrorS(err, "Cluster health check failed", "feature", featName)
+					failure := fmt.Sprintf("cluster went unhealthy at %s while testing feature %q: %s", time.Now().Format(time.RFC3339), featName, err)
+					featResult.addFailure(failure)
+					t.Error(failure)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+type (
+	Observer              = types.Observer
+	ObservableEnvironment = types.ObservableEnvironment
+	FeatureResult         = types.FeatureResult
+	AssessmentResult      = types.AssessmentResult
+	ResourceDiff          = types.ResourceDiff
+)
+
+// ObserverFuncs is an Observer calling the funcs that are set, which saves implementing all the methods of
+// Observer when only some of the events are of interest, e.g.
+//
+//	testenv.(env.ObservableEnvironment).WithObserver(env.ObserverFuncs{
+//		FeatureFinish: func(ctx context.Context, f features.Feature, result env.FeatureResult) {
+//			if result.Failed {
+//				notify(fmt.Sprintf("feature %s failed", result.Name))
+//			}
+//		},
+//	})
+type ObserverFuncs struct {
+	Setup         func(ctx context.Context, err error)
+	FeatureStart  func(ctx context.Context, feature types.Feature)
+	AssessStart   func(ctx context.Context, feature types.Feature, assessment types.Step)
+	AssessFinish  func(ctx context.Context, feature types.Feature, result AssessmentResult)
+	FeatureFinish func(ctx context.Context, feature types.Feature, result FeatureResult)
+	Teardown      func(ctx context.Context, failed bool)
+}
+
+func (o ObserverFuncs) OnSetup(ctx context.Context, err error) {
+	if o.Setup != nil {
+		o.Setup(ctx, err)
+	}
+}
+
+func (o ObserverFuncs) OnFeatureStart(ctx context.Context, feature types.Feature) {
+	if o.FeatureStart != nil {
+		o.FeatureStart(ctx, feature)
+	}
+}
+
+func (o ObserverFuncs) OnAssessStart(ctx context.Context, feature types.Feature, assessment types.Step) {
+	if o.AssessStart != nil {
+		o.AssessStart(ctx, feature, assessment)
+	}
+}
+
+func (o ObserverFuncs) OnAssessFinish(ctx context.Context, feature types.Feature, result AssessmentResult) {
+	if o.AssessFinish != nil {
+		o.AssessFinish(ctx, feature, result)
+	}
+}
+
+func (o ObserverFuncs) OnFeatureFinish(ctx context.Context, feature types.Feature, result FeatureResult) {
+	if o.FeatureFinish != nil {
+		o.FeatureFinish(ctx, feature, result)
+	}
+}
+
+func (o ObserverFuncs) OnTeardown(ctx context.Context, failed bool) {
+	if o.Teardown != nil {
+		o.Teardown(ctx, failed)
+	}
+}
+
+// WithObserver registers an Observer notified of the lifecycle events of the test suite, its features and
+// their assessments. The observers are notified in the order they were registered.
+func (e *testEnv) WithObserver(o Observer) types.Environment {
+	if o == nil {
+		return e
+	}
+	e.observers = append(e.observers, o)
+	return e
+}
+
+// notify calls fn for each of the registered observers
+func (e *testEnv) notify(fn func(o Observer)) {
+	for _, o := range e.observers {
+		fn(o)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// testOutputPrefixes are the prefixes of the lines of the test output naming the test the following lines belong to
+var testOutputPrefixes = []string{"=== RUN", "=== CONT", "=== NAME", "=== PAUSE", "--- FAIL:", "--- PASS:", "--- SKIP:"}
+
+// logLinePattern matches the first line of a message logged through t, which starts with the location of the call
+var logLinePattern = regexp.MustCompile(`^\S+\.go:\d+: `)
+
+// testOutput records the messages logged by the tests through t, such as the failures reported using t.Error
+// or t.Fatal, so that they can be included in the JUnit report. The testing package does not expose these
+// messages, so the output of the tests is copied as is to the original standard output and parsed along the
+// way: each message is attributed to the last test named by the output before it, which holds for both the
+// verbose and the regular output formats of go test.
+type testOutput struct {
+	mu       sync.Mutex
+	messages map[string][]string
+	// current is the test the following messages belong to and inMessage is set while reading the
+	// continuation lines of a message
+	current   string
+	inMessage bool
+
+	stopOnce sync.Once
+	stopFn   func()
+}
+
+// newTestOutput returns a testOutput recording the lines provided using add
+func newTestOutput() *testOutput {
+	return &testOutput{messages: make(map[string][]string), stopFn: func() {}}
+}
+
+// captureTestOutput redirects the standard output to a pipe recording the messages logged by the tests. It
+// must be called before the tests are run, as the testing package writes to the standard output set when
+// they start. The output is restored by stop.
+func captureTestOutput() (*testOutput, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	o := newTestOutput()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(io.TeeReader(r, stdout))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			o.add(scanner.Text())
+		}
+		// keep copying the output when a line is too long to be parsed
+		_, _ = io.Copy(stdout, r)
+	}()
+	o.stopFn = func() {
+		os.Stdout = stdout
+		_ = w.Close()
+		<-done
+		_ = r.Close()
+	}
+	return o, nil
+}
+
+// stop restores the standard output once all the output of the tests has been recorded. It must only be
+// called once the tests are done, as the testing package keeps writing to the pipe until then.
+func (o *testOutput) stop() {
+	if o == nil {
+		return
+	}
+	o.stopOnce.Do(o.stopFn)
+}
+
+// add records a line of the test output
+func (o *testOutput) add(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// the framing lines and the errors are marked in the output of go test -json
+	line = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(line, "\x16"), "\x0f"), "\x0e")
+	trimmed := strings.TrimLeft(line, " \t")
+	for _, prefix := range testOutputPrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			name := strings.TrimSpace(strings.TrimPrefix(trimmed, prefix))
+			if i := strings.LastIndex(name, " ("); i >= 0 {
+				name = name[:i]
+			}
+			o.current, o.inMessage = name, false
+			return
+		}
+	}
+	switch {
+	case o.current == "" || trimmed == line:
+		// the lines that are not indented, such as the summary of the run, are not logged by a test
+		o.inMessage = false
+	case logLinePattern.MatchString(trimmed):
+		o.messages[o.current] = append(o.messages[o.current], trimmed)
+		o.inMessage = true
+	case o.inMessage:
+		last := len(o.messages[o.current]) - 1
+		o.messages[o.current][last] += "\n" + trimmed
+	}
+}
+
+// messagesOf returns the messages logged by the test and by its subtests, except for the subtests named
+// in excluded along with their own subtests
+func (o *testOutput) messagesOf(test string, excluded ...string) []string {
+	if o == nil || test == "" {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var names []string
+	for name := range o.messages {
+		if name != test && !strings.HasPrefix(name, test+"/") {
+			continue
+		}
+		skip := false
+		for _, e := range excluded {
+			if e != "" && (name == e || strings.HasPrefix(name, e+"/")) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var messages []string
+	for _, name := range names {
+		messages = append(messages, o.messages[name]...)
+	}
+	return messages
+}
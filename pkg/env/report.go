@@ -0,0 +1,278 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runReport records the outcome of the features tested by an environment. Features
+// can be tested in parallel, so the results are added while holding the lock.
+type runReport struct {
+	mu       sync.Mutex
+	features []*featureResult
+	// output records the messages logged by the tests, if it is captured
+	output *testOutput
+}
+
+// featureResult is the outcome of a tested feature. Failed is set when any step of the
+// feature failed, including its setup and teardown steps. Assessments can run in parallel,
+// so their results are added while holding the lock.
+type featureResult struct {
+	name string
+	// testName is the name of the test of the feature, which the messages it logged are recorded under
+	testName      string
+	start         time.Time
+	duration      time.Duration
+	failed        bool
+	skipped       bool
+	mu            sync.Mutex
+	assessments   []assessmentResult
+	resourceDiffs []ResourceDiff
+	// failures are the failures reported by the framework itself, such as an unmet precondition
+	// or the cluster going unhealthy, as opposed to the ones reported by the steps through t
+	failures []string
+	// skipReason is why the feature was skipped, if it was
+	skipReason string
+}
+
+// assessmentResult is the outcome of an assessment of a feature
+type assessmentResult struct {
+	name string
+	// testName is the name of the test of the assessment, which the messages it logged are recorded under
+	testName string
+	duration time.Duration
+	failed   bool
+	skipped  bool
+	// slow is set when the assessment took longer than the configured slow threshold
+	slow bool
+	// skipReason is why the assessment was skipped by the framework, if it was
+	skipReason string
+}
+
+func (r *runReport) addFeature(f *featureResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.features = append(r.features, f)
+}
+
+// setOutput sets the captured test output the messages of the failed steps are read from
+func (r *runReport) setOutput(output *testOutput) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.output = output
+}
+
+func (f *featureResult) addAssessment(a assessmentResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.assessments = append(f.assessments, a)
+}
+
+// addFailure records a failure of the feature reported by the framework, as opposed to the ones reported
+// by the steps through t, which are only known from the test output
+func (f *featureResult) addFailure(message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, message)
+}
+
+// finish records the outcome of the feature once its test is done
+func (f *featureResult) finish(duration time.Duration, failed, skipped bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.duration, f.failed, f.skipped = duration, failed, skipped
+}
+
+// setSkipReason records why the feature is skipped
+func (f *featureResult) setSkipReason(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.skipReason = reason
+}
+
+func (f *featureResult) addResourceDiff(d ResourceDiff) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resourceDiffs = append(f.resourceDiffs, d)
+}
+
+// result returns the outcome of the feature as reported to the observers
+func (f *featureResult) result(skipped bool) FeatureResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := FeatureResult{
+		Name:     f.name,
+		Start:    f.start,
+		Duration: f.duration,
+		Failed:   f.failed,
+		Skipped:  skipped,
+	}
+	result.ResourceDiffs = append(result.ResourceDiffs, f.resourceDiffs...)
+	for _, a := range f.assessments {
+		result.Assessments = append(result.Assessments, a.result())
+	}
+	return result
+}
+
+// result returns the outcome of the assessment as reported to the observers
+func (a assessmentResult) result() AssessmentResult {
+	return AssessmentResult{Name: a.name, Duration: a.duration, Failed: a.failed, Skipped: a.skipped, Slow: a.slow}
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitTime formats the duration in seconds as expected by the JUnit XML format
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// failureMessage returns the message of a failure, along with the failures recorded by the framework and the
+// messages logged by the failed steps through t, such as the ones of t.Error or t.Fatal, when known.
+func failureMessage(summary string, failures []string) string {
+	if len(failures) == 0 {
+		return summary + ", see the test output for details"
+	}
+	return summary + ": " + strings.Join(failures, "; ")
+}
+
+// skipMessage returns the message of a skip, along with its reason when known
+func skipMessage(summary, reason string) string {
+	if reason == "" {
+		return summary
+	}
+	return summary + ": " + reason
+}
+
+// writeJUnit writes the recorded results as a JUnit XML report at path. Each feature is
+// reported as a testsuite and each of its assessments as a testcase. A feature that failed
+// outside of its assessments, during its setup or teardown steps, is reported with an
+// additional failed testcase named after the feature, and a feature skipped before any of its
+// assessments ran with a skipped testcase named after the feature. The failures include the
+// messages logged by the failed steps when the test output is captured.
+func (r *runReport) writeJUnit(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suites := junitTestSuites{}
+	var total time.Duration
+	for _, f := range r.features {
+		suite, duration := r.junitSuite(f)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		total += duration
+		suites.TestSuites = append(suites.TestSuites, suite)
+	}
+	suites.Time = junitTime(total)
+
+	out, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode junit report: %w", err)
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), out...), 0o644)
+}
+
+// junitSuite returns the testsuite reporting the feature along with the duration of the feature. The feature
+// may still be running when the report is written on interruption, so it is read while holding its lock.
+func (r *runReport) junitSuite(f *featureResult) (junitTestSuite, time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	suite := junitTestSuite{
+		Name:      f.name,
+		Time:      junitTime(f.duration),
+		Timestamp: f.start.Format(time.RFC3339),
+	}
+	assessFailed := false
+	var assessTests []string
+	for _, a := range f.assessments {
+		assessTests = append(assessTests, a.testName)
+		tc := junitTestCase{Name: a.name, ClassName: f.name, Time: junitTime(a.duration)}
+		if a.slow {
+			tc.SystemOut = fmt.Sprintf("assessment took %.2fs (slow)", a.duration.Seconds())
+		}
+		switch {
+		case a.failed:
+			failures := append(append([]string(nil), f.failures...), r.output.messagesOf(a.testName)...)
+			tc.Failure = &junitMessage{Message: failureMessage(fmt.Sprintf("assessment %q of feature %q failed", a.name, f.name), failures)}
+			suite.Failures++
+			assessFailed = true
+		case a.skipped:
+			tc.Skipped = &junitMessage{Message: skipMessage(fmt.Sprintf("assessment %q of feature %q skipped", a.name, f.name), a.skipReason)}
+			suite.Skipped++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	switch {
+	case f.failed && !assessFailed:
+		// the messages of the assessments, which passed, are left out
+		failures := append(append([]string(nil), f.failures...), r.output.messagesOf(f.testName, assessTests...)...)
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      f.name,
+			ClassName: f.name,
+			Time:      junitTime(f.duration),
+			Failure:   &junitMessage{Message: failureMessage(fmt.Sprintf("feature %q failed outside of its assessments", f.name), failures)},
+		})
+		suite.Failures++
+	case f.skipped && len(f.assessments) == 0:
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      f.name,
+			ClassName: f.name,
+			Time:      junitTime(f.duration),
+			Skipped:   &junitMessage{Message: skipMessage(fmt.Sprintf("feature %q skipped", f.name), f.skipReason)},
+		})
+		suite.Skipped++
+	}
+	suite.Tests = len(suite.TestCases)
+	return suite, f.duration
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+// exit terminates the test binary once the Finish actions have run after a signal. It is
+// defined as a variable so that it can be replaced while unit testing the signal handling.
+var exit = os.Exit
+
+// handleSignals waits for a signal on sigCh while the test suite is running. Upon the first
+// signal, the context of the test run is cancelled and the Finish actions are executed using the
+// latest context of the suite before exiting. A second signal exits right away without waiting for
+// the Finish actions to complete. The returned function stops the handling.
+func (e *testEnv) handleSignals(sigCh <-chan os.Signal, cancel context.CancelFunc) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case sig := <-sigCh:
+			klog.Infof("Received signal %s, cancelling the test run and running the finish actions. Send it again to exit immediately", sig)
+			cancel()
+			go func() {
+				select {
+				case sig := <-sigCh:
+					klog.Errorf("Received signal %s again, exiting without completing the finish actions", sig)
+					exit(1)
+				case <-done:
+				}
+			}()
+			e.runFinishActions(detachedContext{e.runContext()}, true)
+			utils.RunExitHooks()
+			exit(1)
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// detachedContext keeps the values of the wrapped context while ignoring its cancellation, so that
+// the Finish actions can still use the values stored by the Setup actions after the run was cancelled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detachedContext) Done() <-chan struct{}             { return nil }
+func (d detachedContext) Err() error                        { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
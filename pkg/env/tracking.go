@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// resourceSnapshot renders a resource tracked by a feature as YAML. It is defined as a variable so that it can be
+// replaced while unit testing the tracking workflow.
+var resourceSnapshot = snapshotResource
+
+// snapshotResource renders the live object as YAML, or as an empty string when it does not exist
+func snapshotResource(ctx context.Context, cfg *envconf.Config, obj k8s.Object) (string, error) {
+	client, err := cfg.NewClient()
+	if err != nil {
+		return "", err
+	}
+	return client.Resources().SnapshotYAML(ctx, obj)
+}
+
+// snapshot is the YAML of a tracked resource, or the error which prevented fetching it
+type snapshot struct {
+	yaml string
+	err  error
+}
+
+// trackedResourcesOf returns the resources tracked by the feature
+func trackedResourcesOf(f types.Feature) []k8s.Object {
+	if tf, ok := f.(types.ResourceTrackingFeature); ok {
+		return tf.TrackedResources()
+	}
+	return nil
+}
+
+// snapshotResources returns the snapshots of the resources tracked by the feature. As the tracking only affects
+// what is reported, a resource which cannot be fetched is logged to t and left out of the reported changes.
+func (e *testEnv) snapshotResources(ctx context.Context, t *testing.T, f types.Feature) []snapshot {
+	objs := trackedResourcesOf(f)
+	if len(objs) == 0 || e.cfg.DryRunMode() {
+		return nil
+	}
+	snapshots := make([]snapshot, len(objs))
+	for i, obj := range objs {
+		snapshots[i].yaml, snapshots[i].err = resourceSnapshot(ctx, e.cfg, obj)
+		if snapshots[i].err != nil {
+			t.Logf("failed to snapshot tracked resource %s: %s", resourceName(obj), snapshots[i].err)
+		}
+	}
+	return snapshots
+}
+
+// diffResources compares the resources tracked by the feature to their snapshots taken before the feature was
+// tested, and reports the changes to t and in the result of the feature
+func (e *testEnv) diffResources(ctx context.Context, t *testing.T, f types.Feature, before []snapshot, featResult *featureResult) {
+	if len(before) == 0 {
+		return
+	}
+	after := e.snapshotResources(ctx, t, f)
+	for i, obj := range trackedResourcesOf(f) {
+		if before[i].err != nil || after[i].err != nil || before[i].yaml == after[i].yaml {
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(before[i].yaml),
+			B:        difflib.SplitLines(after[i].yaml),
+			FromFile: "before",
+			ToFile:   "after",
+			Context:  3,
+		})
+		if err != nil {
+			t.Logf("failed to diff tracked resource %s: %s", resourceName(obj), err)
+			continue
+		}
+		t.Logf("tracked resource %s changed:\n%s", resourceName(obj), diff)
+		featResult.addResourceDiff(ResourceDiff{Resource: resourceName(obj), Diff: diff})
+	}
+}
+
+// resourceName returns the kind, namespace and name of the object, the kind being the name of its Go type when
+// the object has no type metadata
+func resourceName(obj k8s.Object) string {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	}
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", kind, obj.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", kind, obj.GetNamespace(), obj.GetName())
+}
@@ -19,13 +19,15 @@ package envconf
 import (
 	"encoding/hex"
 	"fmt"
-	"math/rand"
+	"os"
 	"regexp"
+	"strconv"
 	"time"
 
 	log "k8s.io/klog/v2"
 
 	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/conf"
 	"sigs.k8s.io/e2e-framework/pkg/flags"
 )
 
@@ -45,16 +47,42 @@ type Config struct {
 	failFast                bool
 	disableGracefulTeardown bool
 	kubeContext             string
+	healthMonitorInterval   time.Duration
+	keepClusterOnFailure    bool
+	clientOpts              []klient.ConfigOption
+	signals                 []os.Signal
+	junitReportPath         string
+	slowThreshold           time.Duration
+	logVerbosity            *int
+	rand                    *seededRand
 }
 
+// KeepClusterOnFailureEnvVar is the environment variable that can be set to a boolean value
+// to control if the clusters created by the test suite should be kept alive when the suite fails.
+const KeepClusterOnFailureEnvVar = "E2E_KEEP_ON_FAILURE"
+
+// LogVerbosityEnvVar is the environment variable that can be set to the klog verbosity level the
+// framework logs with during the test run.
+const LogVerbosityEnvVar = "E2E_LOG_VERBOSITY"
+
 // New creates and initializes an empty environment configuration
 func New() *Config {
-	return &Config{}
+	keep, _ := strconv.ParseBool(os.Getenv(KeepClusterOnFailureEnvVar))
+	c := &Config{keepClusterOnFailure: keep, rand: newConfigRand()}
+	if value := os.Getenv(LogVerbosityEnvVar); value != "" {
+		level, err := strconv.Atoi(value)
+		if err != nil {
+			log.Warningf("ignoring invalid %s value %q: %s", LogVerbosityEnvVar, value, err)
+		} else {
+			c.WithLogVerbosity(level)
+		}
+	}
+	return c
 }
 
 // NewWithKubeConfig creates and initializes an empty environment configuration
 func NewWithKubeConfig(kubeconfig string) *Config {
-	c := &Config{}
+	c := New()
 	return c.WithKubeconfigFile(kubeconfig)
 }
 
@@ -107,6 +135,13 @@ func (c *Config) WithClient(client klient.Client) *Config {
 	return c
 }
 
+// WithClientConfigOptions sets the options, such as klient.WithQPS or klient.WithUserAgent, applied
+// to the rest configuration of the client created by NewClient or Client from the kubeconfig file.
+func (c *Config) WithClientConfigOptions(opts ...klient.ConfigOption) *Config {
+	c.clientOpts = opts
+	return c
+}
+
 // NewClient is a constructor function that returns a previously
 // created klient.Client or create a new one based on configuration
 // previously set. Will return an error if unable to do so.
@@ -115,7 +150,7 @@ func (c *Config) NewClient() (klient.Client, error) {
 		return c.client, nil
 	}
 
-	client, err := klient.NewWithKubeConfigFile(c.kubeconfig)
+	client, err := c.newClient()
 	if err != nil {
 		return nil, fmt.Errorf("envconfig: client failed: %w", err)
 	}
@@ -134,7 +169,7 @@ func (c *Config) Client() klient.Client {
 		return c.client
 	}
 
-	client, err := klient.NewWithKubeConfigFile(c.kubeconfig)
+	client, err := c.newClient()
 	if err != nil {
 		panic(fmt.Errorf("envconfig: client failed: %w", err).Error())
 	}
@@ -142,6 +177,24 @@ func (c *Config) Client() klient.Client {
 	return c.client
 }
 
+// newClient creates a klient.Client from the kubeconfig file, using the context configured using WithKubeContext
+// when set instead of the current context of the file, which makes it possible to target any of the clusters of a
+// kubeconfig file holding several contexts
+func (c *Config) newClient() (klient.Client, error) {
+	if c.kubeContext == "" {
+		return klient.NewWithKubeConfigFile(c.kubeconfig, c.clientOpts...)
+	}
+	kubeconfig := c.kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = conf.ResolveKubeConfigFile()
+	}
+	restConfig, err := conf.NewWithContextName(kubeconfig, c.kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return klient.New(restConfig, c.clientOpts...)
+}
+
 // WithNamespace updates the environment namespace value
 func (c *Config) WithNamespace(ns string) *Config {
 	c.namespace = ns
@@ -151,7 +204,7 @@ func (c *Config) WithNamespace(ns string) *Config {
 // WithRandomNamespace sets the environment's namespace
 // to a random value
 func (c *Config) WithRandomNamespace() *Config {
-	c.namespace = randNS()
+	c.namespace = c.RandomName("testns-", 32)
 	return c
 }
 
@@ -276,7 +329,8 @@ func (c *Config) DisableGracefulTeardown() bool {
 	return c.disableGracefulTeardown
 }
 
-// WithKubeContext is used to set the kubeconfig context
+// WithKubeContext is used to set the kubeconfig context the client is created
+// from, instead of the current context of the kubeconfig file
 func (c *Config) WithKubeContext(kubeContext string) *Config {
 	c.kubeContext = kubeContext
 	return c
@@ -287,22 +341,114 @@ func (c *Config) KubeContext() string {
 	return c.kubeContext
 }
 
-func randNS() string {
-	return RandomName("testns-", 32)
+// WithClusterHealthMonitor enables a background monitor that periodically checks
+// the API server and node readiness while each feature is being tested. If the
+// cluster becomes unhealthy, the running feature is marked as failed with the
+// time and reason of the failure. An interval of 0 disables the monitor.
+func (c *Config) WithClusterHealthMonitor(interval time.Duration) *Config {
+	c.healthMonitorInterval = interval
+	return c
+}
+
+// ClusterHealthMonitorInterval returns the interval at which the cluster health
+// is checked while a feature is running. A value of 0 means the monitor is disabled.
+func (c *Config) ClusterHealthMonitorInterval() time.Duration {
+	return c.healthMonitorInterval
+}
+
+// WithSignalHandling enables the handling of the provided signals, such as os.Interrupt and
+// syscall.SIGTERM, while the test suite is running. On the first signal received, the context of
+// the test run is cancelled and the Finish actions are executed before exiting, so that resources
+// such as clusters are not leaked when a local run is interrupted. A second signal exits immediately.
+func (c *Config) WithSignalHandling(signals ...os.Signal) *Config {
+	c.signals = signals
+	return c
+}
+
+// SignalHandling returns the signals handled while the test suite is running
+func (c *Config) SignalHandling() []os.Signal {
+	return c.signals
+}
+
+// WithJUnitReport configures the path of a JUnit XML report written once the test suite has completed,
+// after the Finish actions have been executed. Each feature is reported as a testsuite and each of its
+// assessments as a testcase, along with their duration and outcome. The failures include the messages
+// logged by the failed steps through t, such as the ones of t.Error or t.Fatal, which are read from the
+// standard output of the tests while it is copied as is.
+func (c *Config) WithJUnitReport(path string) *Config {
+	c.junitReportPath = path
+	return c
+}
+
+// JUnitReportPath returns the path of the JUnit XML report or an empty string if it is not enabled
+func (c *Config) JUnitReportPath() string {
+	return c.junitReportPath
+}
+
+// WithSlowThreshold configures the duration above which an assessment is considered slow. A warning
+// is logged for each assessment taking longer than the threshold and the assessment is flagged as slow
+// in the reports. This helps spotting gradually slowing steps before they turn into timeouts.
+// A threshold of 0 disables the warning.
+func (c *Config) WithSlowThreshold(threshold time.Duration) *Config {
+	c.slowThreshold = threshold
+	return c
+}
+
+// SlowThreshold returns the duration above which an assessment is considered slow
+func (c *Config) SlowThreshold() time.Duration {
+	return c.slowThreshold
+}
+
+// WithKeepClusterOnFailure can be used to keep the clusters created by the test suite alive when
+// any of the tests fail, so that the live cluster can be inspected after the fact. The default value
+// is read from the E2E_KEEP_ON_FAILURE environment variable. Clusters are still destroyed when the
+// test suite succeeds.
+func (c *Config) WithKeepClusterOnFailure(keep bool) *Config {
+	c.keepClusterOnFailure = keep
+	return c
+}
+
+// KeepClusterOnFailure indicates if the clusters created by the test suite should be kept alive
+// when the test suite fails
+func (c *Config) KeepClusterOnFailure() bool {
+	return c.keepClusterOnFailure
+}
+
+// WithLogVerbosity configures the klog verbosity level the framework, including the cluster providers and
+// the wait helpers, logs with during the test run, which is handy to get detailed logs for a single
+// investigation. The default value is read from the E2E_LOG_VERBOSITY environment variable. As klog has
+// a single verbosity level for the whole process, the level is applied globally when the environment
+// starts running and overrides the value of the -v flag, which is used as is when no level is configured.
+func (c *Config) WithLogVerbosity(level int) *Config {
+	c.logVerbosity = &level
+	return c
+}
+
+// LogVerbosity returns the klog verbosity level configured for the test run, if any
+func (c *Config) LogVerbosity() (int, bool) {
+	if c.logVerbosity == nil {
+		return 0, false
+	}
+	return *c.logVerbosity, true
 }
 
 // RandomName generates a random name of n length with the provided
 // prefix. If prefix is omitted, the then entire name is random char.
+// The names are generated using a process wide random source seeded
+// with the seed returned by ProcessRandSeed.
 func RandomName(prefix string, n int) string {
+	return randomName(nameRand, prefix, n)
+}
+
+func randomName(r *seededRand, prefix string, n int) string {
 	if n == 0 {
 		n = 32
 	}
 	if len(prefix) >= n {
 		return prefix
 	}
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	p := make([]byte, n)
-	r.Read(p)
+	r.read(p)
 	if prefix == "" {
 		return hex.EncodeToString(p)[:n]
 	}
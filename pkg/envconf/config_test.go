@@ -19,6 +19,8 @@ package envconf
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -87,6 +89,67 @@ func TestConfig_New_WithIgnorePanicRecovery(t *testing.T) {
 	}
 }
 
+func TestConfig_New_WithKeepClusterOnFailure(t *testing.T) {
+	t.Setenv(KeepClusterOnFailureEnvVar, "true")
+	if !New().KeepClusterOnFailure() {
+		t.Errorf("expected keep cluster on failure to be enabled when %s is set", KeepClusterOnFailureEnvVar)
+	}
+	if New().WithKeepClusterOnFailure(false).KeepClusterOnFailure() {
+		t.Error("expected keep cluster on failure to be disabled when explicitly configured")
+	}
+}
+
+func TestConfig_New_WithLogVerbosity(t *testing.T) {
+	if _, ok := New().LogVerbosity(); ok {
+		t.Error("expected no log verbosity to be configured by default")
+	}
+	t.Setenv(LogVerbosityEnvVar, "6")
+	if level, ok := New().LogVerbosity(); !ok || level != 6 {
+		t.Errorf("expected log verbosity 6 when %s is set, got %d", LogVerbosityEnvVar, level)
+	}
+	if level, _ := New().WithLogVerbosity(2).LogVerbosity(); level != 2 {
+		t.Errorf("expected log verbosity 2 when explicitly configured, got %d", level)
+	}
+}
+
+func TestConfig_WithRandSeed(t *testing.T) {
+	cfg := New().WithRandSeed(42)
+	if cfg.RandSeed() != 42 {
+		t.Errorf("unexpected seed %d", cfg.RandSeed())
+	}
+	first := []string{cfg.RandomName("a", 16), cfg.RandomName("", 16), cfg.WithRandomNamespace().Namespace()}
+
+	other := New().WithRandSeed(42)
+	second := []string{other.RandomName("a", 16), other.RandomName("", 16), other.WithRandomNamespace().Namespace()}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same names to be generated using the same seed, got %v and %v", first, second)
+	}
+
+	New().WithRandSeed(42)
+	if name := cfg.RandomName("a", 16); name == first[0] {
+		t.Errorf("expected seeding another configuration not to reset the random source, got %s again", name)
+	}
+}
+
+func TestProcessRandSeed(t *testing.T) {
+	oldNameRand, oldConfigSeeds := nameRand, configSeeds
+	defer func() { nameRand, configSeeds = oldNameRand, oldConfigSeeds }()
+
+	// simulates a run of the process using the seed logged by the previous run
+	run := func() []string {
+		nameRand, configSeeds = newSeededRand(ProcessRandSeed()), newSeededRand(ProcessRandSeed()+1)
+		first, second := New().WithRandomNamespace(), New().WithRandomNamespace()
+		return []string{RandomName("cluster", 16), first.Namespace(), second.Namespace()}
+	}
+	names := run()
+	if names[1] == names[2] {
+		t.Errorf("expected distinct configurations to generate distinct names, got %s twice", names[1])
+	}
+	if again := run(); !reflect.DeepEqual(names, again) {
+		t.Errorf("expected the names to be reproduced using the seed of the process, got %v and %v", names, again)
+	}
+}
+
 func TestRandomName(t *testing.T) {
 	t.Run("no prefix yields random name without dash", func(t *testing.T) {
 		out := RandomName("", 16)
@@ -102,3 +165,54 @@ func TestRandomName(t *testing.T) {
 		}
 	})
 }
+
+func TestConfig_WithKubeContext(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	data := `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+- name: staging
+  cluster:
+    server: https://staging.example.com:6443
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+- name: staging
+  context:
+    cluster: staging
+    user: admin
+current-context: dev
+users:
+- name: admin
+  user:
+    token: fake
+`
+	if err := os.WriteFile(kubeconfig, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := New().WithKubeconfigFile(kubeconfig).NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := client.RESTConfig().Host; host != "https://dev.example.com:6443" {
+		t.Errorf("expected the current context to be used, got %s", host)
+	}
+
+	client, err = New().WithKubeconfigFile(kubeconfig).WithKubeContext("staging").NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := client.RESTConfig().Host; host != "https://staging.example.com:6443" {
+		t.Errorf("expected the configured context to be used, got %s", host)
+	}
+
+	if _, err := New().WithKubeconfigFile(kubeconfig).WithKubeContext("missing").NewClient(); err == nil {
+		t.Error("expected an error for a missing context")
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envconf
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "k8s.io/klog/v2"
+)
+
+// RandSeedEnvVar is the environment variable that can be set to the seed of the process, from which the random
+// sources used to generate the random names, such as the ones of the random namespaces, are derived in order to
+// reproduce the names of a previous run. The seed of each run is logged when the environment starts running.
+const RandSeedEnvVar = "E2E_SEED"
+
+// seededRand is a random source safe for concurrent use that remembers its seed
+type seededRand struct {
+	mu   sync.Mutex
+	seed int64
+	rand *rand.Rand
+}
+
+func newSeededRand(seed int64) *seededRand {
+	return &seededRand{seed: seed, rand: rand.New(rand.NewSource(seed))}
+}
+
+// read fills p with random bytes
+func (r *seededRand) read(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rand.Read(p)
+}
+
+// int63 returns a non-negative random int64
+func (r *seededRand) int63() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Int63()
+}
+
+var (
+	// processRandSeed is the seed all the random sources of the process are derived from
+	processRandSeed = defaultRandSeed()
+	// nameRand is the process wide random source the names generated by the RandomName function are generated with
+	nameRand = newSeededRand(processRandSeed)
+	// configSeeds is the random source the default seeds of the configurations are drawn from, in the order the
+	// configurations are created, so that distinct configurations generate distinct names
+	configSeeds = newSeededRand(processRandSeed + 1)
+)
+
+// ProcessRandSeed returns the seed of the process, from which the random source of the RandomName function and the
+// default random sources of the configurations are derived. Setting E2E_SEED to it reproduces the random names
+// of a run, provided they are generated in the same order.
+func ProcessRandSeed() int64 {
+	return processRandSeed
+}
+
+// newConfigRand returns a random source for a configuration seeded using the next seed derived from the seed of
+// the process
+func newConfigRand() *seededRand {
+	return newSeededRand(configSeeds.int63())
+}
+
+// defaultRandSeed returns the seed read from the E2E_SEED environment variable, or based on the current time if
+// it is not set
+func defaultRandSeed() int64 {
+	seed := time.Now().UnixNano()
+	if value := os.Getenv(RandSeedEnvVar); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Warningf("ignoring invalid %s value %q: %s", RandSeedEnvVar, value, err)
+		} else {
+			seed = parsed
+		}
+	}
+	return seed
+}
+
+// randSource returns the random source of the configuration, creating it using the default seed if needed
+func (c *Config) randSource() *seededRand {
+	if c.rand == nil {
+		c.rand = newConfigRand()
+	}
+	return c.rand
+}
+
+// WithRandSeed seeds the random source of the configuration, used by its RandomName and WithRandomNamespace
+// methods, so that a run generates the same names as a previous run using the same seed, provided the names are
+// generated in the same order. The default seed is derived from the seed of the process returned by ProcessRandSeed,
+// which is read from the E2E_SEED environment variable, or is based on the current time if it is not set. Seeding
+// the configuration does not affect the other configurations nor the RandomName function, whose process wide
+// source can only be seeded using E2E_SEED.
+func (c *Config) WithRandSeed(seed int64) *Config {
+	c.rand = newSeededRand(seed)
+	return c
+}
+
+// RandSeed returns the seed of the random source used by the configuration to generate the random names
+func (c *Config) RandSeed() int64 {
+	return c.randSource().seed
+}
+
+// RandomName generates a random name of n length with the provided prefix, like the RandomName function, using
+// the random source of the configuration seeded with WithRandSeed.
+func (c *Config) RandomName(prefix string, n int) string {
+	return randomName(c.randSource(), prefix, n)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/third_party/helm"
+)
+
+type helmReleaseContextKey string
+
+const defaultHelmInstallTimeout = 5 * time.Minute
+
+// HelmInstallOpts configures the Helm chart installed by InstallHelmChart
+type HelmInstallOpts struct {
+	// ReleaseName is the name of the Helm release
+	ReleaseName string
+	// Namespace is the namespace the chart is installed into. It is created if it does not exist.
+	Namespace string
+	// Chart is either the path of a local chart directory or archive, or the reference of a chart
+	// of a repository such as "jetstack/cert-manager"
+	Chart string
+	// Version is the version of the chart to install. The latest version is installed if not set.
+	Version string
+	// RepoName and RepoURL configure the chart repository that is added and updated before the
+	// chart is installed. They are not needed when installing a local chart.
+	RepoName string
+	RepoURL  string
+	// Args are additional arguments passed to helm install, such as "--set" flags
+	Args []string
+	// Timeout is the time to wait for the workloads of the release to be Available. Defaults to 5 minutes.
+	Timeout time.Duration
+}
+
+// InstallHelmChart returns an EnvFunc that installs a Helm chart, either from a repository or from a local path,
+// and waits for the workloads of the release to be Available using the helm --wait flag. The options are saved
+// in the context, using the release name, so that the release can be uninstalled using UninstallHelmChart.
+//
+// NOTE: this requires the helm binary to be available on the PATH.
+func InstallHelmChart(opts HelmInstallOpts) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if opts.ReleaseName == "" || opts.Chart == "" {
+			return ctx, fmt.Errorf("install helm chart func: release name and chart are required")
+		}
+		manager := helm.New(cfg.KubeconfigFile())
+		if opts.RepoURL != "" {
+			if err := manager.RunRepo(helm.WithArgs("add", opts.RepoName, opts.RepoURL, "--force-update")); err != nil {
+				return ctx, fmt.Errorf("install helm chart func: add repo %s: %w", opts.RepoName, err)
+			}
+			if err := manager.RunRepo(helm.WithArgs("update", opts.RepoName)); err != nil {
+				return ctx, fmt.Errorf("install helm chart func: update repo %s: %w", opts.RepoName, err)
+			}
+		}
+
+		timeout := opts.Timeout
+		if timeout == 0 {
+			timeout = defaultHelmInstallTimeout
+		}
+		installOpts := []helm.Option{
+			helm.WithName(opts.ReleaseName),
+			helm.WithChart(opts.Chart),
+			helm.WithArgs("--create-namespace"),
+			helm.WithArgs(opts.Args...),
+			helm.WithWait(),
+			helm.WithTimeout(timeout.String()),
+		}
+		if opts.Namespace != "" {
+			installOpts = append(installOpts, helm.WithNamespace(opts.Namespace))
+		}
+		if opts.Version != "" {
+			installOpts = append(installOpts, helm.WithVersion(opts.Version))
+		}
+		if err := manager.RunInstall(installOpts...); err != nil {
+			return ctx, fmt.Errorf("install helm chart func: install release %s: %w", opts.ReleaseName, err)
+		}
+
+		return context.WithValue(ctx, helmReleaseContextKey(opts.ReleaseName), opts), nil
+	}
+}
+
+// UninstallHelmChart returns an EnvFunc that retrieves a Helm release previously installed using InstallHelmChart
+// from the context, using its release name, and uninstalls it.
+func UninstallHelmChart(releaseName string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		opts, ok := ctx.Value(helmReleaseContextKey(releaseName)).(HelmInstallOpts)
+		if !ok {
+			return ctx, fmt.Errorf("uninstall helm chart func: release %s not found in context", releaseName)
+		}
+		uninstallOpts := []helm.Option{helm.WithReleaseName(opts.ReleaseName), helm.WithWait()}
+		if opts.Namespace != "" {
+			uninstallOpts = append(uninstallOpts, helm.WithNamespace(opts.Namespace))
+		}
+		if err := helm.New(cfg.KubeconfigFile()).RunUninstall(uninstallOpts...); err != nil {
+			return ctx, fmt.Errorf("uninstall helm chart func: uninstall release %s: %w", releaseName, err)
+		}
+		return ctx, nil
+	}
+}
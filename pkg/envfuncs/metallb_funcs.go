@@ -0,0 +1,278 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+const (
+	metalLBManifestURL  = "https://raw.githubusercontent.com/metallb/metallb/v0.13.12/config/manifests/metallb-native.yaml"
+	metalLBNamespace    = "metallb-system"
+	metalLBPoolName     = "e2e-framework"
+	metalLBReadyTimeout = 5 * time.Minute
+)
+
+type metalLBContextKey struct{}
+
+// MetalLBOption configures InstallMetalLB
+type MetalLBOption func(*metalLBOptions)
+
+type metalLBOptions struct {
+	runtime string
+	cluster string
+}
+
+// WithMetalLBRuntime sets the container runtime, docker or podman, whose network of kind is inspected to pick the
+// address range when none is provided. It is docker by default.
+func WithMetalLBRuntime(runtime string) MetalLBOption {
+	return func(o *metalLBOptions) {
+		o.runtime = runtime
+	}
+}
+
+// WithMetalLBCluster uses the container runtime of the cluster saved in the context under name, such as the one
+// configured using kind.WithRuntime, to pick the address range when none is provided. This has no effect when the
+// cluster provider does not report its container runtime.
+func WithMetalLBCluster(name string) MetalLBOption {
+	return func(o *metalLBOptions) {
+		o.cluster = name
+	}
+}
+
+// containerRuntimeProvider is implemented by the cluster providers running the cluster nodes as containers
+type containerRuntimeProvider interface {
+	ContainerRuntime() string
+}
+
+// InstallMetalLB returns an EnvFunc that installs MetalLB in layer 2 mode so that the Services of type LoadBalancer
+// get an external address on kind clusters, which have no cloud provider to provision load balancers. The addresses
+// are allocated from addressRange, such as "172.18.255.200-172.18.255.250". When addressRange is empty, a range is
+// picked at the end of the subnet of the network of kind of the container runtime, which is reachable from the host
+// on Linux. The container runtime is configured using WithMetalLBRuntime or WithMetalLBCluster.
+//
+// The EnvFunc waits for the MetalLB controller and speakers to be ready, the installed objects are saved in the
+// context so that they can be removed using UninstallMetalLB.
+//
+// NOTE: the MetalLB manifest is downloaded from GitHub and detecting the address range requires the CLI of the
+// container runtime.
+func InstallMetalLB(addressRange string, opts ...MetalLBOption) env.Func {
+	options := &metalLBOptions{runtime: "docker"}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if addressRange == "" {
+			runtime := options.runtime
+			if options.cluster != "" {
+				cluster, ok := GetClusterFromContext(ctx, options.cluster)
+				if !ok {
+					return ctx, fmt.Errorf("install metallb func: cluster %s not found in context", options.cluster)
+				}
+				if provider, ok := cluster.(containerRuntimeProvider); ok {
+					runtime = provider.ContainerRuntime()
+				}
+			}
+			subnet, err := kindNetworkSubnet(ctx, utils.ExecRunner{}, runtime)
+			if err != nil {
+				return ctx, fmt.Errorf("install metallb func: %w", err)
+			}
+			if addressRange, err = addressRangeOf(subnet); err != nil {
+				return ctx, fmt.Errorf("install metallb func: %w", err)
+			}
+		}
+
+		r, err := resources.New(cfg.Client().RESTConfig())
+		if err != nil {
+			return ctx, err
+		}
+		objs, err := applyManifestURL(ctx, r, metalLBManifestURL)
+		if err != nil {
+			return ctx, fmt.Errorf("install metallb func: %w", err)
+		}
+		ctx = context.WithValue(ctx, metalLBContextKey{}, objs)
+
+		cond := conditions.New(r)
+		speaker := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "speaker", Namespace: metalLBNamespace}}
+		err = wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+			if done, err := cond.DeploymentAvailable("controller", metalLBNamespace)(ctx); err != nil || !done {
+				return false, nil
+			}
+			if done, err := cond.DaemonSetReady(speaker)(ctx); err != nil || !done {
+				return false, nil
+			}
+			return true, nil
+		}, wait.WithImmediate(), wait.WithTimeout(metalLBReadyTimeout))
+		if err != nil {
+			return ctx, fmt.Errorf("install metallb func: waiting for metallb to be ready: %w", err)
+		}
+
+		// the validating webhook of MetalLB can take a little longer than the controller to serve requests
+		var createErr error
+		err = wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+			for _, obj := range metalLBPoolObjects(addressRange) {
+				if createErr = decoder.CreateOrUpdateHandler(r)(ctx, obj); createErr != nil {
+					return false, nil
+				}
+			}
+			return true, nil
+		}, wait.WithImmediate(), wait.WithInterval(2*time.Second), wait.WithTimeout(2*time.Minute))
+		if err != nil {
+			return ctx, fmt.Errorf("install metallb func: configure address pool %s: %w: %v", addressRange, err, createErr)
+		}
+		return ctx, nil
+	}
+}
+
+// UninstallMetalLB returns an EnvFunc that removes the address pool and the objects of MetalLB installed using
+// InstallMetalLB, as saved in the context.
+func UninstallMetalLB() env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		objs, ok := ctx.Value(metalLBContextKey{}).([]k8s.Object)
+		if !ok {
+			return ctx, fmt.Errorf("uninstall metallb func: metallb not found in context")
+		}
+		r, err := resources.New(cfg.Client().RESTConfig())
+		if err != nil {
+			return ctx, err
+		}
+		remove := decoder.DeleteIgnoreNotFound(r)
+		for _, obj := range metalLBPoolObjects("") {
+			if err := remove(ctx, obj); err != nil {
+				return ctx, fmt.Errorf("uninstall metallb func: %w", err)
+			}
+		}
+		for i := len(objs) - 1; i >= 0; i-- {
+			if err := remove(ctx, objs[i]); err != nil {
+				return ctx, fmt.Errorf("uninstall metallb func: %w", err)
+			}
+		}
+		return ctx, nil
+	}
+}
+
+// metalLBPoolObjects returns the IPAddressPool allocating the addresses of addressRange and the L2Advertisement
+// announcing them
+func metalLBPoolObjects(addressRange string) []k8s.Object {
+	pool := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "metallb.io/v1beta1",
+		"kind":       "IPAddressPool",
+		"metadata":   map[string]interface{}{"name": metalLBPoolName, "namespace": metalLBNamespace},
+		"spec":       map[string]interface{}{"addresses": []interface{}{addressRange}},
+	}}
+	advertisement := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "metallb.io/v1beta1",
+		"kind":       "L2Advertisement",
+		"metadata":   map[string]interface{}{"name": metalLBPoolName, "namespace": metalLBNamespace},
+		"spec":       map[string]interface{}{"ipAddressPools": []interface{}{metalLBPoolName}},
+	}}
+	return []k8s.Object{pool, advertisement}
+}
+
+// applyManifestURL creates or updates the objects of the manifest downloaded from url
+func applyManifestURL(ctx context.Context, r *resources.Resources, url string) ([]k8s.Object, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+
+	var objs []k8s.Object
+	apply := decoder.CreateOrUpdateHandler(r)
+	err = decoder.DecodeEach(ctx, resp.Body, func(ctx context.Context, obj k8s.Object) error {
+		if err := apply(ctx, obj); err != nil {
+			return err
+		}
+		objs = append(objs, obj)
+		return nil
+	})
+	return objs, err
+}
+
+// kindNetworkSubnet returns the IPv4 subnet of the network of the container runtime the kind nodes are attached to,
+// which is the network named by the KIND_EXPERIMENTAL_<RUNTIME>_NETWORK environment variable or kind by default
+func kindNetworkSubnet(ctx context.Context, runner utils.Runner, runtime string) (*net.IPNet, error) {
+	network := os.Getenv(fmt.Sprintf("KIND_EXPERIMENTAL_%s_NETWORK", strings.ToUpper(runtime)))
+	if network == "" {
+		network = "kind"
+	}
+	// docker reports the subnets as the IPAM configs of the network, podman as its subnets
+	format := "{{json .IPAM.Config}}"
+	if runtime == "podman" {
+		format = "{{json .Subnets}}"
+	}
+	res, err := runner.Run(ctx, runtime, "network", "inspect", network, "--format", format)
+	if err != nil {
+		return nil, fmt.Errorf("inspect %s network %s: %s: %w", runtime, network, res.Output(), err)
+	}
+	// the keys are matched case insensitively, docker names the key Subnet and podman subnet
+	var configs []struct {
+		Subnet string `json:"Subnet"`
+	}
+	if err := json.Unmarshal([]byte(res.Stdout), &configs); err != nil {
+		return nil, fmt.Errorf("inspect %s network %s: %w", runtime, network, err)
+	}
+	for _, config := range configs {
+		_, subnet, err := net.ParseCIDR(config.Subnet)
+		if err == nil && subnet.IP.To4() != nil {
+			return subnet, nil
+		}
+	}
+	return nil, fmt.Errorf("%s network %s has no IPv4 subnet", runtime, network)
+}
+
+// addressRangeOf returns a range of 51 addresses close to the end of the subnet, away from the addresses docker
+// assigns to the containers from the start of the subnet
+func addressRangeOf(subnet *net.IPNet) (string, error) {
+	ones, bits := subnet.Mask.Size()
+	if bits != 32 || ones > 24 {
+		return "", fmt.Errorf("subnet %s is too small to allocate load balancer addresses", subnet)
+	}
+	last := binary.BigEndian.Uint32(subnet.IP.To4()) | ^binary.BigEndian.Uint32(subnet.Mask)
+	start, end := make(net.IP, 4), make(net.IP, 4)
+	binary.BigEndian.PutUint32(start, last-55)
+	binary.BigEndian.PutUint32(end, last-5)
+	return fmt.Sprintf("%s-%s", start, end), nil
+}
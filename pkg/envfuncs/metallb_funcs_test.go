@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+// fakeRunner returns result to the commands it runs and records them
+type fakeRunner struct {
+	result   utils.Result
+	err      error
+	commands []string
+}
+
+func (f *fakeRunner) Run(ctx context.Context, path string, args ...string) (utils.Result, error) {
+	f.commands = append(f.commands, strings.Join(append([]string{path}, args...), " "))
+	return f.result, f.err
+}
+
+func TestKindNetworkSubnet(t *testing.T) {
+	tests := []struct {
+		name    string
+		runtime string
+		network string
+		stdout  string
+		command string
+		subnet  string
+	}{
+		{
+			name:    "docker",
+			runtime: "docker",
+			stdout:  `[{"Subnet":"fc00:f853:ccd:e793::/64"},{"Subnet":"172.18.0.0/16","Gateway":"172.18.0.1"}]`,
+			command: "docker network inspect kind --format {{json .IPAM.Config}}",
+			subnet:  "172.18.0.0/16",
+		},
+		{
+			name:    "podman on a custom network",
+			runtime: "podman",
+			network: "e2e",
+			stdout:  `[{"subnet":"10.89.0.0/24","gateway":"10.89.0.1"}]`,
+			command: "podman network inspect e2e --format {{json .Subnets}}",
+			subnet:  "10.89.0.0/24",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.network != "" {
+				t.Setenv("KIND_EXPERIMENTAL_"+strings.ToUpper(tc.runtime)+"_NETWORK", tc.network)
+			}
+			runner := &fakeRunner{result: utils.Result{Stdout: tc.stdout}}
+			subnet, err := kindNetworkSubnet(context.TODO(), runner, tc.runtime)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if subnet.String() != tc.subnet {
+				t.Errorf("expected subnet %s, got %s", tc.subnet, subnet)
+			}
+			if len(runner.commands) != 1 || runner.commands[0] != tc.command {
+				t.Errorf("expected the command %q, got %q", tc.command, runner.commands)
+			}
+		})
+	}
+
+	runner := &fakeRunner{result: utils.Result{Stdout: `[{"Subnet":"fc00:f853:ccd:e793::/64"}]`}}
+	if _, err := kindNetworkSubnet(context.TODO(), runner, "docker"); err == nil {
+		t.Error("expected an error for a network without IPv4 subnet")
+	}
+	runner = &fakeRunner{result: utils.Result{Stderr: "network kind not found"}, err: errors.New("exit status 1")}
+	if _, err := kindNetworkSubnet(context.TODO(), runner, "docker"); err == nil || !strings.Contains(err.Error(), "network kind not found") {
+		t.Errorf("expected the output of the failed command in the error, got: %v", err)
+	}
+}
+
+func TestAddressRangeOf(t *testing.T) {
+	tests := map[string]string{
+		"172.18.0.0/16": "172.18.255.200-172.18.255.250",
+		"10.89.0.0/24":  "10.89.0.200-10.89.0.250",
+		"10.89.0.0/25":  "",
+	}
+	for cidr, expected := range tests {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addressRange, err := addressRangeOf(subnet)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("expected subnet %s to be too small, got range %s", cidr, addressRange)
+			}
+			continue
+		}
+		if err != nil || addressRange != expected {
+			t.Errorf("expected range %s for subnet %s, got %s, %v", expected, cidr, addressRange, err)
+		}
+	}
+}
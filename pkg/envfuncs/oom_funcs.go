@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+type featureStartContextKey struct{}
+
+// MarkFeatureStart returns a FeatureFunc, to register using env.BeforeEachFeature, that records in the context
+// the time each feature starts at, so that FailOnOOMKills only reports the containers killed during the feature.
+func MarkFeatureStart() env.FeatureFunc {
+	return func(ctx context.Context, _ *envconf.Config, _ *testing.T, _ features.Feature) (context.Context, error) {
+		return context.WithValue(ctx, featureStartContextKey{}, time.Now()), nil
+	}
+}
+
+// FailOnOOMKills returns a FeatureFunc, to register using env.AfterEachFeature, that fails the feature when
+// containers of the namespaces were killed for running out of memory, which catches the memory regressions the
+// assessments would otherwise miss. The namespace of the config is checked when no namespace is provided. Only the
+// containers killed since the start of the feature are reported when MarkFeatureStart is registered using
+// env.BeforeEachFeature, all the kills still recorded by the pods are reported otherwise.
+func FailOnOOMKills(namespaces ...string) env.FeatureFunc {
+	return func(ctx context.Context, cfg *envconf.Config, _ *testing.T, _ features.Feature) (context.Context, error) {
+		r, err := resources.New(cfg.Client().RESTConfig())
+		if err != nil {
+			return ctx, fmt.Errorf("fail on oom kills func: %w", err)
+		}
+		checked := namespaces
+		if len(checked) == 0 {
+			checked = []string{cfg.Namespace()}
+		}
+		since, _ := ctx.Value(featureStartContextKey{}).(time.Time)
+		var kills []string
+		for _, namespace := range checked {
+			found, err := r.OOMKills(ctx, namespace, since)
+			if err != nil {
+				return ctx, fmt.Errorf("fail on oom kills func: %w", err)
+			}
+			kills = append(kills, found...)
+		}
+		if len(kills) > 0 {
+			return ctx, fmt.Errorf("fail on oom kills func: containers were OOMKilled: %s", strings.Join(kills, ", "))
+		}
+		return ctx, nil
+	}
+}
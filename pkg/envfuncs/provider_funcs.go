@@ -20,6 +20,9 @@ import (
 	"context"
 	"fmt"
 
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/support"
@@ -97,8 +100,11 @@ func CreateClusterWithConfig(p support.E2EClusterProvider, clusterName, configFi
 
 // DestroyCluster returns an EnvFunc that
 // retrieves a previously saved e2e provider Cluster in the context (using the name), then deletes it.
+// If the env config has been configured to keep the cluster on failure and the test suite failed, the
+// cluster is left running and the path to its kubeconfig is logged instead.
 //
-// NOTE: this should be used in a Environment.Finish step.
+// NOTE: this should be used in a env.FinishLastEnvironment.FinishLast step, so that the cluster is only destroyed once the
+// Environment.Finish steps, which may need the API server, have been executed.
 func DestroyCluster(name string) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
 		clusterVal := ctx.Value(clusterNameContextKey(name))
@@ -111,12 +117,25 @@ func DestroyCluster(name string) env.Func {
 			return ctx, fmt.Errorf("destroy e2e provider cluster func: unexpected type for cluster value")
 		}
 
-		if err := cluster.Destroy(ctx); err != nil {
-			return ctx, fmt.Errorf("destroy e2e provider cluster: %w", err)
+		return ctx, destroyOrKeepCluster(ctx, name, cluster, cfg.KeepClusterOnFailure() && env.SuiteFailed(ctx))
+	}
+}
+
+// destroyOrKeepCluster destroys the cluster, or keeps it alive along with its local state, such as its kubeconfig
+// file, when keep is true
+func destroyOrKeepCluster(ctx context.Context, name string, cluster support.E2EClusterProvider, keep bool) error {
+	if keep {
+		if keeper, ok := cluster.(support.E2EClusterProviderWithKeep); ok {
+			keeper.Keep()
 		}
+		klog.Infof("Test suite failed, keeping cluster %s alive for inspection using kubeconfig %s", name, cluster.GetKubeconfig())
+		return nil
+	}
 
-		return ctx, nil
+	if err := cluster.Destroy(ctx); err != nil {
+		return fmt.Errorf("destroy e2e provider cluster: %w", err)
 	}
+	return nil
 }
 
 // LoadImageToCluster returns an EnvFunc that
@@ -187,3 +206,47 @@ func ExportClusterLogs(name, dest string) env.Func {
 		return ctx, nil
 	}
 }
+
+// DumpClusterStateOnFailure returns an EnvFunc that writes the state of the cluster, including the resources,
+// pod logs and events of the provided namespaces along with the nodes, to the dir directory when the test suite
+// has failed, using resources.DumpClusterState. All the namespaces are dumped if none is provided. Together
+// with ExportClusterLogs, this produces a self-contained post-mortem bundle that can be stored as a CI artifact.
+//
+// NOTE: this should be used in a Environment.Finish step, before the cluster is destroyed.
+func DumpClusterStateOnFailure(dir string, namespaces ...string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if !env.SuiteFailed(ctx) {
+			return ctx, nil
+		}
+		r, err := resources.New(cfg.Client().RESTConfig())
+		if err != nil {
+			return ctx, err
+		}
+		if err := r.DumpClusterState(ctx, dir, namespaces...); err != nil {
+			return ctx, fmt.Errorf("dump cluster state: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// GetClusterCapabilities reports the capabilities of a previously saved e2e provider Cluster in the context (using
+// the name). The capabilities are the ones reported by the provider when it supports it, otherwise they are
+// discovered using support.DiscoverCapabilities.
+func GetClusterCapabilities(ctx context.Context, name string) (support.Capabilities, error) {
+	cluster, ok := GetClusterFromContext(ctx, name)
+	if !ok {
+		return support.Capabilities{}, fmt.Errorf("cluster capabilities: context cluster is nil")
+	}
+	if provider, ok := cluster.(support.E2EClusterProviderWithCapabilities); ok {
+		return provider.Capabilities(ctx)
+	}
+	cfg := cluster.KubernetesRestConfig()
+	if cfg == nil {
+		return support.Capabilities{}, fmt.Errorf("cluster capabilities: cluster %s has not been created", name)
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return support.Capabilities{}, fmt.Errorf("cluster capabilities: %w", err)
+	}
+	return support.DiscoverCapabilities(ctx, clientset)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/support/kind"
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+// kindRunner plays a kind cluster named test that shows up once created
+type kindRunner struct {
+	created bool
+}
+
+func (k *kindRunner) Run(_ context.Context, path string, args ...string) (utils.Result, error) {
+	switch strings.Join(append([]string{path}, args...), " ") {
+	case "kind create cluster --name test":
+		k.created = true
+	case "kind get clusters":
+		if k.created {
+			return utils.Result{Stdout: "test\n"}, nil
+		}
+	case "kind get kubeconfig --name test":
+		return utils.Result{Stdout: `apiVersion: v1
+kind: Config
+clusters:
+- name: kind-test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: kind-test
+  context:
+    cluster: kind-test
+    user: kind-test
+current-context: kind-test
+users:
+- name: kind-test
+  user:
+    token: fake
+`}, nil
+	}
+	return utils.Result{}, nil
+}
+
+func TestDestroyOrKeepCluster_ExitHooks(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	create := func() *kind.Cluster {
+		cluster := kind.NewCluster("test")
+		cluster.WithOpts(kind.WithNoLookup(), kind.WithRunner(&kindRunner{}))
+		if _, err := cluster.Create(context.TODO()); err != nil {
+			t.Fatalf("unexpected error creating cluster: %s", err)
+		}
+		return cluster
+	}
+	kept, destroyed := create(), create()
+	keptKubeconfig, destroyedKubeconfig := kept.GetKubeconfig(), destroyed.GetKubeconfig()
+
+	if err := destroyOrKeepCluster(context.TODO(), "kept", kept, true); err != nil {
+		t.Fatalf("unexpected error keeping cluster: %s", err)
+	}
+	if err := destroyOrKeepCluster(context.TODO(), "destroyed", destroyed, false); err != nil {
+		t.Fatalf("unexpected error destroying cluster: %s", err)
+	}
+	utils.RunExitHooks()
+
+	if _, err := os.Stat(keptKubeconfig); err != nil {
+		t.Errorf("expected the kubeconfig of the kept cluster to outlive the exit hooks: %v", err)
+	}
+	if _, err := os.Stat(destroyedKubeconfig); !os.IsNotExist(err) {
+		t.Errorf("expected the kubeconfig of the destroyed cluster to be removed, got: %v", err)
+	}
+}
@@ -18,9 +18,26 @@ package envfuncs
 
 import (
 	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
 
 	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 )
@@ -49,3 +66,171 @@ func TeardownCRDs(crdPath, pattern string) env.Func {
 		return ctx, decoder.DeleteWithManifestDir(ctx, r, crdPath, pattern, []resources.DeleteOption{})
 	}
 }
+
+// ApplyAndWait is provided as a helper env.Func handler that applies the manifests of the manifestDir directory
+// matching the pattern, creating the objects or updating them if they already exist, and then waits for the
+// condition to be met using the options of wait.For. This captures the ubiquitous apply then wait sequence,
+// such as applying a controller and waiting for its webhook to be serving, in a single step.
+func ApplyAndWait(manifestDir, pattern string, condition apimachinerywait.ConditionWithContextFunc, opts ...wait.Option) env.Func {
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		r, err := resources.New(c.Client().RESTConfig())
+		if err != nil {
+			return ctx, err
+		}
+		if _, err := applyManifestDir(ctx, r, manifestDir, pattern); err != nil {
+			return ctx, err
+		}
+		if err := wait.ForFunc(ctx, condition, opts...); err != nil {
+			return ctx, fmt.Errorf("waiting after applying the manifests of %s: %w", manifestDir, err)
+		}
+		return ctx, nil
+	}
+}
+
+// ApplyAndWaitForDeployments works the same way as ApplyAndWait but waits for each of the Deployments defined
+// by the manifests to be Available instead of waiting for a custom condition.
+func ApplyAndWaitForDeployments(manifestDir, pattern string, opts ...wait.Option) env.Func {
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		r, err := resources.New(c.Client().RESTConfig())
+		if err != nil {
+			return ctx, err
+		}
+		objs, err := applyManifestDir(ctx, r, manifestDir, pattern)
+		if err != nil {
+			return ctx, err
+		}
+		var available []apimachinerywait.ConditionWithContextFunc
+		for _, obj := range objs {
+			if d, ok := obj.(*appsv1.Deployment); ok {
+				available = append(available, conditions.New(r).DeploymentConditionMatch(d.DeepCopy(), appsv1.DeploymentAvailable, corev1.ConditionTrue))
+			}
+		}
+		err = wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+			for _, cond := range available {
+				if done, err := cond(ctx); err != nil || !done {
+					return false, err
+				}
+			}
+			return true, nil
+		}, opts...)
+		if err != nil {
+			return ctx, fmt.Errorf("waiting for the deployments of %s to be available: %w", manifestDir, err)
+		}
+		return ctx, nil
+	}
+}
+
+// InstallCRDsFromFS is provided as a helper env.Func handler that applies the CustomResourceDefinitions of the dir
+// directory of fsys and waits for them to be Established. This supports the config/crd/bases directory of the
+// kubebuilder projects directly, either embedded in the test binary using embed.FS or read using os.DirFS, so that
+// the CRDs of the operator under test do not have to be copied.
+func InstallCRDsFromFS(fsys fs.FS, dir string) env.Func {
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		r, err := resources.New(c.Client().RESTConfig())
+		if err != nil {
+			return ctx, err
+		}
+		crds, err := fs.Sub(fsys, dir)
+		if err != nil {
+			return ctx, err
+		}
+		objs, err := applyManifestFS(ctx, r, crds, "*.yaml")
+		if err != nil {
+			return ctx, fmt.Errorf("install crds of %s: %w", dir, err)
+		}
+		for _, obj := range objs {
+			established := conditions.New(r).HasConditionOfType(obj, "Established", metav1.ConditionTrue)
+			if err := wait.ForFunc(ctx, established, wait.WithImmediate(), wait.WithInterval(time.Second), wait.WithTimeout(time.Minute)); err != nil {
+				return ctx, fmt.Errorf("waiting for CustomResourceDefinition %s to be established: %w", obj.GetName(), err)
+			}
+		}
+		return ctx, nil
+	}
+}
+
+// UninstallCRDsFromFS is provided as a handler function that can be hooked into your test's teardown sequence to
+// delete the CustomResourceDefinitions installed using InstallCRDsFromFS. When waitForCleanup is set, the deletion
+// of the CustomResourceDefinitions waits until all their custom resources are gone, which gives the controller
+// under test the opportunity to remove the finalizers of the custom resources deleted by the tests.
+func UninstallCRDsFromFS(fsys fs.FS, dir string, waitForCleanup bool) env.Func {
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		r, err := resources.New(c.Client().RESTConfig())
+		if err != nil {
+			return ctx, err
+		}
+		crds, err := fs.Sub(fsys, dir)
+		if err != nil {
+			return ctx, err
+		}
+		objs, err := decoder.DecodeAllFiles(ctx, crds, "*.yaml")
+		if err != nil {
+			return ctx, fmt.Errorf("uninstall crds of %s: %w", dir, err)
+		}
+		for _, obj := range objs {
+			if waitForCleanup {
+				if err := waitForCustomResourcesDeleted(ctx, r, obj); err != nil {
+					return ctx, err
+				}
+			}
+			if err := decoder.DeleteIgnoreNotFound(r)(ctx, obj); err != nil {
+				return ctx, fmt.Errorf("uninstall crds of %s: %w", dir, err)
+			}
+		}
+		return ctx, nil
+	}
+}
+
+// waitForCustomResourcesDeleted waits until no custom resource defined by the CustomResourceDefinition remains,
+// using the default timeout of the waits
+func waitForCustomResourcesDeleted(ctx context.Context, r *resources.Resources, crd k8s.Object) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(crd)
+	if err != nil {
+		return err
+	}
+	group, _, _ := unstructured.NestedString(content, "spec", "group")
+	kind, _, _ := unstructured.NestedString(content, "spec", "names", "kind")
+	versions, _, _ := unstructured.NestedSlice(content, "spec", "versions")
+	var version string
+	for _, v := range versions {
+		fields, _ := v.(map[string]interface{})
+		if storage, _ := fields["storage"].(bool); storage {
+			version, _ = fields["name"].(string)
+		}
+	}
+	if kind == "" || version == "" {
+		return fmt.Errorf("custom resource definition %s has no kind or storage version", crd.GetName())
+	}
+
+	err = wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(schema.GroupVersionKind{Group: group, Version: version, Kind: kind + "List"})
+		if err := r.List(ctx, list); err != nil {
+			// the custom resources are gone along with their definition
+			return apierrors.IsNotFound(err) || meta.IsNoMatchError(err), nil
+		}
+		return len(list.Items) == 0, nil
+	}, wait.WithImmediate(), wait.WithInterval(time.Second))
+	if err != nil {
+		return fmt.Errorf("waiting for the %s custom resources to be deleted: %w", kind, err)
+	}
+	return nil
+}
+
+// applyManifestDir creates or updates the objects of the manifests of the directory matching the pattern
+func applyManifestDir(ctx context.Context, r *resources.Resources, manifestDir, pattern string) ([]k8s.Object, error) {
+	return applyManifestFS(ctx, r, os.DirFS(manifestDir), pattern)
+}
+
+// applyManifestFS creates or updates the objects of the manifests of fsys matching the pattern
+func applyManifestFS(ctx context.Context, r *resources.Resources, fsys fs.FS, pattern string) ([]k8s.Object, error) {
+	var objs []k8s.Object
+	apply := decoder.CreateOrUpdateHandler(r)
+	err := decoder.DecodeEachFile(ctx, fsys, pattern, func(ctx context.Context, obj k8s.Object) error {
+		if err := apply(ctx, obj); err != nil {
+			return err
+		}
+		objs = append(objs, obj)
+		return nil
+	})
+	return objs, err
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+const defaultSystemReadyTimeout = 5 * time.Minute
+
+// SystemReadyOption configures the checks done by WaitForSystemReady
+type SystemReadyOption func(*systemReadyOptions)
+
+// systemCheck is a condition checked by WaitForSystemReady along with what it waits for
+type systemCheck struct {
+	description string
+	condition   apimachinerywait.ConditionWithContextFunc
+}
+
+type systemReadyOptions struct {
+	namespaces []string
+	timeout    time.Duration
+}
+
+// WithAddonNamespaces adds namespaces to check along with kube-system, such as the namespaces of the addons
+// installed by the cluster provider, e.g. local-path-storage on kind clusters
+func WithAddonNamespaces(namespaces ...string) SystemReadyOption {
+	return func(o *systemReadyOptions) {
+		o.namespaces = append(o.namespaces, namespaces...)
+	}
+}
+
+// WithSystemReadyTimeout sets how long WaitForSystemReady waits for the cluster to settle, 5 minutes by default
+func WithSystemReadyTimeout(timeout time.Duration) SystemReadyOption {
+	return func(o *systemReadyOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WaitForSystemReady returns an EnvFunc that waits for the cluster to be settled, which is when all the nodes
+// are Ready and all the Deployments and DaemonSets of kube-system, and of the addon namespaces configured using
+// WithAddonNamespaces, are available. Right after a cluster is created, the system components can still be
+// starting, so this is meant to be used in env.Setup after the creation of the cluster to keep the first
+// feature from flaking. A namespace that does not exist is considered ready.
+func WaitForSystemReady(opts ...SystemReadyOption) env.Func {
+	options := &systemReadyOptions{namespaces: []string{"kube-system"}, timeout: defaultSystemReadyTimeout}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		r, err := resources.New(cfg.Client().RESTConfig())
+		if err != nil {
+			return ctx, fmt.Errorf("wait for system ready func: %w", err)
+		}
+		cond := conditions.New(r)
+		checks := []systemCheck{{description: "nodes to be ready", condition: cond.NodesReady()}}
+		for _, ns := range options.namespaces {
+			checks = append(checks,
+				systemCheck{description: fmt.Sprintf("deployments of namespace %s to be available", ns), condition: cond.AllDeploymentsAvailable(ns)},
+				systemCheck{description: fmt.Sprintf("daemonsets of namespace %s to be ready", ns), condition: cond.AllDaemonSetsReady(ns)},
+			)
+		}
+
+		// the checks share the timeout, which bounds the context of the waits rather than each wait
+		waitCtx, cancel := context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+		for _, c := range checks {
+			if err := wait.For(c.condition, wait.WithImmediate(), wait.WithContext(waitCtx), wait.WithTimeout(options.timeout)); err != nil {
+				return ctx, fmt.Errorf("wait for system ready func: waiting for %s: %w", c.description, err)
+			}
+		}
+		return ctx, nil
+	}
+}
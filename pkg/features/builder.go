@@ -19,6 +19,7 @@ package features
 import (
 	"fmt"
 
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/internal/types"
 )
 
@@ -64,6 +65,17 @@ func (b *FeatureBuilder) WithSetup(name string, fn Func) *FeatureBuilder {
 	return b.WithStep(name, types.LevelSetup, fn)
 }
 
+// WithSetupStep adds a new named setup step that is executed as a subtest of the feature named after
+// the step. When a feature has several setup steps, this makes the step that failed visible in the
+// test output. Failing the subtest using t.Fatal or t.FailNow stops the feature like for any other
+// setup step.
+func (b *FeatureBuilder) WithSetupStep(name string, fn Func) *FeatureBuilder {
+	step := newStep(name, types.LevelSetup, fn)
+	step.subtest = true
+	b.feat.steps = append(b.feat.steps, step)
+	return b
+}
+
 // Teardown adds a new teardown step that will be applied after feature test.
 func (b *FeatureBuilder) Teardown(fn Func) *FeatureBuilder {
 	return b.WithTeardown(fmt.Sprintf("%s-teardown", b.feat.name), fn)
@@ -75,15 +87,94 @@ func (b *FeatureBuilder) WithTeardown(name string, fn Func) *FeatureBuilder {
 	return b.WithStep(name, types.LevelTeardown, fn)
 }
 
+// WithTeardownStep adds a new named teardown step that is executed as a subtest of the feature named
+// after the step, the same way WithSetupStep does for setup steps.
+func (b *FeatureBuilder) WithTeardownStep(name string, fn Func) *FeatureBuilder {
+	step := newStep(name, types.LevelTeardown, fn)
+	step.subtest = true
+	b.feat.steps = append(b.feat.steps, step)
+	return b
+}
+
 // Assess adds an assessment step to the feature test.
 func (b *FeatureBuilder) Assess(desc string, fn Func) *FeatureBuilder {
 	return b.WithStep(desc, types.LevelAssess, fn)
 }
 
+// AssessParallel adds an assessment step that is executed as a parallel subtest, using t.Parallel, so that
+// independent assessments, such as read-only checks, run concurrently. Adjacent parallel assessments are
+// executed together as the subtests of a "parallel" subtest of the feature, which completes once all of them
+// are done, so the following assessments and the teardown steps only run afterwards.
+//
+// The parallel assessments share the context returned by the previous steps, the contexts they return are
+// discarded. They must not modify the state they share, such as the objects stored in the context, without
+// synchronization.
+func (b *FeatureBuilder) AssessParallel(desc string, fn Func) *FeatureBuilder {
+	step := newStep(desc, types.LevelAssess, fn)
+	step.parallel = true
+	b.feat.steps = append(b.feat.steps, step)
+	return b
+}
+
 func (b *FeatureBuilder) AssessWithDescription(name, description string, fn Func) *FeatureBuilder {
 	return b.WithStepDescription(name, description, types.LevelAssess, fn)
 }
 
+// Require adds a check of a hard prerequisite of the feature, such as the presence of a specific storage class
+// or of a license secret, which is run before the setup steps of the feature. If the check returns an error,
+// the feature fails with a message reporting the unmet precondition and none of its steps, including the
+// assessments and teardowns, are executed. Unlike a skipped feature, a feature with an unmet precondition
+// is reported as failed, unless the check returns a *SkipError to skip the feature when it does not apply.
+func (b *FeatureBuilder) Require(check RequirementFunc) *FeatureBuilder {
+	b.feat.requirements = append(b.feat.requirements, check)
+	return b
+}
+
+// WithFixture attaches fixtures to the feature, so that their setup is executed before the setup steps of the
+// feature unless another feature depending on them already did, and their teardown once the last feature depending
+// on them is done. See Fixture for how the fixtures are shared.
+func (b *FeatureBuilder) WithFixture(fixtures ...*Fixture) *FeatureBuilder {
+	for _, fixture := range fixtures {
+		fixture.addDependent()
+		b.feat.fixtures = append(b.feat.fixtures, fixture)
+	}
+	return b
+}
+
+// DependsOn declares that the feature depends on the features with the provided names, for instance when it uses
+// the objects they create. The features passed to the same Test or TestInParallel call are tested in the order of
+// their dependencies instead of the order they are passed in, and a feature is skipped when a feature it depends
+// on failed or was skipped. An unknown dependency or a dependency cycle fails the test before any feature is tested.
+// Registering the features tested together using env.DependentFeaturesEnvironment reports these errors when the
+// test suite starts instead, before any of the Setup funcs of the environment is executed, e.g.
+//
+//	testenv.(env.DependentFeaturesEnvironment).RegisterFeatures(install, upgrade)
+func (b *FeatureBuilder) DependsOn(names ...string) *FeatureBuilder {
+	b.feat.dependencies = append(b.feat.dependencies, names...)
+	return b
+}
+
+// TrackResources reports the changes made to the objects while the feature is tested, such as a replica count
+// bumped by a controller. The objects are fetched before the setup steps of the feature and once its assessments
+// are done, before its teardown steps, and the changes are reported as unified diffs of their YAML, ignoring the
+// managedFields and resourceVersion metadata. The diffs are logged in the output of the feature and reported to
+// the observers in the FeatureResult. A missing object is rendered as empty, so that an object created or deleted
+// by the feature shows as a whole. Only the kind, name and namespace of the objects are used.
+func (b *FeatureBuilder) TrackResources(objs ...k8s.Object) *FeatureBuilder {
+	b.feat.tracked = append(b.feat.tracked, objs...)
+	return b
+}
+
+// SkipIfVersionOutside skips the feature when the version of the Kubernetes API server, fetched using the discovery
+// API, is outside of the [min, max) range, such as a feature relying on an API introduced in 1.27 or on a behavior
+// removed in 1.29. The bounds are versions such as "1.27" or "v1.28.3", either of them can be empty for an open-ended
+// range. The check is run along with the prerequisites configured using Require and the reason of the skip is
+// reported in the test output.
+func (b *FeatureBuilder) SkipIfVersionOutside(min, max string) *FeatureBuilder {
+	b.feat.requirements = append(b.feat.requirements, serverVersionWithin(min, max))
+	return b
+}
+
 // Feature returns a feature configured by builder.
 func (b *FeatureBuilder) Feature() types.Feature {
 	return b.feat
@@ -133,6 +133,51 @@ func TestFeatureBuilder(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "subtest setup and teardown steps",
+			setup: func(t *testing.T) types.Feature {
+				return New("test").
+					WithSetupStep("setup-step", func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+						return ctx
+					}).
+					WithTeardownStep("teardown-step", func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+						return ctx
+					}).Feature()
+			},
+			eval: func(t *testing.T, f types.Feature) {
+				ft := f.(*defaultFeature) // nolint
+				for level, name := range map[types.Level]string{types.LevelSetup: "setup-step", types.LevelTeardown: "teardown-step"} {
+					steps := GetStepsByLevel(ft.Steps(), level)
+					if len(steps) != 1 || steps[0].Name() != name {
+						t.Fatalf("unexpected steps: %v", steps)
+					}
+					if s, ok := steps[0].(types.SubtestStep); !ok || !s.Subtest() {
+						t.Errorf("expected step %s to be executed as a subtest", name)
+					}
+				}
+			},
+		},
+		{
+			name: "parallel assessment",
+			setup: func(t *testing.T) types.Feature {
+				noop := func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+					return ctx
+				}
+				return New("test").AssessParallel("parallel", noop).Assess("serial", noop).Feature()
+			},
+			eval: func(t *testing.T, f types.Feature) {
+				ft := f.(*defaultFeature) // nolint
+				assessments := GetStepsByLevel(ft.Steps(), types.LevelAssess)
+				if len(assessments) != 2 {
+					t.Fatalf("unexpected assessments: %v", assessments)
+				}
+				for i, parallel := range []bool{true, false} {
+					if s, ok := assessments[i].(types.ParallelStep); !ok || s.Parallel() != parallel {
+						t.Errorf("expected assessment %s to be parallel: %t", assessments[i].Name(), parallel)
+					}
+				}
+			},
+		},
 		{
 			name: "one teardown",
 			setup: func(t *testing.T) types.Feature {
@@ -254,6 +299,25 @@ func TestFeatureBuilder(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "with requirements",
+			setup: func(t *testing.T) types.Feature {
+				return New("test").Require(func(ctx context.Context, _ *envconf.Config) error {
+					return nil
+				}).Require(func(ctx context.Context, _ *envconf.Config) error {
+					return nil
+				}).Feature()
+			},
+			eval: func(t *testing.T, f types.Feature) {
+				ft, ok := f.(types.RequiringFeature)
+				if !ok {
+					t.Fatal("expected feature to implement RequiringFeature")
+				}
+				if len(ft.Requirements()) != 2 {
+					t.Errorf("unexpected number of requirements %d", len(ft.Requirements()))
+				}
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -262,3 +326,31 @@ func TestFeatureBuilder(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionWithin(t *testing.T) {
+	tests := []struct {
+		server, min, max string
+		within           bool
+	}{
+		{server: "v1.27.3", min: "1.27", max: "1.29", within: true},
+		{server: "v1.26.6", min: "1.27", max: "1.29"},
+		{server: "v1.29.0", min: "1.27", max: "1.29"},
+		{server: "v1.28.2+k3s1", min: "1.27", max: "1.29", within: true},
+		{server: "v1.30.0", min: "v1.27.0", within: true},
+		{server: "v1.22.0", max: "1.23", within: true},
+		{server: "v1.23.1", max: "1.23"},
+		{server: "v1.25.0", within: true},
+	}
+	for _, tt := range tests {
+		within, err := versionWithin(tt.server, tt.min, tt.max)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %s", tt.server, err)
+		}
+		if within != tt.within {
+			t.Errorf("expected %s within [%q, %q) to be %t", tt.server, tt.min, tt.max, tt.within)
+		}
+	}
+	if _, err := versionWithin("v1.27.3", "latest", ""); err == nil {
+		t.Error("expected an invalid bound to be rejected")
+	}
+}
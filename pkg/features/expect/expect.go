@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package expect provides light assertion helpers to be used in the setup, assessment and teardown
+// functions of features, so that failures are reported with uniform messages across a suite. All the
+// helpers stop the test on failure, the same way t.Fatal does. Using them is optional, the features
+// can keep using t.Error and t.Fatal directly or any assertion library.
+//
+//	Assess("pod is running", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+//		pod := &corev1.Pod{...}
+//		expect.NoError(t, cfg.Client().Resources().Create(ctx, pod), pod)
+//		expect.Eventually(ctx, t, conditions.New(cfg.Client().Resources()).PodRunning(pod), wait.WithTimeout(time.Minute))
+//		expect.Equal(t, pod.Spec.NodeName, "kind-control-plane")
+//		return ctx
+//	})
+package expect
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+// NoError fails the test if err is not nil. The objects under test, if any, are named in the failure
+// message to make it clear which resource the failing operation was performed on.
+func NoError(t testing.TB, err error, objs ...k8s.Object) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("expect: unexpected error%s: %s", describe(objs...), err)
+	}
+}
+
+// Error fails the test if err is nil
+func Error(t testing.TB, err error, objs ...k8s.Object) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expect: expected an error%s", describe(objs...))
+	}
+}
+
+// Equal fails the test if got and want are not deeply equal. When got is an object, it is named in
+// the failure message.
+func Equal(t testing.TB, got, want interface{}) {
+	t.Helper()
+	if reflect.DeepEqual(got, want) {
+		return
+	}
+	if obj, ok := got.(k8s.Object); ok {
+		t.Fatalf("expect: unexpected value%s:\ngot:  %+v\nwant: %+v", describe(obj), got, want)
+	}
+	t.Fatalf("expect: unexpected value:\ngot:  %+v\nwant: %+v", got, want)
+}
+
+// Eventually fails the test if the condition, typically one of the conditions of the
+// klient/wait/conditions package, is not met in time. The condition is polled using ctx, which is
+// usually the context the feature function was invoked with, and the options of wait.For.
+func Eventually(ctx context.Context, t testing.TB, condition apimachinerywait.ConditionWithContextFunc, opts ...wait.Option) {
+	t.Helper()
+	if err := wait.ForFunc(ctx, condition, opts...); err != nil {
+		t.Fatalf("expect: condition not met: %s", err)
+	}
+}
+
+// describe returns the kind, namespace and name of the objects, to be appended to a failure message
+func describe(objs ...k8s.Object) string {
+	var desc string
+	for _, obj := range objs {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		if kind == "" {
+			kind = reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+		}
+		name := obj.GetName()
+		if obj.GetNamespace() != "" {
+			name = obj.GetNamespace() + "/" + name
+		}
+		desc += fmt.Sprintf(" [%s %s]", kind, name)
+	}
+	if desc == "" {
+		return ""
+	}
+	return " for" + desc
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+// recorder records the failure reported by the helpers instead of stopping the test
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestExpect(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+	done := func(context.Context) (bool, error) { return true, nil }
+	never := func(context.Context) (bool, error) { return false, nil }
+
+	tests := []struct {
+		name     string
+		assert   func(t testing.TB)
+		expected string
+	}{
+		{name: "no error", assert: func(t testing.TB) { NoError(t, nil, pod) }},
+		{
+			name:     "unexpected error",
+			assert:   func(t testing.TB) { NoError(t, errors.New("boom"), pod) },
+			expected: "expect: unexpected error for [Pod default/test-pod]: boom",
+		},
+		{name: "error", assert: func(t testing.TB) { Error(t, errors.New("boom")) }},
+		{name: "missing error", assert: func(t testing.TB) { Error(t, nil) }, expected: "expect: expected an error"},
+		{name: "equal", assert: func(t testing.TB) { Equal(t, []string{"a"}, []string{"a"}) }},
+		{
+			name:     "not equal",
+			assert:   func(t testing.TB) { Equal(t, 1, 2) },
+			expected: "expect: unexpected value:\ngot:  1\nwant: 2",
+		},
+		{name: "condition met", assert: func(t testing.TB) { Eventually(context.TODO(), t, done, wait.WithImmediate()) }},
+		{
+			name: "condition not met",
+			assert: func(t testing.TB) {
+				Eventually(context.TODO(), t, never, wait.WithTimeout(50*time.Millisecond), wait.WithInterval(10*time.Millisecond))
+			},
+			expected: "expect: condition not met: context deadline exceeded",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			test.assert(r)
+			if r.failure != test.expected {
+				t.Errorf("unexpected failure: %q, expected: %q", r.failure, test.expected)
+			}
+		})
+	}
+}
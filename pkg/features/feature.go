@@ -19,6 +19,7 @@ package features
 import (
 	"regexp"
 
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/internal/types"
 )
 
@@ -28,13 +29,23 @@ type (
 	Step    = types.Step
 	Func    = types.StepFunc
 	Level   = types.Level
+
+	// RequirementFunc checks a prerequisite of a feature configured using FeatureBuilder.Require
+	RequirementFunc = types.RequirementFunc
+
+	// SkipError is returned by a RequirementFunc to skip the feature instead of failing it
+	SkipError = types.SkipError
 )
 
 type defaultFeature struct {
-	name        string
-	description string
-	labels      types.Labels
-	steps       []types.Step
+	name         string
+	description  string
+	labels       types.Labels
+	steps        []types.Step
+	requirements []types.RequirementFunc
+	fixtures     []types.Fixture
+	dependencies []string
+	tracked      []k8s.Object
 }
 
 func newDefaultFeature(name, description string) *defaultFeature {
@@ -53,6 +64,22 @@ func (f *defaultFeature) Steps() []types.Step {
 	return f.steps
 }
 
+func (f *defaultFeature) Requirements() []types.RequirementFunc {
+	return f.requirements
+}
+
+func (f *defaultFeature) Fixtures() []types.Fixture {
+	return f.fixtures
+}
+
+func (f *defaultFeature) Dependencies() []string {
+	return f.dependencies
+}
+
+func (f *defaultFeature) TrackedResources() []k8s.Object {
+	return f.tracked
+}
+
 func (f *defaultFeature) Description() string {
 	return f.description
 }
@@ -62,6 +89,8 @@ type testStep struct {
 	description string
 	level       Level
 	fn          Func
+	subtest     bool
+	parallel    bool
 }
 
 func newStep(name string, level Level, fn Func) *testStep {
@@ -93,6 +122,14 @@ func (s *testStep) Description() string {
 	return s.description
 }
 
+func (s *testStep) Subtest() bool {
+	return s.subtest
+}
+
+func (s *testStep) Parallel() bool {
+	return s.parallel
+}
+
 func GetStepsByLevel(steps []types.Step, l types.Level) []types.Step {
 	if steps == nil {
 		return nil
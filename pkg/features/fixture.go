@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// Fixture is a setup shared by several features, such as the installation of an operator needed by all of them,
+// attached to the features using FeatureBuilder.WithFixture. Within a run of an Environment, the setup of the
+// fixture is executed once, before the setup steps of the first feature depending on it, and its teardown is
+// executed once the last of the features depending on it is done, including the features skipped by the test
+// filters. A fixture still set up when the test suite finishes, for instance because some of its features were
+// never tested, is torn down before the Finish funcs of the environment are executed.
+//
+// The values added to the context by the setup of the fixture are visible to the steps of all the features
+// depending on it, the values added by the features are not shared. When the setup fails, all the features
+// depending on the fixture fail and the teardown is not executed.
+//
+// The features depending on a fixture are counted when they are built, so a fixture is meant to be shared by the
+// features tested in a single Environment.
+type Fixture struct {
+	name     string
+	setup    types.EnvFunc
+	teardown types.EnvFunc
+
+	mu sync.Mutex
+	// dependents is the number of features depending on the fixture, done is the number of those which
+	// completed since the fixture was set up
+	dependents int
+	done       int
+	ready      bool
+	ctx        context.Context
+	err        error
+}
+
+// NewFixture returns a fixture running setup the first time a feature depending on it is tested and teardown
+// once all of them are done. Either function can be nil.
+func NewFixture(name string, setup, teardown types.EnvFunc) *Fixture {
+	return &Fixture{name: name, setup: setup, teardown: teardown}
+}
+
+// Name returns the name of the fixture
+func (f *Fixture) Name() string {
+	return f.name
+}
+
+// addDependent records a feature depending on the fixture
+func (f *Fixture) addDependent() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dependents++
+}
+
+// Acquire sets the fixture up, unless it is already, and returns ctx extended with the values added to the context
+// by the setup. Concurrent callers wait for the setup to complete. This is invoked by the test environment before
+// a feature depending on the fixture is tested and should not be needed by most users.
+func (f *Fixture) Acquire(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.ready && f.err == nil {
+		f.ctx, f.err = ctx, nil
+		if f.setup != nil {
+			if f.ctx, f.err = f.setup(ctx, cfg); f.ctx == nil {
+				f.ctx = ctx
+			}
+		}
+		if f.err != nil {
+			f.err = fmt.Errorf("fixture %s setup: %w", f.name, f.err)
+		} else {
+			f.ready = true
+		}
+	}
+	if f.err != nil {
+		return ctx, f.err
+	}
+	return &fixtureContext{Context: ctx, shared: f.ctx}, nil
+}
+
+// Release records that a feature depending on the fixture is done, whether it acquired the fixture or was skipped,
+// and tears the fixture down once all the features depending on it are done. This is invoked by the test
+// environment and should not be needed by most users.
+func (f *Fixture) Release(cfg *envconf.Config) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.done++
+	if f.done < f.dependents {
+		return nil
+	}
+	return f.reset(cfg)
+}
+
+// Close tears the fixture down if it is still set up. This is invoked by the test environment when the test suite
+// finishes and should not be needed by most users.
+func (f *Fixture) Close(cfg *envconf.Config) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reset(cfg)
+}
+
+// reset runs the teardown of the fixture if it is set up and starts a new round, so that the fixture is set up
+// again if a feature depending on it is tested afterwards
+func (f *Fixture) reset(cfg *envconf.Config) error {
+	ready, ctx := f.ready, f.ctx
+	f.done, f.ready, f.ctx, f.err = 0, false, nil, nil
+	if !ready || f.teardown == nil {
+		return nil
+	}
+	if _, err := f.teardown(ctx, cfg); err != nil {
+		return fmt.Errorf("fixture %s teardown: %w", f.name, err)
+	}
+	return nil
+}
+
+// fixtureContext is the context of a feature extended with the values of the context returned by the setup of a
+// fixture. The values of the feature take precedence, the deadline and cancellation are the ones of the feature.
+type fixtureContext struct {
+	context.Context
+	shared context.Context
+}
+
+func (c *fixtureContext) Value(key interface{}) interface{} {
+	if value := c.Context.Value(key); value != nil {
+		return value
+	}
+	return c.shared.Value(key)
+}
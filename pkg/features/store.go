@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"sync"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+type storeContextKey struct{}
+
+// Store is a key/value store scoped to the execution of a single feature. It provides a way to share
+// data between the steps of a feature, such as handing the name of an object created during the Setup
+// phase to the Assess phase, without leaking the data to other features.
+//
+// The store of the feature being tested can be retrieved from the context passed to the steps using
+// StoreFromContext. The store is created before the BeforeEachFeature actions are executed and is
+// cleared once the AfterEachFeature actions have been executed.
+type Store struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// ContextWithStore returns a copy of ctx with a new empty Store attached to it. This is invoked by the
+// test environment before a feature is tested and should not be needed by most users.
+func ContextWithStore(ctx context.Context) context.Context {
+	return context.WithValue(ctx, storeContextKey{}, &Store{values: make(map[string]interface{})})
+}
+
+// ReleaseStore clears the Store attached to ctx and returns a copy of ctx where the store is no longer
+// accessible. This is invoked by the test environment once a feature has been tested.
+func ReleaseStore(ctx context.Context) context.Context {
+	if s := StoreFromContext(ctx); s != nil {
+		s.mu.Lock()
+		s.values = make(map[string]interface{})
+		s.mu.Unlock()
+	}
+	return context.WithValue(ctx, storeContextKey{}, (*Store)(nil))
+}
+
+// StoreFromContext returns the Store of the feature being tested or nil if ctx is not
+// associated with a feature.
+func StoreFromContext(ctx context.Context) *Store {
+	s, _ := ctx.Value(storeContextKey{}).(*Store)
+	return s
+}
+
+// Set stores the value under the provided key, replacing any existing value.
+func (s *Store) Set(key string, value interface{}) {
+	if s == nil {
+		panic("features: store is only available while a feature is being tested")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Get returns the value stored under the key and reports whether the key was found.
+func (s *Store) Get(key string) (interface{}, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.values[key]
+	return val, ok
+}
+
+// Delete removes the value stored under the key
+func (s *Store) Delete(key string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// GetString returns the string value stored under the key. The boolean result is false if the key
+// was not found or if the stored value is not a string.
+func (s *Store) GetString(key string) (string, bool) {
+	val, _ := s.Get(key)
+	str, ok := val.(string)
+	return str, ok
+}
+
+// GetInt returns the int value stored under the key. The boolean result is false if the key
+// was not found or if the stored value is not an int.
+func (s *Store) GetInt(key string) (int, bool) {
+	val, _ := s.Get(key)
+	i, ok := val.(int)
+	return i, ok
+}
+
+// GetBool returns the bool value stored under the key. The boolean result is false if the key
+// was not found or if the stored value is not a bool.
+func (s *Store) GetBool(key string) (value, ok bool) {
+	val, _ := s.Get(key)
+	value, ok = val.(bool)
+	return value, ok
+}
+
+// GetObject returns the Kubernetes object stored under the key. The boolean result is false if the key
+// was not found or if the stored value does not satisfy k8s.Object.
+func (s *Store) GetObject(key string) (k8s.Object, bool) {
+	val, _ := s.Get(key)
+	obj, ok := val.(k8s.Object)
+	return obj, ok
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStore(t *testing.T) {
+	ctx := ContextWithStore(context.Background())
+	store := StoreFromContext(ctx)
+	if store == nil {
+		t.Fatal("expected a store to be attached to the context")
+	}
+
+	store.Set("name", "test-pod")
+	store.Set("replicas", 3)
+	store.Set("enabled", true)
+	store.Set("pod", &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod"}})
+
+	if v, ok := store.GetString("name"); !ok || v != "test-pod" {
+		t.Errorf("unexpected string value: %v", v)
+	}
+	if v, ok := store.GetInt("replicas"); !ok || v != 3 {
+		t.Errorf("unexpected int value: %v", v)
+	}
+	if v, ok := store.GetBool("enabled"); !ok || !v {
+		t.Errorf("unexpected bool value: %v", v)
+	}
+	if v, ok := store.GetObject("pod"); !ok || v.GetName() != "test-pod" {
+		t.Errorf("unexpected object value: %v", v)
+	}
+	if _, ok := store.GetInt("name"); ok {
+		t.Error("expected type mismatch to be reported")
+	}
+
+	store.Delete("name")
+	if _, ok := store.Get("name"); ok {
+		t.Error("expected deleted key to be missing")
+	}
+
+	ctx = ReleaseStore(ctx)
+	if StoreFromContext(ctx) != nil {
+		t.Error("expected store to be inaccessible after release")
+	}
+	if _, ok := store.Get("replicas"); ok {
+		t.Error("expected store to be cleared after release")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/internal/types"
+)
+
+// serverVersionWithin returns a requirement skipping the feature when the version of the API server is outside of
+// the [min, max) range
+func serverVersionWithin(min, max string) RequirementFunc {
+	return func(ctx context.Context, cfg *envconf.Config) error {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return err
+		}
+		dc, err := discovery.NewDiscoveryClientForConfig(client.RESTConfig())
+		if err != nil {
+			return fmt.Errorf("server version: %w", err)
+		}
+		info, err := dc.ServerVersion()
+		if err != nil {
+			return fmt.Errorf("server version: %w", err)
+		}
+		within, err := versionWithin(info.GitVersion, min, max)
+		if err != nil {
+			return err
+		}
+		if !within {
+			return &types.SkipError{Reason: fmt.Sprintf("server version %s is outside of the supported range %s", info.GitVersion, versionRange(min, max))}
+		}
+		return nil
+	}
+}
+
+// versionWithin reports whether the server version is within the [min, max) range, where empty bounds are open
+func versionWithin(server, min, max string) (bool, error) {
+	v, err := version.ParseGeneric(server)
+	if err != nil {
+		return false, fmt.Errorf("server version: %w", err)
+	}
+	if min != "" {
+		minVersion, err := version.ParseGeneric(min)
+		if err != nil {
+			return false, fmt.Errorf("minimum version: %w", err)
+		}
+		if !v.AtLeast(minVersion) {
+			return false, nil
+		}
+	}
+	if max != "" {
+		maxVersion, err := version.ParseGeneric(max)
+		if err != nil {
+			return false, fmt.Errorf("maximum version: %w", err)
+		}
+		if !v.LessThan(maxVersion) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// versionRange returns a readable form of the [min, max) range
+func versionRange(min, max string) string {
+	switch {
+	case min == "":
+		return "< " + max
+	case max == "":
+		return ">= " + min
+	default:
+		return fmt.Sprintf(">= %s, < %s", min, max)
+	}
+}
@@ -19,7 +19,9 @@ package types
 import (
 	"context"
 	"testing"
+	"time"
 
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/flags"
 )
@@ -87,6 +89,49 @@ type Environment interface {
 	Run(*testing.M) int
 }
 
+// FinishLastEnvironment is an environment able to execute funcs after
+// all the funcs registered using Finish. The environments created by
+// the env package implement it, e.g.
+//
+//	testenv.(env.FinishLastEnvironment).FinishLast(envfuncs.DestroyKindCluster(name))
+type FinishLastEnvironment interface {
+	Environment
+
+	// FinishLast registers funcs that are executed at the end of the
+	// test suite, after all the funcs registered using Finish.
+	FinishLast(...EnvFunc) Environment
+}
+
+// ObservableEnvironment is an environment notifying observers of the
+// lifecycle events of the test suite, its features and their
+// assessments. The environments created by the env package implement
+// it, e.g.
+//
+//	testenv.(env.ObservableEnvironment).WithObserver(observer)
+type ObservableEnvironment interface {
+	Environment
+
+	// WithObserver registers an Observer notified of the lifecycle
+	// events of the test suite, its features and their assessments.
+	WithObserver(Observer) Environment
+}
+
+// DependentFeaturesEnvironment is an environment validating the
+// dependencies declared by the features before the test suite starts,
+// so that an unknown dependency or a dependency cycle is reported before
+// any of the Setup funcs is executed. The environments created by the
+// env package implement it, e.g.
+//
+//	testenv.(env.DependentFeaturesEnvironment).RegisterFeatures(install, upgrade)
+type DependentFeaturesEnvironment interface {
+	Environment
+
+	// RegisterFeatures registers features tested together by a call to
+	// Test or TestInParallel and validates their dependencies. Run fails
+	// before executing any func when they are invalid.
+	RegisterFeatures(...Feature) Environment
+}
+
 type Labels = flags.LabelsMap
 
 type Feature interface {
@@ -127,6 +172,83 @@ type DescribableStep interface {
 	Description() string
 }
 
+// SubtestStep is a step that can be executed as a subtest named after the step so that a failure
+// of one of the setup or teardown steps of a feature can be located right away in the test output
+type SubtestStep interface {
+	Step
+	// Subtest indicates if the step is to be executed as a subtest
+	Subtest() bool
+}
+
+// ParallelStep is an assessment that can be executed as a parallel subtest, concurrently with the
+// adjacent parallel assessments of the same feature
+type ParallelStep interface {
+	Step
+	// Parallel indicates if the step is to be executed as a parallel subtest
+	Parallel() bool
+}
+
+// RequirementFunc checks a hard prerequisite of a feature, such as the presence of a storage class or
+// of a license secret, and returns an error describing what is missing when it is not met
+type RequirementFunc func(context.Context, *envconf.Config) error
+
+// SkipError is returned by a RequirementFunc when the feature does not apply to the cluster, so that the feature
+// is skipped with the reason instead of failing
+type SkipError struct {
+	Reason string
+}
+
+func (e *SkipError) Error() string {
+	return e.Reason
+}
+
+// RequiringFeature is a feature with prerequisites that must be met for its steps to be executed
+type RequiringFeature interface {
+	Feature
+
+	// Requirements returns the checks of the prerequisites of the feature
+	Requirements() []RequirementFunc
+}
+
+// Fixture is a setup shared by several features, which is set up once for all of them and torn down once all of
+// them are done
+type Fixture interface {
+	// Name is the name of the fixture
+	Name() string
+	// Acquire sets the fixture up, unless it is already, and returns the context extended with the values
+	// added by its setup
+	Acquire(context.Context, *envconf.Config) (context.Context, error)
+	// Release records that a feature depending on the fixture is done and tears the fixture down once all
+	// of them are done
+	Release(*envconf.Config) error
+	// Close tears the fixture down if it is still set up
+	Close(*envconf.Config) error
+}
+
+// FixtureFeature is a feature depending on fixtures shared with other features
+type FixtureFeature interface {
+	Feature
+
+	// Fixtures returns the fixtures the feature depends on
+	Fixtures() []Fixture
+}
+
+// DependentFeature is a feature depending on the outcome of other features tested along with it
+type DependentFeature interface {
+	Feature
+
+	// Dependencies returns the names of the features that must pass before the feature is tested
+	Dependencies() []string
+}
+
+// ResourceTrackingFeature is a feature reporting the changes made to resources while it is tested
+type ResourceTrackingFeature interface {
+	Feature
+
+	// TrackedResources returns the resources of which the changes are reported
+	TrackedResources() []k8s.Object
+}
+
 type DescribableFeature interface {
 	Feature
 
@@ -135,3 +257,60 @@ type DescribableFeature interface {
 	// feature.
 	Description() string
 }
+
+// FeatureResult is the outcome of a tested feature reported to the observers. Failed is set when any step of
+// the feature failed, including its setup and teardown steps.
+type FeatureResult struct {
+	Name        string
+	Start       time.Time
+	Duration    time.Duration
+	Failed      bool
+	Skipped     bool
+	Assessments []AssessmentResult
+	// ResourceDiffs are the changes made to the resources tracked by the feature, if any
+	ResourceDiffs []ResourceDiff
+}
+
+// ResourceDiff is the change made to a resource tracked by a feature. Diff is the unified diff of the YAML of the
+// resource before the setup steps of the feature and once its assessments are done.
+type ResourceDiff struct {
+	// Resource is the kind, namespace and name of the resource
+	Resource string
+	Diff     string
+}
+
+// AssessmentResult is the outcome of an assessment of a feature reported to the observers. Slow is set when
+// the assessment took longer than the configured slow threshold.
+type AssessmentResult struct {
+	Name     string
+	Duration time.Duration
+	Failed   bool
+	Skipped  bool
+	Slow     bool
+}
+
+// Observer is notified of the lifecycle events of a test suite, for instance to open a tracing span per
+// feature or to post a message when a feature fails. Features and assessments can be tested in parallel,
+// so the methods must be safe for concurrent use. The methods are called synchronously and should return
+// quickly, a failure of the observer cannot fail the tests.
+type Observer interface {
+	// OnSetup is called once the Setup funcs of the environment have been executed, with the error of
+	// the one that failed, if any
+	OnSetup(ctx context.Context, err error)
+
+	// OnFeatureStart is called before the steps of a feature are executed
+	OnFeatureStart(ctx context.Context, feature Feature)
+
+	// OnAssessStart is called before an assessment of a feature is executed
+	OnAssessStart(ctx context.Context, feature Feature, assessment Step)
+
+	// OnAssessFinish is called once an assessment of a feature is done
+	OnAssessFinish(ctx context.Context, feature Feature, result AssessmentResult)
+
+	// OnFeatureFinish is called once the steps of a feature, including its teardown steps, are done
+	OnFeatureFinish(ctx context.Context, feature Feature, result FeatureResult)
+
+	// OnTeardown is called once the Finish funcs of the environment have been executed, with the
+	// outcome of the test suite
+	OnTeardown(ctx context.Context, failed bool)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package support
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// cniDaemonSets are the name prefixes of the DaemonSets deploying the well known CNI plugins along with the plugin
+// names. When the DaemonSets of several plugins are found, such as when a CNI plugin chains another one, the first
+// one listed is reported.
+var cniDaemonSets = []struct {
+	prefix string
+	cni    string
+}{
+	{prefix: "cilium", cni: "cilium"},
+	{prefix: "calico-node", cni: "calico"},
+	{prefix: "antrea-agent", cni: "antrea"},
+	{prefix: "weave-net", cni: "weave"},
+	{prefix: "kube-flannel", cni: "flannel"},
+	{prefix: "kindnet", cni: "kindnet"},
+}
+
+// Capabilities describes what the cluster supports, so that features can be skipped on the clusters lacking what
+// they need, for instance using features.FeatureBuilder.Require.
+type Capabilities struct {
+	// Provider is the name of the cluster provider, when known
+	Provider string
+	// LoadBalancer reports whether Services of type LoadBalancer get an external address
+	LoadBalancer bool
+	// VolumeSnapshots reports whether the snapshot.storage.k8s.io API is served
+	VolumeSnapshots bool
+	// Metrics reports whether the metrics.k8s.io API, used by kubectl top and the autoscalers, is served
+	Metrics bool
+	// DefaultStorageClass is the name of the default StorageClass, empty when there is none
+	DefaultStorageClass string
+	// CNI is the name of the CNI plugin, such as kindnet, calico or cilium, empty when it was not recognized
+	CNI string
+}
+
+// DiscoverCapabilities probes the cluster reached using clientset for its capabilities. The APIs are looked up using
+// the discovery API, the default StorageClass and the CNI plugin are found by listing the StorageClasses and the
+// DaemonSets. As this cannot be discovered without creating a Service, LoadBalancer support is reported when
+// MetalLB is installed or when an existing Service of type LoadBalancer has been assigned an external address.
+func DiscoverCapabilities(ctx context.Context, clientset kubernetes.Interface) (Capabilities, error) {
+	var caps Capabilities
+	groups, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		return caps, fmt.Errorf("capabilities: discover api groups: %w", err)
+	}
+	for _, group := range groups.Groups {
+		switch group.Name {
+		case "snapshot.storage.k8s.io":
+			caps.VolumeSnapshots = true
+		case "metrics.k8s.io":
+			caps.Metrics = true
+		case "metallb.io":
+			caps.LoadBalancer = true
+		}
+	}
+
+	if !caps.LoadBalancer {
+		services, err := clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return caps, fmt.Errorf("capabilities: list services: %w", err)
+		}
+		for _, svc := range services.Items {
+			if svc.Spec.Type == v1.ServiceTypeLoadBalancer && len(svc.Status.LoadBalancer.Ingress) > 0 {
+				caps.LoadBalancer = true
+				break
+			}
+		}
+	}
+
+	storageClasses, err := clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return caps, fmt.Errorf("capabilities: list storage classes: %w", err)
+	}
+	for _, sc := range storageClasses.Items {
+		if sc.Annotations[defaultStorageClassAnnotation] == "true" {
+			caps.DefaultStorageClass = sc.Name
+			break
+		}
+	}
+
+	daemonSets, err := clientset.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return caps, fmt.Errorf("capabilities: list daemonsets: %w", err)
+	}
+	for _, known := range cniDaemonSets {
+		for _, ds := range daemonSets.Items {
+			if strings.HasPrefix(ds.Name, known.prefix) {
+				caps.CNI = known.cni
+				return caps, nil
+			}
+		}
+	}
+	return caps, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package support
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDiscoverCapabilities(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "standard", Annotations: map[string]string{defaultStorageClassAnnotation: "true"}}},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "lb", Namespace: "default"},
+			Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+			Status:     v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "172.18.255.200"}}}},
+		},
+		// kindnet is still deployed when cilium is installed alongside it
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kindnet", Namespace: "kube-system"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "cilium", Namespace: "kube-system"}},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "metrics.k8s.io/v1beta1"},
+	}
+
+	caps, err := DiscoverCapabilities(context.TODO(), clientset)
+	if err != nil {
+		t.Fatalf("unexpected error discovering capabilities: %s", err)
+	}
+	expected := Capabilities{LoadBalancer: true, Metrics: true, DefaultStorageClass: "standard", CNI: "cilium"}
+	if caps != expected {
+		t.Errorf("expected capabilities %+v, got %+v", expected, caps)
+	}
+}
+
+func TestDiscoverCapabilities_ListError(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	errForbidden := errors.New("forbidden")
+	clientset.PrependReactor("list", "storageclasses", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errForbidden
+	})
+	if _, err := DiscoverCapabilities(context.TODO(), clientset); !errors.Is(err, errForbidden) {
+		t.Errorf("expected the list error to be returned, got: %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/support"
+	"sigs.k8s.io/yaml"
+)
+
+const kindConfigAPIVersion = "kind.x-k8s.io/v1alpha4"
+
+// WithContainerdConfigPatches configures raw containerd config patches, in TOML, applied to the nodes of the
+// kind cluster, for instance to configure registry mirrors or the sandbox image. The patches are appended to
+// the containerdConfigPatches of the kind config file provided to CreateWithConfig, or of a generated config
+// file if none is provided, along with the settings configured using WithNetworking.
+func WithContainerdConfigPatches(patches ...string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.containerdConfigPatches = patches
+		}
+	}
+}
+
+// WithFeatureGates enables or disables feature gates, such as the gates of alpha features, on all the
+// Kubernetes components of the kind cluster. The gates are merged into the featureGates of the kind config
+// file provided to CreateWithConfig, or of a generated config file if none is provided, which kind turns
+// into the kubeadm configuration of the API server, the controller manager, the scheduler and the kubelet.
+func WithFeatureGates(gates map[string]bool) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.featureGates = gates
+		}
+	}
+}
+
+// WithRuntimeConfig enables or disables API groups and versions, such as alpha APIs, using the runtime config
+// of the API server of the kind cluster, e.g. {"api/alpha": "true"}. The settings are merged into the
+// runtimeConfig of the kind config file provided to CreateWithConfig, or of a generated config file if none
+// is provided.
+func WithRuntimeConfig(runtimeConfig map[string]string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.runtimeConfig = runtimeConfig
+		}
+	}
+}
+
+// ConfigOptions are the settings rendered into a kind config by GenerateConfig. They are the settings of the
+// WithNetworking, WithContainerdConfigPatches, WithFeatureGates and WithRuntimeConfig cluster options.
+type ConfigOptions struct {
+	// BaseConfig is an optional kind config, in YAML, into which the settings are merged
+	BaseConfig string
+	// PodSubnet, ServiceSubnet and IPFamily configure the networking of the cluster as WithNetworking does,
+	// empty values keep the kind defaults
+	PodSubnet     string
+	ServiceSubnet string
+	IPFamily      string
+	// ContainerdConfigPatches are appended to the containerd config patches of the base config
+	ContainerdConfigPatches []string
+	// FeatureGates are merged into the feature gates of the base config
+	FeatureGates map[string]bool
+	// RuntimeConfig is merged into the runtime config of the base config
+	RuntimeConfig map[string]string
+}
+
+// GenerateConfig renders the kind config carrying the provided settings, the way the cluster options do when the
+// cluster is created. This gives access to the generated config to inspect it or to tweak it before passing it
+// to CreateWithConfig, when the cluster options do not cover a case. The rendered config is checked to parse as
+// a kind Cluster config.
+func GenerateConfig(opts ConfigOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
+	config := map[string]interface{}{"kind": "Cluster", "apiVersion": kindConfigAPIVersion}
+	if opts.BaseConfig != "" {
+		if err := yaml.Unmarshal([]byte(opts.BaseConfig), &config); err != nil {
+			return "", fmt.Errorf("kind: parse base config: %w", err)
+		}
+	}
+	opts.apply(config)
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	if err := validateClusterConfig(data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// configOptions returns the settings of the cluster to render into its kind config
+func (k *Cluster) configOptions() ConfigOptions {
+	opts := ConfigOptions{
+		ContainerdConfigPatches: k.containerdConfigPatches,
+		FeatureGates:            k.featureGates,
+		RuntimeConfig:           k.runtimeConfig,
+	}
+	if k.networking != nil {
+		opts.PodSubnet = k.networking.podSubnet
+		opts.ServiceSubnet = k.networking.serviceSubnet
+		opts.IPFamily = k.networking.ipFamily
+	}
+	return opts
+}
+
+// empty returns true when there is no setting to render into the kind config
+func (o ConfigOptions) empty() bool {
+	return o.PodSubnet == "" && o.ServiceSubnet == "" && o.IPFamily == "" &&
+		len(o.ContainerdConfigPatches) == 0 && len(o.FeatureGates) == 0 && len(o.RuntimeConfig) == 0
+}
+
+// validate checks that the IP family is supported and that the patches, feature gates and runtime config keys
+// are not empty
+func (o ConfigOptions) validate() error {
+	switch o.IPFamily {
+	case "", IPFamilyIPv4, IPFamilyIPv6, IPFamilyDual:
+	default:
+		return fmt.Errorf("kind: unsupported ip family %q: must be one of %q, %q or %q", o.IPFamily, IPFamilyIPv4, IPFamilyIPv6, IPFamilyDual)
+	}
+	for i, patch := range o.ContainerdConfigPatches {
+		if strings.TrimSpace(patch) == "" {
+			return fmt.Errorf("kind: containerd config patch %d is empty", i)
+		}
+	}
+	for gate := range o.FeatureGates {
+		if strings.TrimSpace(gate) == "" {
+			return fmt.Errorf("kind: feature gate name is empty")
+		}
+	}
+	for key := range o.RuntimeConfig {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("kind: runtime config key is empty")
+		}
+	}
+	return nil
+}
+
+// apply sets the settings in the kind config
+func (o ConfigOptions) apply(config map[string]interface{}) {
+	if o.PodSubnet != "" || o.ServiceSubnet != "" || o.IPFamily != "" {
+		net, _ := config["networking"].(map[string]interface{})
+		if net == nil {
+			net = map[string]interface{}{}
+		}
+		for key, value := range map[string]string{
+			"podSubnet":     o.PodSubnet,
+			"serviceSubnet": o.ServiceSubnet,
+			"ipFamily":      o.IPFamily,
+		} {
+			if value != "" {
+				net[key] = value
+			}
+		}
+		config["networking"] = net
+	}
+	if len(o.ContainerdConfigPatches) > 0 {
+		patches, _ := config["containerdConfigPatches"].([]interface{})
+		for _, patch := range o.ContainerdConfigPatches {
+			patches = append(patches, patch)
+		}
+		config["containerdConfigPatches"] = patches
+	}
+	if len(o.FeatureGates) > 0 {
+		gates, _ := config["featureGates"].(map[string]interface{})
+		if gates == nil {
+			gates = map[string]interface{}{}
+		}
+		for gate, enabled := range o.FeatureGates {
+			gates[gate] = enabled
+		}
+		config["featureGates"] = gates
+	}
+	if len(o.RuntimeConfig) > 0 {
+		runtimeConfig, _ := config["runtimeConfig"].(map[string]interface{})
+		if runtimeConfig == nil {
+			runtimeConfig = map[string]interface{}{}
+		}
+		for key, value := range o.RuntimeConfig {
+			runtimeConfig[key] = value
+		}
+		config["runtimeConfig"] = runtimeConfig
+	}
+}
+
+// clusterConfig holds the top level fields of a kind Cluster config, the nested settings are left to kind
+type clusterConfig struct {
+	Kind                            string                 `json:"kind"`
+	APIVersion                      string                 `json:"apiVersion"`
+	Name                            string                 `json:"name,omitempty"`
+	FeatureGates                    map[string]bool        `json:"featureGates,omitempty"`
+	RuntimeConfig                   map[string]string      `json:"runtimeConfig,omitempty"`
+	Networking                      map[string]interface{} `json:"networking,omitempty"`
+	Nodes                           []interface{}          `json:"nodes,omitempty"`
+	KubeadmConfigPatches            []string               `json:"kubeadmConfigPatches,omitempty"`
+	KubeadmConfigPatchesJSON6902    []interface{}          `json:"kubeadmConfigPatchesJSON6902,omitempty"`
+	ContainerdConfigPatches         []string               `json:"containerdConfigPatches,omitempty"`
+	ContainerdConfigPatchesJSON6902 []string               `json:"containerdConfigPatchesJSON6902,omitempty"`
+}
+
+// validateClusterConfig checks that the data parses as a kind Cluster config, rejecting unknown top level fields
+// and fields of the wrong type
+func validateClusterConfig(data []byte) error {
+	var config clusterConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return fmt.Errorf("kind: invalid cluster config: %w", err)
+	}
+	if config.Kind != "Cluster" || config.APIVersion != kindConfigAPIVersion {
+		return fmt.Errorf("kind: invalid cluster config: unexpected kind %q and apiVersion %q, expected Cluster and %s", config.Kind, config.APIVersion, kindConfigAPIVersion)
+	}
+	return nil
+}
+
+// resolvePath returns the path resolved against the working directory configured using WithWorkingDir, the way
+// kind resolves it when executed in that directory
+func (k *Cluster) resolvePath(path string) string {
+	if k.workingDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(k.workingDir, path)
+}
+
+// withGeneratedConfig returns the kind create arguments updated to use a config file carrying the settings
+// configured using WithNetworking, WithContainerdConfigPatches, WithFeatureGates and WithRuntimeConfig. The config
+// file passed with --config, if any, is used as the base of the generated config file. The returned function
+// removes the generated file.
+func (k *Cluster) withGeneratedConfig(args []string) ([]string, func(), error) {
+	opts := k.configOptions()
+	if opts.empty() {
+		return args, func() {}, nil
+	}
+
+	configIndex, configPath, inline := configArg(args)
+	if configIndex >= 0 {
+		data, err := os.ReadFile(k.resolvePath(configPath))
+		if err != nil {
+			return nil, nil, fmt.Errorf("kind: read config file: %w", err)
+		}
+		opts.BaseConfig = string(data)
+	}
+
+	data, err := GenerateConfig(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.CreateTemp("", fmt.Sprintf("kind-config-%s-*.yaml", k.name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("kind: config file: %w", err)
+	}
+	_, err = file.WriteString(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, nil, fmt.Errorf("kind: config file: %w", err)
+	}
+	cleanup := func() {
+		if err := os.Remove(file.Name()); err != nil {
+			log.ErrorS(err, "failed to remove the generated kind config file", "path", file.Name())
+		}
+	}
+
+	updated := append([]string{}, args...)
+	switch {
+	case configIndex >= 0 && inline:
+		updated[configIndex] = "--config=" + file.Name()
+	case configIndex >= 0:
+		updated[configIndex] = file.Name()
+	default:
+		updated = append(updated, "--config", file.Name())
+	}
+	return updated, cleanup, nil
+}
+
+// configArg returns the index of the kind create argument holding the path of the config file along with the
+// path, which is passed either as --config <path> or as --config=<path>, in which case inline is true. The
+// index is -1 if no config file is passed.
+func configArg(args []string) (index int, path string, inline bool) {
+	for i, arg := range args {
+		if arg == "--config" && i < len(args)-1 {
+			return i + 1, args[i+1], false
+		}
+		if strings.HasPrefix(arg, "--config=") {
+			return i, strings.TrimPrefix(arg, "--config="), true
+		}
+	}
+	return -1, "", false
+}
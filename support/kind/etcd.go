@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+const (
+	etcdDataDir          = "/var/lib/etcd"
+	etcdSnapshotFile     = etcdDataDir + "/e2e-snapshot.db"
+	etcdRestoreDataDir   = etcdDataDir + "/e2e-restore"
+	staticPodManifestDir = "/etc/kubernetes/manifests"
+)
+
+// startControlPlaneCommand moves the etcd and kube-apiserver static pod manifests back to the manifest directory
+var startControlPlaneCommand = []string{"mv", "/etc/kubernetes/etcd.yaml", "/etc/kubernetes/kube-apiserver.yaml", staticPodManifestDir + "/"}
+
+// etcdctlTLSArgs are the arguments of etcdctl to connect to the etcd of the control plane node
+var etcdctlTLSArgs = []string{
+	"--endpoints=https://127.0.0.1:2379",
+	"--cacert=/etc/kubernetes/pki/etcd/ca.crt",
+	"--cert=/etc/kubernetes/pki/etcd/server.crt",
+	"--key=/etc/kubernetes/pki/etcd/server.key",
+}
+
+// controlPlaneNode returns the name of the container backing the kind control plane node
+func (k *Cluster) controlPlaneNode() string {
+	return fmt.Sprintf("%s-control-plane", k.name)
+}
+
+// execOnControlPlane runs the command with its arguments inside the control plane node container and
+// returns the trimmed output of the command. The arguments are passed as is, without going through a shell.
+func (k *Cluster) execOnControlPlane(ctx context.Context, command ...string) (string, error) {
+	res, err := k.runContainerRuntime(ctx, append([]string{"exec", k.controlPlaneNode()}, command...)...)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err, res.Output())
+	}
+	return strings.TrimSpace(res.Stdout), nil
+}
+
+// etcdContainerID returns the ID of the etcd container running on the control plane node
+func (k *Cluster) etcdContainerID(ctx context.Context) (string, error) {
+	out, err := k.execOnControlPlane(ctx, "crictl", "ps", "--name", "etcd", "-q")
+	if err != nil {
+		return "", err
+	}
+	id := strings.TrimSpace(strings.Split(out, "\n")[0])
+	if id == "" {
+		return "", fmt.Errorf("etcd container is not running on node %s", k.controlPlaneNode())
+	}
+	return id, nil
+}
+
+// SnapshotEtcd takes a snapshot of the etcd database of the kind cluster using etcdctl from within the
+// etcd container running on the control plane node and copies the snapshot to dest on the host.
+//
+// This can be combined with RestoreEtcd to reset the cluster to a known state between test scenarios
+// without having to recreate the whole cluster. Only single control plane clusters are supported.
+func (k *Cluster) SnapshotEtcd(ctx context.Context, dest string) error {
+	log.V(4).Info("Taking etcd snapshot of kind cluster ", k.name)
+	id, err := k.etcdContainerID(ctx)
+	if err != nil {
+		return fmt.Errorf("kind: etcd snapshot failed: %w", err)
+	}
+
+	save := append(append([]string{"crictl", "exec", id, "etcdctl"}, etcdctlTLSArgs...), "snapshot", "save", etcdSnapshotFile)
+	if _, err := k.execOnControlPlane(ctx, save...); err != nil {
+		return fmt.Errorf("kind: etcd snapshot failed: %w", err)
+	}
+
+	if res, err := k.runContainerRuntime(ctx, "cp", k.controlPlaneNode()+":"+etcdSnapshotFile, dest); err != nil {
+		return fmt.Errorf("kind: copy etcd snapshot to %s failed: %s: %s", dest, err, res.Output())
+	}
+
+	if _, err := k.execOnControlPlane(ctx, "rm", "-f", etcdSnapshotFile); err != nil {
+		log.ErrorS(err, "failed to remove the etcd snapshot from the control plane node")
+	}
+	return nil
+}
+
+// RestoreEtcd restores the etcd database of the kind cluster from a snapshot located at src on the host
+// that was previously taken using SnapshotEtcd.
+//
+// Restoring the snapshot requires the etcd and kube-apiserver static pods to be stopped while the etcd
+// data directory is replaced. The API server will be unavailable during that time and all the existing
+// connections and watches against it will be dropped. Once the API server is back, controllers will
+// reconcile the cluster against the restored state, so callers should wait for the control plane and
+// their workloads to become ready again (for instance using WaitForControlPlane) before continuing. If the
+// restore fails once the control plane has been stopped, the control plane is restarted before returning.
+func (k *Cluster) RestoreEtcd(ctx context.Context, src string) error {
+	log.V(4).Info("Restoring etcd snapshot of kind cluster ", k.name, " from ", src)
+	id, err := k.etcdContainerID(ctx)
+	if err != nil {
+		return fmt.Errorf("kind: etcd restore failed: %w", err)
+	}
+
+	if res, err := k.runContainerRuntime(ctx, "cp", src, k.controlPlaneNode()+":"+etcdSnapshotFile); err != nil {
+		return fmt.Errorf("kind: copy etcd snapshot from %s failed: %s: %s", src, err, res.Output())
+	}
+
+	// restore the snapshot into a temporary data directory while etcd is still running
+	for _, command := range [][]string{
+		{"rm", "-rf", etcdRestoreDataDir},
+		{"crictl", "exec", id, "etcdctl", "snapshot", "restore", etcdSnapshotFile, "--data-dir", etcdRestoreDataDir},
+	} {
+		if _, err := k.execOnControlPlane(ctx, command...); err != nil {
+			return fmt.Errorf("kind: etcd restore failed: %w", err)
+		}
+	}
+
+	// stop the etcd and kube-apiserver static pods by moving their manifests out of the manifest directory
+	if _, err := k.execOnControlPlane(ctx, "mv", staticPodManifestDir+"/etcd.yaml", staticPodManifestDir+"/kube-apiserver.yaml", "/etc/kubernetes/"); err != nil {
+		return fmt.Errorf("kind: failed to stop control plane: %w", err)
+	}
+	restarted := false
+	defer func() {
+		if restarted {
+			return
+		}
+		// bring the control plane back up whatever the state of the data directory, even when ctx is done, so
+		// that the cluster is not left without a control plane
+		rollbackCtx, cancel := context.WithTimeout(detachedContext{ctx}, 30*time.Second)
+		defer cancel()
+		if _, err := k.execOnControlPlane(rollbackCtx, startControlPlaneCommand...); err != nil {
+			log.ErrorS(err, "failed to restart the control plane after a failed etcd restore", "cluster", k.name)
+		}
+	}()
+	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	err = wait.For(func(ctx context.Context) (bool, error) {
+		out, err := k.execOnControlPlane(ctx, "crictl", "ps", "--name", "etcd|kube-apiserver", "-q")
+		return err == nil && out == "", nil
+	}, wait.WithContext(waitCtx), wait.WithInterval(time.Second))
+	if err != nil {
+		return fmt.Errorf("kind: timed out waiting for control plane to stop: %w", err)
+	}
+
+	// swap the data directory and bring the control plane back up
+	for _, command := range [][]string{
+		{"rm", "-rf", etcdDataDir + "/member"},
+		{"mv", etcdRestoreDataDir + "/member", etcdDataDir + "/member"},
+		{"rm", "-rf", etcdRestoreDataDir, etcdSnapshotFile},
+	} {
+		if _, err := k.execOnControlPlane(ctx, command...); err != nil {
+			return fmt.Errorf("kind: etcd restore failed: %w", err)
+		}
+	}
+	if _, err := k.execOnControlPlane(ctx, startControlPlaneCommand...); err != nil {
+		return fmt.Errorf("kind: etcd restore failed: %w", err)
+	}
+	restarted = true
+	return nil
+}
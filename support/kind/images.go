@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/support"
+)
+
+// nodeImages maps the kind releases to the node images, pinned by digest, they were built and tested with,
+// by Kubernetes version. The images come from the release notes of kind, a node image is only guaranteed to
+// work with the kind release it was published for.
+var nodeImages = map[string]map[string]string{
+	"v0.17.0": {
+		"v1.25.3":  "kindest/node:v1.25.3@sha256:f52781bc0d7a19fb6c405c2af83abfeb311f130707a0e219175677e366cc45d1",
+		"v1.24.7":  "kindest/node:v1.24.7@sha256:577c630ce8e509131eab1aea12c022190978dd2f745aac5eb1fe65c0807eb315",
+		"v1.23.13": "kindest/node:v1.23.13@sha256:ef453bb7c79f0e3caba88d2067d4196f427794086a7d0df8df4f019d5e336b61",
+		"v1.22.15": "kindest/node:v1.22.15@sha256:7d9708c4b0873f0fe2e171e2b1b7f45ae89482617778c1c875f1053d4cef2e41",
+		"v1.21.14": "kindest/node:v1.21.14@sha256:9d9eb5fb26b4fbc0c6d95fa8c790414f9750dd583f5d7cee45d92e8c26670aa1",
+		"v1.20.15": "kindest/node:v1.20.15@sha256:a32bf55309294120616886b5338f95dd98a2f7231519c7dedcec32ba29699394",
+		"v1.19.16": "kindest/node:v1.19.16@sha256:476cb3269232888437b61deca013832fee41f9f074f9bed79f57e4280f7c48b7",
+	},
+	"v0.20.0": {
+		"v1.27.3":  "kindest/node:v1.27.3@sha256:3966ac761ae0136263ffdb6cfd4db23ef8a83cba8a463690e98317add2c9ba72",
+		"v1.26.6":  "kindest/node:v1.26.6@sha256:6e2d8b28a5b601defe327b98bd1c2d1930b49e5d8c512e1895099e4504007adb",
+		"v1.25.11": "kindest/node:v1.25.11@sha256:227fa11ce74ea76a0474eeefb84cb75d8dad1b08638371ecf0e86259b35be0c8",
+		"v1.24.15": "kindest/node:v1.24.15@sha256:7db4f8bea3e14b82d12e044e25e34bd53754b7f2b0e9d56df21774e6f66a70ab",
+		"v1.23.17": "kindest/node:v1.23.17@sha256:59c989ff8a517a93127d4a536e7014d28e235fb3529d9fba91b3951d461edfdb",
+		"v1.22.17": "kindest/node:v1.22.17@sha256:f5b2e5698c6c9d6d0adc419c0deae21a425c07d81bbf3b6a6834042f25d4fba2",
+		"v1.21.14": "kindest/node:v1.21.14@sha256:8a4e9bb3f415d2bb81629ce33ef9c76ba514c14d707f9797a01e3216376ba093",
+	},
+}
+
+// WithKubernetesVersion configures the Kubernetes version of the cluster, such as "v1.25.3" or "1.25" for the
+// latest patch release available. The version is resolved to the node image, pinned by digest, published for
+// the kind release in use, which is the one configured using WithVersion or E2E_KIND_VERSION, or the default
+// one. Creating the cluster fails if the kind release has no node image for the version. The node image
+// configured using WithImage or WithImageDigest takes precedence over the resolved one.
+func WithKubernetesVersion(version string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.kubernetesVersion = version
+		}
+	}
+}
+
+// NodeImage returns the node image, pinned by digest, published for the kind release kindVersion for the
+// Kubernetes version, as resolved by WithKubernetesVersion.
+func NodeImage(kindVersion, kubernetesVersion string) (string, error) {
+	kindVersion = "v" + strings.TrimPrefix(kindVersion, "v")
+	images, ok := nodeImages[kindVersion]
+	if !ok {
+		supported := make([]string, 0, len(nodeImages))
+		for v := range nodeImages {
+			supported = append(supported, v)
+		}
+		sort.Strings(supported)
+		return "", fmt.Errorf("kind: no node image known for kind %s: supported kind versions are %s", kindVersion, strings.Join(supported, ", "))
+	}
+
+	version := "v" + strings.TrimPrefix(kubernetesVersion, "v")
+	if image, ok := images[version]; ok {
+		return image, nil
+	}
+	if strings.Count(version, ".") == 1 {
+		for v, image := range images {
+			if strings.HasPrefix(v, version+".") {
+				return image, nil
+			}
+		}
+	}
+	supported := make([]string, 0, len(images))
+	for v := range images {
+		supported = append(supported, v)
+	}
+	sort.Strings(supported)
+	return "", fmt.Errorf("kind: no node image for kubernetes %s with kind %s: supported versions are %s", kubernetesVersion, kindVersion, strings.Join(supported, ", "))
+}
+
+// resolveNodeImage sets the node image, along with its digest, for the Kubernetes version configured using
+// WithKubernetesVersion, unless a node image was configured explicitly
+func (k *Cluster) resolveNodeImage() error {
+	if k.kubernetesVersion == "" {
+		return nil
+	}
+	if k.image != "" {
+		log.V(4).Infof("Using node image %s configured explicitly instead of the one of kubernetes %s", k.image, k.kubernetesVersion)
+		return nil
+	}
+	k.resolveKind()
+	image, err := NodeImage(k.version, k.kubernetesVersion)
+	if err != nil {
+		return err
+	}
+	name, digest, _ := strings.Cut(image, "@")
+	k.image, k.imageDigest = name, digest
+	return nil
+}
+
+// hasArg returns true if the flag is part of the kind arguments, either as a separate argument or in the
+// flag=value form
+func hasArg(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
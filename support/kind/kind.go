@@ -19,12 +19,16 @@ package kind
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	log "k8s.io/klog/v2"
 	"sigs.k8s.io/e2e-framework/klient"
@@ -35,22 +39,63 @@ import (
 	"sigs.k8s.io/e2e-framework/support"
 	"sigs.k8s.io/e2e-framework/support/utils"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
 )
 
 var kindVersion = "v0.17.0"
 
+const (
+	// RuntimeDocker configures kind to use docker as the container runtime for the cluster nodes
+	RuntimeDocker = "docker"
+	// RuntimePodman configures kind to use podman as the container runtime for the cluster nodes
+	RuntimePodman = "podman"
+
+	kindProviderEnvVar = "KIND_EXPERIMENTAL_PROVIDER"
+
+	// kindPathEnvVar and kindVersionEnvVar configure the kind binary and the version of kind installed when it
+	// is not found, unless WithPath and WithVersion are used
+	kindPathEnvVar    = "E2E_KIND_PATH"
+	kindVersionEnvVar = "E2E_KIND_VERSION"
+)
+
 type Cluster struct {
-	path        string
-	name        string
-	kubecfgFile string
-	version     string
-	image       string
-	rc          *rest.Config
+	path                    string
+	name                    string
+	kubecfgFile             string
+	kubecfgFiles            []string
+	kubeContext             string
+	version                 string
+	pathSource              string
+	versionSource           string
+	image                   string
+	imageDigest             string
+	kubernetesVersion       string
+	runtime                 string
+	clientOpts              []klient.ConfigOption
+	networking              *networking
+	containerdConfigPatches []string
+	featureGates            map[string]bool
+	runtimeConfig           map[string]string
+	noInstall               bool
+	noLookup                bool
+	recreateExisting        bool
+	isolated                bool
+	isolatedKubecfgFile     string
+	metadata                map[string]string
+	runner                  utils.Runner
+	workingDir              string
+	rc                      *rest.Config
+	rcProvided              bool
 }
 
 // Enforce Type check always to avoid future breaks
-var _ support.E2EClusterProvider = &Cluster{}
+var (
+	_ support.E2EClusterProvider                 = &Cluster{}
+	_ support.E2EClusterProviderWithCapabilities = &Cluster{}
+	_ support.E2EClusterProviderWithKeep         = &Cluster{}
+)
 
 func NewCluster(name string) *Cluster {
 	return &Cluster{name: name}
@@ -60,6 +105,8 @@ func NewProvider() support.E2EClusterProvider {
 	return &Cluster{}
 }
 
+// WithImage configures the kind node image the cluster is created from, unless an image is passed to Create
+// using --image
 func WithImage(image string) support.ClusterOpts {
 	return func(c support.E2EClusterProvider) {
 		k, ok := c.(*Cluster)
@@ -69,19 +116,147 @@ func WithImage(image string) support.ClusterOpts {
 	}
 }
 
+// WithImageDigest configures the kind node image along with the sha256 digest it is expected to have. The
+// image is pulled and its digest is verified before the cluster is created, failing early with a clear
+// message if it does not match. The digest can be provided with or without the "sha256:" prefix.
+func WithImageDigest(image, sha256 string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.image = image
+			k.imageDigest = "sha256:" + strings.TrimPrefix(sha256, "sha256:")
+		}
+	}
+}
+
 func WithPath(path string) support.ClusterOpts {
 	return func(c support.E2EClusterProvider) {
 		k, ok := c.(*Cluster)
 		if ok {
 			k.path = path
+			k.pathSource = "WithPath"
 		}
 	}
 }
 
-func (k *Cluster) SetDefaults() support.E2EClusterProvider {
-	if k.path == "" {
-		k.path = "kind"
+// WithNoAutoInstall disables the automatic installation of kind using go install when the kind binary
+// cannot be found. The binary configured using WithPath, or kind on the PATH, is used as is and creating
+// the cluster fails with an error wrapping utils.ErrProviderNotFound if it is not available. This ensures
+// tests always run against a pinned, externally provisioned kind binary.
+func WithNoAutoInstall() support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.noInstall = true
+		}
 	}
+}
+
+// WithNoLookup disables looking up and installing the kind binary on the local machine. The binary configured
+// using WithPath, or kind, is passed as is to the Runner, which is meant for the Runners configured using
+// WithRunner that do not execute the binaries locally.
+func WithNoLookup() support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.noLookup = true
+		}
+	}
+}
+
+// WithRuntime configures the container runtime used by kind to run the cluster nodes. The supported values
+// are RuntimeDocker and RuntimePodman. The runtime is passed to every kind invocation using the
+// KIND_EXPERIMENTAL_PROVIDER environment variable. If not configured, kind uses its own default.
+func WithRuntime(runtime string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.runtime = runtime
+		}
+	}
+}
+
+// WithIsolatedKubeconfig makes every kind invocation use a kubeconfig file dedicated to the cluster, by
+// setting the KUBECONFIG environment variable of the kind process, instead of merging the context of the
+// cluster into the default kubeconfig of the user. This prevents clusters created in parallel, such as in
+// multi cluster tests, from changing the current context of each other or of the user. The file is a
+// temporary file created in the directory returned by os.TempDir, and is removed along with the other
+// kubeconfig files of the cluster.
+func WithIsolatedKubeconfig() support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.isolated = true
+		}
+	}
+}
+
+// WithKubeContext configures the name of the kubeconfig context of the cluster instead of kind-<name>. The context
+// of the kubeconfig file returned when the cluster is created is renamed accordingly, the REST config of the cluster
+// is built from that context and GetKubectlContext returns it, so that the context can be referred to by a name
+// chosen by the tests, such as when merging the kubeconfig files of several clusters.
+func WithKubeContext(name string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.kubeContext = name
+		}
+	}
+}
+
+// WithClientConfigOptions configures the options, such as klient.WithQPS, klient.WithBurst or
+// klient.WithUserAgent, applied to the rest configuration returned by KubernetesRestConfig.
+func WithClientConfigOptions(opts ...klient.ConfigOption) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.clientOpts = opts
+		}
+	}
+}
+
+// WithRunner configures the Runner executing the kind and container runtime commands of the cluster instead of
+// utils.ExecRunner. The kind binary is still looked up, and installed if needed, on the local machine, use
+// WithNoLookup along with a Runner that does not execute the binaries locally, such as a fake Runner testing the
+// commands constructed by the provider without creating actual clusters.
+func WithRunner(runner utils.Runner) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.runner = runner
+		}
+	}
+}
+
+// WithReuseExisting configures whether Create reuses an existing kind cluster with the same name, which is the
+// default, or deletes it and creates a fresh cluster in its place so that the tests never run against the state
+// left behind by a previous run.
+func WithReuseExisting(reuse bool) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.recreateExisting = !reuse
+		}
+	}
+}
+
+// WithWorkingDir configures the working directory the kind commands are executed in, so that the relative paths
+// passed to them, such as the path of the config file provided to CreateWithConfig or of an image archive, are
+// resolved against dir instead of the working directory of the test process.
+func WithWorkingDir(dir string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.workingDir = dir
+		}
+	}
+}
+
+// SetDefaults sets the path and the version of kind which are not configured using WithPath and WithVersion, from
+// the E2E_KIND_PATH and E2E_KIND_VERSION env vars when they are set, which pins the kind binary and version in a
+// CI pipeline without changing the tests, or to kind on the PATH and the default version otherwise.
+func (k *Cluster) SetDefaults() support.E2EClusterProvider {
+	k.resolveKind()
 	return k
 }
 
@@ -92,11 +267,23 @@ func (k *Cluster) WithName(name string) support.E2EClusterProvider {
 
 func (k *Cluster) WithPath(path string) support.E2EClusterProvider {
 	k.path = path
+	k.pathSource = "WithPath"
 	return k
 }
 
 func (k *Cluster) WithVersion(ver string) support.E2EClusterProvider {
 	k.version = ver
+	k.versionSource = "WithVersion"
+	return k
+}
+
+// WithRestConfig configures the REST config used to access the cluster instead of the one loaded from the
+// kubeconfig file of the cluster, for instance to use credentials issued by an external authority that a
+// static kubeconfig file cannot express. The options configured using WithClientConfigOptions are still
+// applied to it. KubernetesRestConfig returns this config, even before the cluster is created.
+func (k *Cluster) WithRestConfig(rc *rest.Config) support.E2EClusterProvider {
+	k.rc = rc
+	k.rcProvided = true
 	return k
 }
 
@@ -107,42 +294,76 @@ func (k *Cluster) WithOpts(opts ...support.ClusterOpts) support.E2EClusterProvid
 	return k
 }
 
-func (k *Cluster) getKubeconfig() (string, error) {
-	kubecfg := fmt.Sprintf("%s-kubecfg", k.name)
-
-	p := utils.RunCommand(fmt.Sprintf(`%s get kubeconfig --name %s`, k.path, k.name))
-	if p.Err() != nil {
-		return "", fmt.Errorf("kind get kubeconfig: %w", p.Err())
+func (k *Cluster) getKubeconfig(ctx context.Context) (string, error) {
+	res, err := k.runKind(ctx, "get", "kubeconfig", "--name", k.name)
+	if err != nil {
+		return "", fmt.Errorf("kind get kubeconfig: %w: %s", err, res.Output())
 	}
-
-	var stdout bytes.Buffer
-	if _, err := stdout.ReadFrom(p.Out()); err != nil {
-		return "", fmt.Errorf("kind kubeconfig stdout bytes: %w", err)
+	data := []byte(res.Stdout)
+	if k.kubeContext != "" {
+		if data, err = renameContext(data, fmt.Sprintf("kind-%s", k.name), k.kubeContext); err != nil {
+			return "", fmt.Errorf("kind kubeconfig file: %w", err)
+		}
 	}
+	stdout := bytes.NewReader(data)
 
-	file, err := os.CreateTemp("", fmt.Sprintf("kind-cluser-%s", kubecfg))
+	file, err := k.createKubeconfigFile()
 	if err != nil {
 		return "", fmt.Errorf("kind kubeconfig file: %w", err)
 	}
-	defer file.Close()
-
 	k.kubecfgFile = file.Name()
 
-	if n, err := io.Copy(file, &stdout); n == 0 || err != nil {
+	n, err := io.Copy(file, stdout)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if n == 0 || err != nil {
 		return "", fmt.Errorf("kind kubecfg file: bytes copied: %d: %w]", n, err)
 	}
 
 	return file.Name(), nil
 }
 
-func (k *Cluster) clusterExists(name string) (string, bool) {
-	clusters := utils.FetchCommandOutput(fmt.Sprintf("%s get clusters", k.path))
-	for _, c := range strings.Split(clusters, "\n") {
+// Exists reports whether the kind cluster exists, so that callers can decide whether to create it. An error is
+// returned when the kind clusters cannot be listed.
+func (k *Cluster) Exists(ctx context.Context) (bool, error) {
+	clusters, err := k.listClusters(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range clusters {
+		if c == k.name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// listClusters returns the names of the kind clusters, ignoring the blank lines and the surrounding whitespace
+// of the output of kind get clusters
+func (k *Cluster) listClusters(ctx context.Context) ([]string, error) {
+	res, err := k.runKind(ctx, "get", "clusters")
+	if err != nil {
+		return nil, fmt.Errorf("kind: list clusters: %s: %s", err, res.Output())
+	}
+	var clusters []string
+	for _, line := range strings.Split(res.Stdout, "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			clusters = append(clusters, name)
+		}
+	}
+	return clusters, nil
+}
+
+// clusterExists returns the list of the kind clusters and whether the named cluster is one of them
+func (k *Cluster) clusterExists(ctx context.Context, name string) (string, bool) {
+	clusters, _ := k.listClusters(ctx)
+	for _, c := range clusters {
 		if c == name {
-			return clusters, true
+			return strings.Join(clusters, "\n"), true
 		}
 	}
-	return clusters, false
+	return strings.Join(clusters, "\n"), false
 }
 
 func (k *Cluster) CreateWithConfig(ctx context.Context, kindConfigFile string) (string, error) {
@@ -158,38 +379,66 @@ func (k *Cluster) CreateWithConfig(ctx context.Context, kindConfigFile string) (
 
 func (k *Cluster) Create(ctx context.Context, args ...string) (string, error) {
 	log.V(4).Info("Creating kind cluster ", k.name)
+	if err := k.validateRuntime(); err != nil {
+		return "", err
+	}
+	if err := k.configOptions().validate(); err != nil {
+		return "", err
+	}
+	if err := k.resolveNodeImage(); err != nil {
+		return "", err
+	}
+	if k.image != "" && !hasArg(args, "--image") {
+		args = append(args, "--image", k.image)
+	}
 	if err := k.findOrInstallKind(); err != nil {
 		return "", err
 	}
 
-	if _, ok := k.clusterExists(k.name); ok {
-		log.V(4).Info("Skipping Kind Cluster.Create: cluster already created: ", k.name)
-		return k.getKubeconfig()
+	if _, ok := k.clusterExists(ctx, k.name); ok {
+		if !k.recreateExisting {
+			log.Infof("Reusing existing kind cluster %s, its state is left as is", k.name)
+			kConfig, err := k.getKubeconfig(ctx)
+			if err != nil {
+				return "", err
+			}
+			return kConfig, k.initKubernetesAccessClients()
+		}
+		log.Infof("Deleting existing kind cluster %s to create a fresh one", k.name)
+		if res, err := k.runKind(ctx, "delete", "cluster", "--name", k.name); err != nil {
+			return "", fmt.Errorf("kind: delete existing cluster %v failed: %s: %s", k.name, err, res.Output())
+		}
+		k.removeMetadata(ctx)
+	}
+
+	if err := k.verifyImageDigest(ctx); err != nil {
+		return "", err
 	}
 
-	command := fmt.Sprintf(`%s create cluster --name %s`, k.path, k.name)
-	if len(args) > 0 {
-		command = fmt.Sprintf("%s %s", command, strings.Join(args, " "))
+	args, cleanup, err := k.withGeneratedConfig(args)
+	if err != nil {
+		return "", err
 	}
-	log.V(4).Info("Launching:", command)
-	p := utils.RunCommand(command)
-	if p.Err() != nil {
+	defer cleanup()
+
+	args = append([]string{"create", "cluster", "--name", k.name}, args...)
+	log.V(4).Info("Launching: ", k.path, " ", strings.Join(args, " "))
+	res, err := k.runKind(ctx, args...)
+	if err != nil {
 		// Print the output data as well so that it can be useful to debug cluster bringup failures
-		var data []byte
-		b := bytes.NewBuffer(data)
-		_, err := io.Copy(b, p.Out())
-		if err != nil {
-			log.ErrorS(err, "failed to read data from the kind create process output due to an error")
-		}
-		return "", fmt.Errorf("failed to create kind cluster: %s : %s: %s", p.Err(), p.Result(), b.String())
+		return "", fmt.Errorf("failed to create kind cluster: %s : %s", err, res.Output())
 	}
-	clusters, ok := k.clusterExists(k.name)
+	clusters, ok := k.clusterExists(ctx, k.name)
 	if !ok {
 		return "", fmt.Errorf("kind Cluster.Create: cluster %v still not in 'cluster list' after creation: %v", k.name, clusters)
 	}
 	log.V(4).Info("kind clusters available: ", clusters)
 
-	kConfig, err := k.getKubeconfig()
+	if err := k.storeMetadata(ctx); err != nil {
+		return "", err
+	}
+
+	kConfig, err := k.getKubeconfig(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -197,7 +446,13 @@ func (k *Cluster) Create(ctx context.Context, args ...string) (string, error) {
 }
 
 func (k *Cluster) initKubernetesAccessClients() error {
+	if k.rcProvided {
+		return nil
+	}
 	cfg, err := conf.New(k.kubecfgFile)
+	if k.kubeContext != "" {
+		cfg, err = conf.NewWithContextName(k.kubecfgFile, k.kubeContext)
+	}
 	if err != nil {
 		return err
 	}
@@ -209,10 +464,46 @@ func (k *Cluster) GetKubeconfig() string {
 	return k.kubecfgFile
 }
 
+// GetKubectlContext returns the name of the kubeconfig context of the cluster, which is the one configured using
+// WithKubeContext or kind-<name> by default
 func (k *Cluster) GetKubectlContext() string {
+	if k.kubeContext != "" {
+		return k.kubeContext
+	}
 	return fmt.Sprintf("kind-%s", k.name)
 }
 
+// Capabilities reports what the kind cluster supports. kind clusters have no cloud provider, so LoadBalancer
+// support is only reported when MetalLB, or another controller assigning external addresses, has been installed.
+func (k *Cluster) Capabilities(ctx context.Context) (support.Capabilities, error) {
+	if k.rc == nil {
+		return support.Capabilities{}, fmt.Errorf("kind: cluster %s has not been created", k.name)
+	}
+	clientset, err := kubernetes.NewForConfig(k.KubernetesRestConfig())
+	if err != nil {
+		return support.Capabilities{}, fmt.Errorf("kind: capabilities: %w", err)
+	}
+	caps, err := support.DiscoverCapabilities(ctx, clientset)
+	caps.Provider = "kind"
+	return caps, err
+}
+
+// GetControlPlaneIP returns the IP address of the control plane node container on the network of the container
+// runtime it is attached to, which is the address at which other containers attached to the same network, such
+// as a sibling container of a CI job, reach the API server on port 6443 and the NodePort services of the cluster.
+func (k *Cluster) GetControlPlaneIP(ctx context.Context) (string, error) {
+	node := k.controlPlaneNode()
+	res, err := k.runContainerRuntime(ctx, "inspect", node, "--format", "{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}")
+	if err != nil {
+		return "", fmt.Errorf("kind: control plane node container %s of cluster %s not found: %s: %s", node, k.name, err, res.Output())
+	}
+	ips := strings.Fields(res.Stdout)
+	if len(ips) == 0 {
+		return "", fmt.Errorf("kind: control plane node container %s of cluster %s has no IP address", node, k.name)
+	}
+	return ips[0], nil
+}
+
 // ExportLogs export all cluster logs to the provided path.
 func (k *Cluster) ExportLogs(ctx context.Context, dest string) error {
 	log.V(4).Info("Exporting kind cluster logs to ", dest)
@@ -220,9 +511,8 @@ func (k *Cluster) ExportLogs(ctx context.Context, dest string) error {
 		return err
 	}
 
-	p := utils.RunCommand(fmt.Sprintf(`%s export logs %s --name %s`, k.path, dest, k.name))
-	if p.Err() != nil {
-		return fmt.Errorf("kind: export cluster %v logs failed: %s: %s", k.name, p.Err(), p.Result())
+	if res, err := k.runKind(ctx, "export", "logs", dest, "--name", k.name); err != nil {
+		return fmt.Errorf("kind: export cluster %v logs failed: %s: %s", k.name, err, res.Output())
 	}
 
 	return nil
@@ -234,42 +524,266 @@ func (k *Cluster) Destroy(ctx context.Context) error {
 		return err
 	}
 
-	p := utils.RunCommand(fmt.Sprintf(`%s delete cluster --name %s`, k.path, k.name))
-	if p.Err() != nil {
-		return fmt.Errorf("kind: delete cluster %v failed: %s: %s", k.name, p.Err(), p.Result())
+	if res, err := k.runKind(ctx, "delete", "cluster", "--name", k.name); err != nil {
+		return fmt.Errorf("kind: delete cluster %v failed: %s: %s", k.name, err, res.Output())
 	}
 
-	log.V(4).Info("Removing kubeconfig file ", k.kubecfgFile)
-	if err := os.RemoveAll(k.kubecfgFile); err != nil {
-		return fmt.Errorf("kind: remove kubefconfig %v failed: %w", k.kubecfgFile, err)
+	k.removeMetadata(ctx)
+
+	if err := k.removeKubeconfigFiles(); err != nil {
+		return err
 	}
 
+	k.Reset()
 	return nil
 }
 
+// Reset clears the state tied to the cluster that was last created, such as its kubeconfig file and
+// REST config, unless the latter was configured using WithRestConfig, while keeping the configuration of the
+// Cluster (name, path, version, image, ...) intact.
+// This is invoked by Destroy so that the same Cluster can be used to create a new cluster afterwards.
+func (k *Cluster) Reset() {
+	k.kubecfgFile = ""
+	k.isolatedKubecfgFile = ""
+	if !k.rcProvided {
+		k.rc = nil
+	}
+}
+
+// resolveKind sets the path and the version of kind which are not configured yet, preferring the E2E_KIND_PATH and
+// E2E_KIND_VERSION env vars over kind on the PATH and the default version
+func (k *Cluster) resolveKind() {
+	if k.path == "" {
+		k.path, k.pathSource = "kind", "default"
+		if path := os.Getenv(kindPathEnvVar); path != "" {
+			k.path, k.pathSource = path, kindPathEnvVar
+		}
+	}
+	if k.version == "" {
+		k.version, k.versionSource = kindVersion, "default"
+		if version := os.Getenv(kindVersionEnvVar); version != "" {
+			k.version, k.versionSource = version, kindVersionEnvVar
+		}
+	}
+}
+
 func (k *Cluster) findOrInstallKind() error {
-	if k.version != "" {
-		kindVersion = k.version
+	k.resolveKind()
+	log.V(2).InfoS("Using kind", "path", k.path, "pathSource", k.pathSource, "version", k.version, "versionSource", k.versionSource)
+	if k.noLookup {
+		return nil
+	}
+	if k.noInstall {
+		_, err := utils.FindProvider(k.path)
+		return err
 	}
-	path, err := utils.FindOrInstallGoBasedProvider(k.path, "kind", "sigs.k8s.io/kind", kindVersion)
+	path, err := utils.FindOrInstallGoBasedProvider(k.path, "kind", "sigs.k8s.io/kind", k.version)
 	if path != "" {
 		k.path = path
 	}
 	return err
 }
 
+// validateRuntime checks that the container runtime configured using WithRuntime is supported
+func (k *Cluster) validateRuntime() error {
+	switch k.runtime {
+	case "", RuntimeDocker, RuntimePodman:
+		return nil
+	default:
+		return fmt.Errorf("kind: unsupported runtime %q: must be one of %q or %q", k.runtime, RuntimeDocker, RuntimePodman)
+	}
+}
+
+// verifyImageDigest pulls the node image configured using WithImageDigest and checks that its digest
+// matches the expected one. This is a no-op if no digest was configured.
+func (k *Cluster) verifyImageDigest(ctx context.Context) error {
+	if k.imageDigest == "" {
+		return nil
+	}
+	log.V(4).Info("Verifying digest of kind node image ", k.image)
+	if res, err := k.runContainerRuntime(ctx, "pull", k.image); err != nil {
+		return fmt.Errorf("kind: failed to pull node image %s: %s: %s", k.image, err, res.Output())
+	}
+
+	res, err := k.runContainerRuntime(ctx, "image", "inspect", "--format", "{{json .RepoDigests}}", k.image)
+	if err != nil {
+		return fmt.Errorf("kind: failed to inspect node image %s: %s: %s", k.image, err, res.Output())
+	}
+	var repoDigests []string
+	if err := json.Unmarshal([]byte(res.Stdout), &repoDigests); err != nil {
+		return fmt.Errorf("kind: failed to read digests of node image %s: %w", k.image, err)
+	}
+	for _, d := range repoDigests {
+		if strings.HasSuffix(d, "@"+k.imageDigest) {
+			return nil
+		}
+	}
+	return fmt.Errorf("kind: node image %s digest mismatch: expected %s, found %v", k.image, k.imageDigest, repoDigests)
+}
+
+// ContainerRuntime returns the container runtime running the cluster nodes, as configured using WithRuntime, whose
+// CLI is used to interact with the nodes
+func (k *Cluster) ContainerRuntime() string {
+	if k.runtime == "" {
+		return RuntimeDocker
+	}
+	return k.runtime
+}
+
+// isolatedKubeconfig returns the path of the kubeconfig file kind writes the context of the cluster to
+// when WithIsolatedKubeconfig is used. The file is created on first use and then used by all the
+// invocations targeting the cluster until it is destroyed.
+func (k *Cluster) isolatedKubeconfig() (string, error) {
+	if k.isolatedKubecfgFile == "" {
+		file, err := k.createKubeconfigFile()
+		if err != nil {
+			return "", fmt.Errorf("kind: isolated kubeconfig file: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return "", fmt.Errorf("kind: isolated kubeconfig file: %w", err)
+		}
+		k.isolatedKubecfgFile = file.Name()
+	}
+	return k.isolatedKubecfgFile, nil
+}
+
+// run executes the command using the Runner configured with WithRunner or utils.ExecRunner
+func (k *Cluster) run(ctx context.Context, path string, args ...string) (utils.Result, error) {
+	if k.runner != nil {
+		return k.runner.Run(ctx, path, args...)
+	}
+	return utils.ExecRunner{}.Run(ctx, path, args...)
+}
+
+// runKind runs kind with the environment required to use the configured container runtime
+// and, if WithIsolatedKubeconfig is used, the kubeconfig file dedicated to the cluster
+func (k *Cluster) runKind(ctx context.Context, args ...string) (utils.Result, error) {
+	var env []string
+	if k.runtime != "" {
+		env = append(env, fmt.Sprintf("%s=%s", kindProviderEnvVar, k.runtime))
+	}
+	if k.isolated {
+		kubeconfig, err := k.isolatedKubeconfig()
+		if err != nil {
+			return utils.Result{}, err
+		}
+		env = append(env, fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
+	}
+	path := k.path
+	if path == "" {
+		path = "kind"
+	}
+	ctx = utils.ContextWithWorkingDir(utils.ContextWithEnv(ctx, env...), k.workingDir)
+	return k.run(ctx, path, args...)
+}
+
+// runContainerRuntime runs the CLI of the container runtime used by kind
+func (k *Cluster) runContainerRuntime(ctx context.Context, args ...string) (utils.Result, error) {
+	return k.run(ctx, k.ContainerRuntime(), args...)
+}
+
+// LoadImage loads the image into the cluster nodes. The image can either be a reference to an image present
+// in the image store of the container runtime used by kind or the path to an image archive file, in which case
+// it is loaded the same way LoadImageArchive does. Images of the podman image store are exported to a temporary
+// archive first as kind can only load images from the docker image store directly.
+//
+// Concurrent loads of the same image into the same cluster, such as the ones of features running in parallel,
+// are coalesced into a single load whose result, including its error, is shared by all the callers. A caller
+// stops waiting when its context is done, which does not cancel the load shared with the other callers.
 func (k *Cluster) LoadImage(ctx context.Context, image string) error {
-	p := utils.RunCommand(fmt.Sprintf(`%s load docker-image --name %s %s`, k.path, k.name, image))
-	if p.Err() != nil {
-		return fmt.Errorf("kind: load docker-image %v failed: %s: %s", image, p.Err(), p.Result())
+	key := fmt.Sprintf("%s/%s/%s", k.ContainerRuntime(), k.name, image)
+	return imageLoads.do(ctx, key, func(ctx context.Context) error {
+		return k.loadImage(ctx, image)
+	})
+}
+
+func (k *Cluster) loadImage(ctx context.Context, image string) error {
+	if info, err := os.Stat(k.resolvePath(image)); err == nil && info.Mode().IsRegular() {
+		return k.LoadImageArchive(ctx, image)
+	}
+
+	runtime := k.ContainerRuntime()
+	if res, err := k.runContainerRuntime(ctx, "image", "inspect", image); err != nil {
+		return fmt.Errorf("kind: image %s not found in the %s image store, it must be pulled or built before being loaded: %s", image, runtime, res.Output())
+	}
+
+	if runtime == RuntimePodman {
+		return k.loadImageThroughArchive(ctx, image)
+	}
+	if res, err := k.runKind(ctx, "load", "docker-image", "--name", k.name, image); err != nil {
+		return fmt.Errorf("kind: load docker-image %v failed: %s: %s", image, err, res.Output())
 	}
 	return nil
 }
 
+// loadImageThroughArchive exports the image from the image store of the container runtime to a temporary
+// archive that is then loaded into the cluster nodes
+func (k *Cluster) loadImageThroughArchive(ctx context.Context, image string) error {
+	dir, err := os.MkdirTemp("", "kind-image-")
+	if err != nil {
+		return fmt.Errorf("kind: load image %s failed: %w", image, err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "image.tar")
+	if res, err := k.runContainerRuntime(ctx, "save", "-o", archive, image); err != nil {
+		return fmt.Errorf("kind: save image %s failed: %s: %s", image, err, res.Output())
+	}
+	return k.LoadImageArchive(ctx, archive)
+}
+
 func (k *Cluster) LoadImageArchive(ctx context.Context, imageArchive string) error {
-	p := utils.RunCommand(fmt.Sprintf(`%s load image-archive --name %s %s`, k.path, k.name, imageArchive))
-	if p.Err() != nil {
-		return fmt.Errorf("kind: load image-archive %v failed: %s: %s", imageArchive, p.Err(), p.Result())
+	if res, err := k.runKind(ctx, "load", "image-archive", "--name", k.name, imageArchive); err != nil {
+		return fmt.Errorf("kind: load image-archive %v failed: %s: %s", imageArchive, err, res.Output())
+	}
+	return nil
+}
+
+// WaitForReady waits until the cluster is usable by tests, which is the gate most users should rely upon
+// after creating a cluster. It checks, in order, that the API server reports itself as healthy, that all
+// the nodes are Ready and that the CoreDNS deployment is Available. The checks are polled every second by
+// default and the options provided, such as wait.WithTimeout, wait.WithInterval or wait.WithJitter, apply
+// to the whole sequence rather than to each check. Without wait.WithTimeout, the default timeout of the
+// klient/wait package, or the deadline of ctx if any, applies.
+//
+// WaitForControlPlane is the lower level primitive invoked as part of the cluster creation workflow that
+// only checks that the control plane and the system pods are running. A cluster can pass that check
+// while its nodes are not Ready yet or while DNS resolution is not available.
+func (k *Cluster) WaitForReady(ctx context.Context, client klient.Client, opts ...wait.Option) error {
+	r, err := resources.New(client.RESTConfig())
+	if err != nil {
+		return err
+	}
+	cond := conditions.New(r)
+	coreDNSAvailable := cond.DeploymentAvailable("coredns", "kube-system")
+	checks := []struct {
+		name      string
+		condition apimachinerywait.ConditionWithContextFunc
+	}{
+		{name: "api server healthy", condition: cond.APIServerReady()},
+		{name: "nodes ready", condition: cond.NodesReady()},
+		{name: "coredns available", condition: func(ctx context.Context) (bool, error) {
+			done, err := coreDNSAvailable(ctx)
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return done, err
+		}},
+	}
+
+	// the checks are passed in order, the next one is checked right away once the current one passes
+	current := 0
+	err = wait.ForFunc(ctx, func(ctx context.Context) (bool, error) {
+		for ; current < len(checks); current++ {
+			done, err := checks[current].condition(ctx)
+			if err != nil || !done {
+				return false, err
+			}
+		}
+		return true, nil
+	}, append([]wait.Option{wait.WithInterval(time.Second), wait.WithImmediate()}, opts...)...)
+	if err != nil {
+		return fmt.Errorf("kind: cluster %s not ready: waiting for %s: %w", k.name, checks[current].name, err)
 	}
 	return nil
 }
@@ -279,6 +793,9 @@ func (k *Cluster) WaitForControlPlane(ctx context.Context, client klient.Client)
 	if err != nil {
 		return err
 	}
+	if err := wait.For(conditions.New(r).APIServerReady(), wait.WithImmediate()); err != nil {
+		return err
+	}
 	for _, sl := range []metav1.LabelSelectorRequirement{
 		{Key: "component", Operator: metav1.LabelSelectorOpIn, Values: []string{"etcd", "kube-apiserver", "kube-controller-manager", "kube-scheduler"}},
 		{Key: "k8s-app", Operator: metav1.LabelSelectorOpIn, Values: []string{"kindnet", "kube-dns", "kube-proxy"}},
@@ -302,5 +819,8 @@ func (k *Cluster) WaitForControlPlane(ctx context.Context, client klient.Client)
 }
 
 func (k *Cluster) KubernetesRestConfig() *rest.Config {
-	return k.rc
+	if k.rc == nil {
+		return nil
+	}
+	return klient.ApplyConfigOptions(k.rc, k.clientOpts...)
 }
@@ -0,0 +1,1153 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/support/utils"
+	"sigs.k8s.io/yaml"
+)
+
+const fakeKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kind-test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: kind-test
+  context:
+    cluster: kind-test
+    user: kind-test
+current-context: kind-test
+users:
+- name: kind-test
+  user:
+    token: fake
+`
+
+// fakeRunner records the commands it is asked to run and returns the results configured for them. When
+// several results are configured for a command, they are returned in order and the last one is repeated.
+type fakeRunner struct {
+	commands []string
+	args     [][]string
+	env      [][]string
+	dirs     []string
+	results  map[string][]utils.Result
+	errors   map[string]error
+}
+
+func (f *fakeRunner) Run(ctx context.Context, path string, args ...string) (utils.Result, error) {
+	command := strings.Join(append([]string{path}, args...), " ")
+	f.commands = append(f.commands, command)
+	f.args = append(f.args, args)
+	f.env = append(f.env, utils.EnvFromContext(ctx))
+	f.dirs = append(f.dirs, utils.WorkingDirFromContext(ctx))
+	var result utils.Result
+	if results := f.results[command]; len(results) > 0 {
+		result = results[0]
+		if len(results) > 1 {
+			f.results[command] = results[1:]
+		}
+	}
+	return result, f.errors[command]
+}
+
+func TestCluster_CreateAndDestroy(t *testing.T) {
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		// the cluster only shows up once it has been created
+		"kind get clusters":               {{Stdout: "other\n"}, {Stdout: "other\ntest\n"}},
+		"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithRuntime(RuntimePodman), WithMetadata(map[string]string{"build-id": "42"}))
+
+	kubeconfig, err := cluster.Create(context.TODO(), "--image", "kindest/node:v1.28.0")
+	if err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+	if kubeconfig == "" || cluster.KubernetesRestConfig() == nil {
+		t.Error("expected the kubeconfig and the REST config of the cluster to be set")
+	}
+	if err := cluster.Destroy(context.TODO()); err != nil {
+		t.Fatalf("unexpected error destroying cluster: %s", err)
+	}
+
+	expected := []string{
+		"kind get clusters",
+		"kind create cluster --name test --image kindest/node:v1.28.0",
+		"kind get clusters",
+		"podman volume create --label io.x-k8s.kind.cluster=test --label e2e-framework.sigs.k8s.io/build-id=42 e2e-framework-kind-metadata-test",
+		"kind get kubeconfig --name test",
+		"kind delete cluster --name test",
+		"podman volume rm e2e-framework-kind-metadata-test",
+	}
+	if !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("unexpected commands:\n%s\nexpected:\n%s", strings.Join(runner.commands, "\n"), strings.Join(expected, "\n"))
+	}
+	for i, command := range runner.commands {
+		env := runner.env[i]
+		if strings.HasPrefix(command, "kind ") && !reflect.DeepEqual(env, []string{kindProviderEnvVar + "=podman"}) {
+			t.Errorf("unexpected environment for %q: %v", command, env)
+		}
+		if strings.HasPrefix(command, "podman ") && len(env) != 0 {
+			t.Errorf("unexpected environment for %q: %v", command, env)
+		}
+	}
+}
+
+func TestCluster_CreateRecreatesExisting(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"kind get clusters":               {{Stdout: "test\n"}},
+		"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithReuseExisting(false))
+
+	if _, err := cluster.Create(context.TODO()); err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+	expected := []string{
+		"kind get clusters",
+		"kind delete cluster --name test",
+		"docker volume rm e2e-framework-kind-metadata-test",
+		"kind create cluster --name test",
+	}
+	if len(runner.commands) < len(expected) || !reflect.DeepEqual(runner.commands[:len(expected)], expected) {
+		t.Errorf("unexpected commands:\n%s\nexpected to start with:\n%s", strings.Join(runner.commands, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestCluster_KubernetesVersion(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	digest := "sha256:3966ac761ae0136263ffdb6cfd4db23ef8a83cba8a463690e98317add2c9ba72"
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"kind get clusters": {{Stdout: "other\n"}, {Stdout: "other\ntest\n"}},
+		"docker image inspect --format {{json .RepoDigests}} kindest/node:v1.27.3": {{Stdout: `["kindest/node@` + digest + `"]`}},
+		"kind get kubeconfig --name test":                                          {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithVersion("v0.20.0")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithKubernetesVersion("1.27"))
+
+	if _, err := cluster.Create(context.TODO()); err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+	expected := []string{
+		"kind get clusters",
+		"docker pull kindest/node:v1.27.3",
+		"docker image inspect --format {{json .RepoDigests}} kindest/node:v1.27.3",
+		"kind create cluster --name test --image kindest/node:v1.27.3",
+	}
+	if len(runner.commands) < len(expected) || !reflect.DeepEqual(runner.commands[:len(expected)], expected) {
+		t.Errorf("unexpected commands:\n%s\nexpected to start with:\n%s", strings.Join(runner.commands, "\n"), strings.Join(expected, "\n"))
+	}
+
+	cluster = NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(&fakeRunner{}), WithKubernetesVersion("v1.10.0"))
+	if _, err := cluster.Create(context.TODO()); err == nil || !strings.Contains(err.Error(), "supported versions are") {
+		t.Errorf("expected an unsupported kubernetes version to be rejected, got: %v", err)
+	}
+}
+
+func TestNodeImage(t *testing.T) {
+	tests := []struct {
+		kindVersion       string
+		kubernetesVersion string
+		image             string
+		wantErr           bool
+	}{
+		{kindVersion: "v0.17.0", kubernetesVersion: "v1.25.3", image: "kindest/node:v1.25.3@sha256:f52781bc0d7a19fb6c405c2af83abfeb311f130707a0e219175677e366cc45d1"},
+		{kindVersion: "0.20.0", kubernetesVersion: "1.26", image: "kindest/node:v1.26.6@sha256:6e2d8b28a5b601defe327b98bd1c2d1930b49e5d8c512e1895099e4504007adb"},
+		{kindVersion: "v0.17.0", kubernetesVersion: "1.2", wantErr: true},
+		{kindVersion: "v0.17.0", kubernetesVersion: "v1.27.3", wantErr: true},
+		{kindVersion: "v0.1.0", kubernetesVersion: "v1.25.3", wantErr: true},
+	}
+	for _, test := range tests {
+		image, err := NodeImage(test.kindVersion, test.kubernetesVersion)
+		if (err != nil) != test.wantErr || image != test.image {
+			t.Errorf("NodeImage(%q, %q) = %q, %v: expected %q, error %t", test.kindVersion, test.kubernetesVersion, image, err, test.image, test.wantErr)
+		}
+	}
+}
+
+func TestCluster_Exists(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		exists bool
+	}{
+		{name: "listed", output: "kind\ntest\n", exists: true},
+		{name: "listed last without trailing newline", output: "kind\ntest", exists: true},
+		{name: "trailing whitespace", output: "kind\r\ntest  \r\n\n", exists: true},
+		{name: "not listed", output: "kind\ntesting\n"},
+		{name: "no clusters", output: "\n"},
+		{name: "kind failure", err: errors.New("exit status 1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{
+				results: map[string][]utils.Result{"kind get clusters": {{Stdout: tt.output}}},
+				errors:  map[string]error{"kind get clusters": tt.err},
+			}
+			cluster := NewCluster("test")
+			cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+			exists, err := cluster.Exists(context.TODO())
+			if (err != nil) != (tt.err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exists != tt.exists {
+				t.Errorf("expected exists to be %t, got %t", tt.exists, exists)
+			}
+		})
+	}
+
+	// an empty output must not be taken for a cluster with an empty name
+	cluster := NewCluster("")
+	cluster.WithOpts(WithNoLookup(), WithRunner(&fakeRunner{results: map[string][]utils.Result{"kind get clusters": {{Stdout: "\n"}}}}))
+	if exists, err := cluster.Exists(context.TODO()); err != nil || exists {
+		t.Errorf("expected no cluster to be found, got %t: %v", exists, err)
+	}
+}
+
+func TestCluster_CreateFailure(t *testing.T) {
+	runner := &fakeRunner{
+		results: map[string][]utils.Result{"kind create cluster --name test": {{Stderr: "port is already allocated", ExitCode: 1}}},
+		errors:  map[string]error{"kind create cluster --name test": fmt.Errorf("exit status 1")},
+	}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+
+	_, err := cluster.Create(context.TODO())
+	if err == nil || !strings.Contains(err.Error(), "port is already allocated") {
+		t.Fatalf("expected the error to report the output of kind, got: %v", err)
+	}
+}
+
+func TestCluster_Runtime(t *testing.T) {
+	tests := []struct {
+		name    string
+		runtime string
+		env     []string
+		cli     string
+	}{
+		{name: "default", cli: "docker"},
+		{name: "docker", runtime: RuntimeDocker, env: []string{kindProviderEnvVar + "=docker"}, cli: "docker"},
+		{name: "podman", runtime: RuntimePodman, env: []string{kindProviderEnvVar + "=podman"}, cli: "podman"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := &fakeRunner{results: map[string][]utils.Result{
+				test.cli + " exec test-control-plane crictl ps --name etcd -q": {{Stdout: "abc123\n"}},
+			}}
+			cluster := NewCluster("test")
+			cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithRuntime(test.runtime))
+			if err := cluster.ExportLogs(context.TODO(), "/tmp/logs"); err != nil {
+				t.Fatalf("unexpected error exporting logs: %s", err)
+			}
+			if err := cluster.SnapshotEtcd(context.TODO(), "/tmp/snapshot.db"); err != nil {
+				t.Fatalf("unexpected error taking etcd snapshot: %s", err)
+			}
+			if runner.commands[0] != "kind export logs /tmp/logs --name test" || !reflect.DeepEqual(runner.env[0], test.env) {
+				t.Errorf("expected kind to be run with the environment %v, got %q with %v", test.env, runner.commands[0], runner.env[0])
+			}
+			for _, command := range runner.commands[1:] {
+				if !strings.HasPrefix(command, test.cli+" ") {
+					t.Errorf("expected the nodes to be reached using %s, got: %s", test.cli, command)
+				}
+			}
+		})
+	}
+}
+
+func TestCluster_UnsupportedRuntime(t *testing.T) {
+	runner := &fakeRunner{}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithRuntime("containerd"))
+	_, err := cluster.Create(context.TODO())
+	if err == nil || !strings.Contains(err.Error(), `unsupported runtime "containerd"`) {
+		t.Errorf("expected the unsupported runtime to be rejected, got: %v", err)
+	}
+	if len(runner.commands) != 0 {
+		t.Errorf("expected no command to be run, got:\n%s", strings.Join(runner.commands, "\n"))
+	}
+}
+
+func TestCluster_ImageDigest(t *testing.T) {
+	const (
+		image   = "kindest/node:v1.28.0"
+		digest  = "sha256:b7e1cf6b2b729f604133c667a6be8aab6f4dde5bb042c1891ae248d9154f665b"
+		inspect = "docker image inspect --format {{json .RepoDigests}} " + image
+	)
+	tests := []struct {
+		name    string
+		digest  string
+		results map[string][]utils.Result
+		errors  map[string]error
+		err     string
+	}{
+		{
+			name:    "matching digest",
+			digest:  digest,
+			results: map[string][]utils.Result{inspect: {{Stdout: `["docker.io/kindest/node@` + digest + `"]`}}},
+		},
+		{
+			name:    "matching digest without prefix",
+			digest:  strings.TrimPrefix(digest, "sha256:"),
+			results: map[string][]utils.Result{inspect: {{Stdout: `["docker.io/kindest/node@` + digest + `"]`}}},
+		},
+		{
+			name:    "mismatching digest",
+			digest:  digest,
+			results: map[string][]utils.Result{inspect: {{Stdout: `["docker.io/kindest/node@sha256:0000"]`}}},
+			err:     "node image kindest/node:v1.28.0 digest mismatch",
+		},
+		{
+			name:   "pull failure",
+			digest: digest,
+			errors: map[string]error{"docker pull " + image: errors.New("manifest unknown")},
+			err:    "failed to pull node image kindest/node:v1.28.0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+			results := map[string][]utils.Result{
+				"kind get clusters":               {{Stdout: "other\n"}, {Stdout: "other\ntest\n"}},
+				"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+			}
+			for command, result := range test.results {
+				results[command] = result
+			}
+			runner := &fakeRunner{results: results, errors: test.errors}
+			cluster := NewCluster("test")
+			cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithImageDigest(image, test.digest))
+			_, err := cluster.Create(context.TODO())
+
+			created := false
+			for _, command := range runner.commands {
+				if strings.HasPrefix(command, "kind create cluster") {
+					created = true
+					if command != "kind create cluster --name test --image "+image {
+						t.Errorf("expected the cluster to be created from the verified node image, got: %s", command)
+					}
+				}
+			}
+			if test.err == "" {
+				if err != nil || !created {
+					t.Errorf("expected the cluster to be created, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected an error containing %q, got: %v", test.err, err)
+			}
+			if created {
+				t.Error("expected the cluster not to be created")
+			}
+		})
+	}
+}
+
+func TestCluster_GetControlPlaneIP(t *testing.T) {
+	inspect := " inspect test-control-plane --format {{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}"
+	tests := []struct {
+		name    string
+		runtime string
+		result  utils.Result
+		err     error
+		ip      string
+		errMsg  string
+	}{
+		{name: "docker", result: utils.Result{Stdout: "172.18.0.2 \n"}, ip: "172.18.0.2"},
+		{name: "podman", runtime: RuntimePodman, result: utils.Result{Stdout: "10.89.0.3 10.88.0.4 \n"}, ip: "10.89.0.3"},
+		{name: "no address", result: utils.Result{Stdout: " \n"}, errMsg: "has no IP address"},
+		{name: "not found", result: utils.Result{Stderr: "no such object"}, err: errors.New("exit status 1"), errMsg: "control plane node container test-control-plane of cluster test not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cli := RuntimeDocker
+			if test.runtime != "" {
+				cli = test.runtime
+			}
+			runner := &fakeRunner{
+				results: map[string][]utils.Result{cli + inspect: {test.result}},
+				errors:  map[string]error{cli + inspect: test.err},
+			}
+			cluster := NewCluster("test")
+			cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithRuntime(test.runtime))
+			ip, err := cluster.GetControlPlaneIP(context.TODO())
+			if test.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errMsg) {
+					t.Errorf("expected an error containing %q, got: %v", test.errMsg, err)
+				}
+				return
+			}
+			if err != nil || ip != test.ip {
+				t.Errorf("expected the control plane IP %s, got %q: %v", test.ip, ip, err)
+			}
+		})
+	}
+}
+
+func TestCluster_LoadImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		runtime  string
+		expected []string
+	}{
+		{
+			name:    "docker",
+			runtime: RuntimeDocker,
+			expected: []string{
+				"docker image inspect example:latest",
+				"kind load docker-image --name test example:latest",
+			},
+		},
+		{
+			name:    "podman",
+			runtime: RuntimePodman,
+			expected: []string{
+				"podman image inspect example:latest",
+				"podman save -o <archive> example:latest",
+				"kind load image-archive --name test <archive>",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := &fakeRunner{}
+			cluster := NewCluster("test")
+			cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithRuntime(test.runtime))
+			if err := cluster.LoadImage(context.TODO(), "example:latest"); err != nil {
+				t.Fatalf("unexpected error loading image: %s", err)
+			}
+			var commands []string
+			for _, command := range runner.commands {
+				fields := strings.Fields(command)
+				for i, field := range fields {
+					if strings.HasSuffix(field, "image.tar") {
+						fields[i] = "<archive>"
+					}
+				}
+				commands = append(commands, strings.Join(fields, " "))
+			}
+			if !reflect.DeepEqual(commands, test.expected) {
+				t.Errorf("unexpected commands:\n%s\nexpected:\n%s", strings.Join(commands, "\n"), strings.Join(test.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestCluster_GeneratedConfig(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "kind-config.yaml")
+	err := os.WriteFile(base, []byte(`kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+nodes:
+- role: control-plane
+containerdConfigPatches:
+- |-
+  [plugins."io.containerd.grpc.v1.cri"]
+    sandbox_image = "registry.k8s.io/pause:3.9"
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror := `[plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+  endpoint = ["http://registry:5000"]`
+
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNetworking("10.10.0.0/16", "", IPFamilyIPv4), WithContainerdConfigPatches(mirror))
+	args, cleanup, err := cluster.withGeneratedConfig([]string{"--config", base})
+	if err != nil {
+		t.Fatalf("unexpected error generating config: %s", err)
+	}
+	defer cleanup()
+	if len(args) != 2 || args[1] == base {
+		t.Fatalf("expected the config file to be replaced by the generated one, got: %v", args)
+	}
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		Nodes                   []interface{}     `json:"nodes"`
+		Networking              map[string]string `json:"networking"`
+		ContainerdConfigPatches []string          `json:"containerdConfigPatches"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Nodes) != 1 {
+		t.Errorf("expected the nodes of the base config to be kept, got: %v", config.Nodes)
+	}
+	if config.Networking["podSubnet"] != "10.10.0.0/16" || config.Networking["ipFamily"] != IPFamilyIPv4 {
+		t.Errorf("unexpected networking: %v", config.Networking)
+	}
+	if len(config.ContainerdConfigPatches) != 2 || config.ContainerdConfigPatches[1] != mirror {
+		t.Errorf("expected the patch to be appended to the ones of the base config, got: %v", config.ContainerdConfigPatches)
+	}
+}
+
+func TestCluster_GeneratedConfigInlineFlag(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "kind-config.yaml")
+	err := os.WriteFile(base, []byte(`kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+nodes:
+- role: control-plane
+- role: worker
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithContainerdConfigPatches(`[plugins."io.containerd.grpc.v1.cri"]`))
+	args, cleanup, err := cluster.withGeneratedConfig([]string{"--name", "test", "--config=" + base})
+	if err != nil {
+		t.Fatalf("unexpected error generating config: %s", err)
+	}
+	defer cleanup()
+	if len(args) != 3 || !strings.HasPrefix(args[2], "--config=") || args[2] == "--config="+base {
+		t.Fatalf("expected the config file to be replaced by the generated one, got: %v", args)
+	}
+	data, err := os.ReadFile(strings.TrimPrefix(args[2], "--config="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		Nodes []interface{} `json:"nodes"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Nodes) != 2 {
+		t.Errorf("expected the nodes of the base config to be kept, got: %v", config.Nodes)
+	}
+}
+
+func TestCluster_WorkingDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "kind-config.yaml"), []byte("kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"kind get clusters":               {{Stdout: ""}, {Stdout: "test\n"}},
+		"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithWorkingDir(dir), WithFeatureGates(map[string]bool{"SidecarContainers": true}))
+
+	// the relative config path is resolved against the working directory to generate the config
+	if _, err := cluster.CreateWithConfig(context.TODO(), "kind-config.yaml"); err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+	for i, command := range runner.commands {
+		if strings.HasPrefix(command, "kind ") && runner.dirs[i] != dir {
+			t.Errorf("expected %q to be executed in %s, got %q", command, dir, runner.dirs[i])
+		}
+	}
+}
+
+func TestCluster_EmptyContainerdConfigPatch(t *testing.T) {
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(&fakeRunner{}), WithContainerdConfigPatches(" "))
+	if _, err := cluster.Create(context.TODO()); err == nil {
+		t.Error("expected empty containerd config patch to be rejected")
+	}
+}
+
+func TestCluster_FeatureGatesAndRuntimeConfig(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "kind-config.yaml")
+	err := os.WriteFile(base, []byte(`kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+featureGates:
+  SidecarContainers: false
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := NewCluster("test")
+	cluster.WithOpts(
+		WithFeatureGates(map[string]bool{"SidecarContainers": true, "InPlacePodVerticalScaling": true}),
+		WithRuntimeConfig(map[string]string{"api/alpha": "true"}),
+	)
+	args, cleanup, err := cluster.withGeneratedConfig([]string{"--config", base})
+	if err != nil {
+		t.Fatalf("unexpected error generating config: %s", err)
+	}
+	defer cleanup()
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		FeatureGates  map[string]bool   `json:"featureGates"`
+		RuntimeConfig map[string]string `json:"runtimeConfig"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.FeatureGates) != 2 || !config.FeatureGates["SidecarContainers"] || !config.FeatureGates["InPlacePodVerticalScaling"] {
+		t.Errorf("expected the feature gates to be merged into the ones of the base config, got: %v", config.FeatureGates)
+	}
+	if config.RuntimeConfig["api/alpha"] != "true" {
+		t.Errorf("unexpected runtime config: %v", config.RuntimeConfig)
+	}
+
+	cluster = NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(&fakeRunner{}), WithFeatureGates(map[string]bool{"": true}))
+	if _, err := cluster.Create(context.TODO()); err == nil {
+		t.Error("expected empty feature gate name to be rejected")
+	}
+}
+
+func TestGenerateConfig(t *testing.T) {
+	config, err := GenerateConfig(ConfigOptions{
+		BaseConfig:   "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n- role: control-plane\n- role: worker\n",
+		IPFamily:     IPFamilyDual,
+		FeatureGates: map[string]bool{"SidecarContainers": true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error generating config: %s", err)
+	}
+	for _, expected := range []string{"ipFamily: dual", "SidecarContainers: true", "role: worker"} {
+		if !strings.Contains(config, expected) {
+			t.Errorf("expected the config to contain %q, got:\n%s", expected, config)
+		}
+	}
+
+	if _, err := GenerateConfig(ConfigOptions{IPFamily: "ipv5"}); err == nil {
+		t.Error("expected unsupported ip family to be rejected")
+	}
+	if _, err := GenerateConfig(ConfigOptions{BaseConfig: "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnode: []\n"}); err == nil {
+		t.Error("expected unknown field of the base config to be rejected")
+	}
+	if _, err := GenerateConfig(ConfigOptions{BaseConfig: "kind: Pod\napiVersion: v1\n"}); err == nil {
+		t.Error("expected base config of another kind to be rejected")
+	}
+}
+
+func TestCluster_WithRestConfig(t *testing.T) {
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"kind get clusters":               {{Stdout: "test\n"}},
+		"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	rc := &rest.Config{Host: "https://vault-issued.example.com:6443", BearerToken: "issued"}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithClientConfigOptions(klient.WithQPS(50)))
+	cluster.WithRestConfig(rc)
+
+	if got := cluster.KubernetesRestConfig(); got == nil || got.Host != rc.Host {
+		t.Fatalf("expected the supplied config to be returned before the cluster is created, got: %v", got)
+	}
+	if _, err := cluster.Create(context.TODO()); err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+	got := cluster.KubernetesRestConfig()
+	if got.Host != rc.Host || got.BearerToken != rc.BearerToken || got.QPS != 50 {
+		t.Errorf("expected the supplied config with the client options applied, got: %v", got)
+	}
+	cluster.Reset()
+	if got := cluster.KubernetesRestConfig(); got == nil || got.Host != rc.Host {
+		t.Errorf("expected the supplied config to be kept on reset, got: %v", got)
+	}
+}
+
+// blockingRunner fails the image loads once released, counting the kind load invocations
+type blockingRunner struct {
+	release chan struct{}
+	mu      sync.Mutex
+	loads   int
+}
+
+func (b *blockingRunner) Run(ctx context.Context, path string, args ...string) (utils.Result, error) {
+	if path == "kind" && args[0] == "load" {
+		b.mu.Lock()
+		b.loads++
+		b.mu.Unlock()
+		<-b.release
+		return utils.Result{Stderr: "no nodes found"}, fmt.Errorf("exit status 1")
+	}
+	return utils.Result{}, nil
+}
+
+func TestCluster_LoadImageCoalesced(t *testing.T) {
+	runner := &blockingRunner{release: make(chan struct{})}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = cluster.LoadImage(context.TODO(), "example:latest")
+		}(i)
+	}
+	// give all the loads the time to be requested before the first one completes
+	time.Sleep(100 * time.Millisecond)
+	close(runner.release)
+	wg.Wait()
+
+	if runner.loads != 1 {
+		t.Errorf("expected concurrent loads to be coalesced into a single kind invocation, got %d", runner.loads)
+	}
+	for i, err := range errs {
+		if err == nil || !strings.Contains(err.Error(), "no nodes found") {
+			t.Errorf("expected load %d to report the error of the shared load, got: %v", i, err)
+		}
+	}
+}
+
+func TestCluster_CapabilitiesBeforeCreate(t *testing.T) {
+	if _, err := NewCluster("test").Capabilities(context.TODO()); err == nil {
+		t.Error("expected capabilities of a cluster that has not been created to fail")
+	}
+}
+
+func TestCluster_DestroyRemovesKubeconfigFiles(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"kind get clusters":               {{Stdout: "test\n"}},
+		"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+
+	// the cluster already exists, so each Create fetches its kubeconfig again into a new file
+	for i := 0; i < 2; i++ {
+		if _, err := cluster.Create(context.TODO()); err != nil {
+			t.Fatalf("unexpected error creating cluster: %s", err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(os.TempDir(), kubeconfigFilePrefix+"*"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 kubeconfig files, got: %v", files)
+	}
+	if err := cluster.Destroy(context.TODO()); err != nil {
+		t.Fatalf("unexpected error destroying cluster: %s", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(os.TempDir(), "*")); len(files) != 0 {
+		t.Errorf("expected no temporary file to remain after Destroy, got: %v", files)
+	}
+}
+
+func TestRemoveStaleKubeconfigFiles(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	own := filepath.Join(os.TempDir(), fmt.Sprintf("%s%d-test-1", kubeconfigFilePrefix, os.Getpid()))
+	// pids are far below this value on the supported platforms, so no process is running with it
+	stale := filepath.Join(os.TempDir(), kubeconfigFilePrefix+"2147483646-test-1")
+	recent := filepath.Join(os.TempDir(), kubeconfigFilePrefix+"2147483646-test-2")
+	old := time.Now().Add(-2 * staleKubeconfigAge)
+	for _, file := range []string{own, stale, recent} {
+		if err := os.WriteFile(file, []byte(fakeKubeconfig), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if file != recent {
+			if err := os.Chtimes(file, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	removeStaleKubeconfigFiles()
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("expected the old kubeconfig file of the process that is gone to be removed")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("expected the recent kubeconfig file of a process that is not visible to be kept: %s", err)
+	}
+	if _, err := os.Stat(own); err != nil {
+		t.Errorf("expected the kubeconfig file of the current process to be kept: %s", err)
+	}
+}
+
+func TestCluster_ExitHooksRemoveKubeconfigFiles(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"kind get clusters":               {{Stdout: "test\n"}},
+		"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+	if _, err := cluster.Create(context.TODO()); err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+
+	utils.RunExitHooks()
+	if files, _ := filepath.Glob(filepath.Join(os.TempDir(), "*")); len(files) != 0 {
+		t.Errorf("expected the exit hooks to remove the kubeconfig files of the cluster, got: %v", files)
+	}
+}
+
+func TestCluster_IsolatedKubeconfig(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	kubeconfigOf := func(runner *fakeRunner) string {
+		var kubeconfig string
+		for _, env := range runner.env {
+			for _, v := range env {
+				if strings.HasPrefix(v, "KUBECONFIG=") {
+					if kubeconfig != "" && v != "KUBECONFIG="+kubeconfig {
+						t.Errorf("expected all the kind invocations to use the same kubeconfig, got %s and %s", kubeconfig, v)
+					}
+					kubeconfig = strings.TrimPrefix(v, "KUBECONFIG=")
+				}
+			}
+		}
+		return kubeconfig
+	}
+
+	var clusters []*Cluster
+	var kubeconfigs []string
+	for i := 0; i < 2; i++ {
+		runner := &fakeRunner{results: map[string][]utils.Result{
+			"kind get clusters":               {{Stdout: "other\n"}, {Stdout: "other\ntest\n"}},
+			"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+		}}
+		cluster := NewCluster("test")
+		cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithIsolatedKubeconfig())
+		if _, err := cluster.Create(context.TODO()); err != nil {
+			t.Fatalf("unexpected error creating cluster: %s", err)
+		}
+		kubeconfig := kubeconfigOf(runner)
+		if filepath.Dir(kubeconfig) != filepath.Clean(os.TempDir()) {
+			t.Errorf("expected the isolated kubeconfig to be created in %s, got %q", os.TempDir(), kubeconfig)
+		}
+		clusters = append(clusters, cluster)
+		kubeconfigs = append(kubeconfigs, kubeconfig)
+	}
+	if kubeconfigs[0] == kubeconfigs[1] {
+		t.Errorf("expected clusters of the same name to use distinct kubeconfig files, got %s", kubeconfigs[0])
+	}
+
+	if err := clusters[0].Destroy(context.TODO()); err != nil {
+		t.Fatalf("unexpected error destroying cluster: %s", err)
+	}
+	if _, err := os.Stat(kubeconfigs[0]); !os.IsNotExist(err) {
+		t.Errorf("expected the isolated kubeconfig to be removed on destroy, got: %v", err)
+	}
+	if _, err := os.Stat(kubeconfigs[1]); err != nil {
+		t.Errorf("expected the isolated kubeconfig of the other cluster to be kept: %v", err)
+	}
+}
+
+func TestCluster_KindEnvVars(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv(kindPathEnvVar, "/opt/bin/kind")
+	t.Setenv(kindVersionEnvVar, "v0.20.0")
+
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"/opt/bin/kind get clusters":               {{Stdout: "other\n"}, {Stdout: "other\ntest\n"}},
+		"/opt/bin/kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+	if _, err := cluster.Create(context.TODO()); err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+	if runner.commands[0] != "/opt/bin/kind get clusters" {
+		t.Errorf("expected the kind binary of %s to be used, got commands:\n%s", kindPathEnvVar, strings.Join(runner.commands, "\n"))
+	}
+	if cluster.version != "v0.20.0" || cluster.versionSource != kindVersionEnvVar {
+		t.Errorf("expected the kind version of %s, got %s from %s", kindVersionEnvVar, cluster.version, cluster.versionSource)
+	}
+
+	// the options configured explicitly take precedence over the env vars
+	cluster = NewCluster("test")
+	cluster.WithPath("/usr/local/bin/kind")
+	cluster.WithVersion("v0.19.0")
+	cluster.SetDefaults()
+	if cluster.path != "/usr/local/bin/kind" || cluster.version != "v0.19.0" {
+		t.Errorf("expected the explicit path and version, got %s and %s", cluster.path, cluster.version)
+	}
+	cluster = NewCluster("test")
+	cluster.WithOpts(WithPath("/usr/local/bin/kind"))
+	cluster.SetDefaults()
+	if cluster.path != "/usr/local/bin/kind" || cluster.pathSource != "WithPath" || cluster.version != "v0.20.0" {
+		t.Errorf("expected the explicit path and the version of %s, got %s and %s", kindVersionEnvVar, cluster.path, cluster.version)
+	}
+
+	// the defaults are used when the env vars are not set
+	t.Setenv(kindPathEnvVar, "")
+	t.Setenv(kindVersionEnvVar, "")
+	cluster = NewCluster("test")
+	cluster.SetDefaults()
+	if cluster.path != "kind" || cluster.version != kindVersion || cluster.pathSource != "default" || cluster.versionSource != "default" {
+		t.Errorf("expected the default path and version, got %s from %s and %s from %s", cluster.path, cluster.pathSource, cluster.version, cluster.versionSource)
+	}
+}
+
+func TestCluster_KubeContext(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"kind get clusters":               {{Stdout: "test\n"}},
+		"kind get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	if cluster.GetKubectlContext() != "kind-test" {
+		t.Errorf("expected the default context kind-test, got %s", cluster.GetKubectlContext())
+	}
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner), WithKubeContext("staging"))
+	kubeconfig, err := cluster.Create(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+	if cluster.GetKubectlContext() != "staging" {
+		t.Errorf("expected the configured context, got %s", cluster.GetKubectlContext())
+	}
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := config.Contexts["staging"]; !ok || config.CurrentContext != "staging" || len(config.Contexts) != 1 {
+		t.Errorf("expected the context of the kubeconfig to be renamed, got contexts %v and current context %s", config.Contexts, config.CurrentContext)
+	}
+	if rc := cluster.KubernetesRestConfig(); rc == nil || rc.Host != "https://127.0.0.1:6443" {
+		t.Errorf("expected the rest config to be built from the renamed context, got %v", rc)
+	}
+}
+
+func TestCluster_RunnerLooksUpKind(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	runner := &fakeRunner{}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithRunner(runner), WithPath(filepath.Join(t.TempDir(), "kind")), WithNoAutoInstall())
+	if _, err := cluster.Create(context.TODO()); !errors.Is(err, utils.ErrProviderNotFound) {
+		t.Errorf("expected the kind binary to be looked up when a runner is configured, got: %v", err)
+	}
+	if len(runner.commands) != 0 {
+		t.Errorf("expected no command to be run without a kind binary, got:\n%s", strings.Join(runner.commands, "\n"))
+	}
+}
+
+func TestCluster_NoAutoInstall(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	kind := filepath.Join(t.TempDir(), "kind")
+	if err := os.WriteFile(kind, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		kind + " get clusters":               {{Stdout: "test\n"}},
+		kind + " get kubeconfig --name test": {{Stdout: fakeKubeconfig}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithRunner(runner), WithPath(kind), WithNoAutoInstall())
+	if _, err := cluster.Create(context.TODO()); err != nil {
+		t.Fatalf("unexpected error creating cluster: %s", err)
+	}
+	if err := cluster.Destroy(context.TODO()); err != nil {
+		t.Fatalf("unexpected error destroying cluster: %s", err)
+	}
+	for _, command := range runner.commands {
+		if !strings.HasPrefix(command, kind+" ") && !strings.HasPrefix(command, "docker ") {
+			t.Errorf("expected the pinned kind binary to be used as is, got: %s", command)
+		}
+	}
+
+	missing := NewCluster("test")
+	missing.WithOpts(WithRunner(&fakeRunner{}), WithPath(filepath.Join(t.TempDir(), "kind")), WithNoAutoInstall())
+	if err := missing.ExportLogs(context.TODO(), t.TempDir()); !errors.Is(err, utils.ErrProviderNotFound) {
+		t.Errorf("expected the missing kind binary not to be installed when exporting logs, got: %v", err)
+	}
+	if err := missing.Destroy(context.TODO()); !errors.Is(err, utils.ErrProviderNotFound) {
+		t.Errorf("expected the missing kind binary not to be installed when destroying the cluster, got: %v", err)
+	}
+}
+
+func TestCluster_SnapshotEtcdArgs(t *testing.T) {
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"docker exec test-control-plane crictl ps --name etcd -q": {{Stdout: "abc123\n"}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+	if err := cluster.SnapshotEtcd(context.TODO(), "/tmp/snapshot.db"); err != nil {
+		t.Fatalf("unexpected error taking etcd snapshot: %s", err)
+	}
+	expected := append(append([]string{"exec", "test-control-plane", "crictl", "exec", "abc123", "etcdctl"}, etcdctlTLSArgs...), "snapshot", "save", etcdSnapshotFile)
+	if len(runner.args) < 2 || !reflect.DeepEqual(runner.args[1], expected) {
+		t.Errorf("expected the snapshot to be saved using the arguments %q, got %q", expected, runner.args)
+	}
+}
+
+func TestCluster_SnapshotEtcdNotRunning(t *testing.T) {
+	runner := &fakeRunner{}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+	err := cluster.SnapshotEtcd(context.TODO(), "/tmp/snapshot.db")
+	if err == nil || !strings.Contains(err.Error(), "etcd container is not running on node test-control-plane") {
+		t.Errorf("expected the snapshot to fail without an etcd container, got: %v", err)
+	}
+	if len(runner.commands) != 1 {
+		t.Errorf("expected no snapshot to be attempted, got commands:\n%s", strings.Join(runner.commands, "\n"))
+	}
+}
+
+func TestCluster_RestoreEtcd(t *testing.T) {
+	runner := &fakeRunner{results: map[string][]utils.Result{
+		"docker exec test-control-plane crictl ps --name etcd -q": {{Stdout: "abc123\n"}},
+	}}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+	if err := cluster.RestoreEtcd(context.TODO(), "/tmp/snapshot.db"); err != nil {
+		t.Fatalf("unexpected error restoring etcd snapshot: %s", err)
+	}
+	expected := []string{
+		"docker exec test-control-plane crictl ps --name etcd -q",
+		"docker cp /tmp/snapshot.db test-control-plane:/var/lib/etcd/e2e-snapshot.db",
+		"docker exec test-control-plane rm -rf /var/lib/etcd/e2e-restore",
+		"docker exec test-control-plane crictl exec abc123 etcdctl snapshot restore /var/lib/etcd/e2e-snapshot.db --data-dir /var/lib/etcd/e2e-restore",
+		"docker exec test-control-plane mv /etc/kubernetes/manifests/etcd.yaml /etc/kubernetes/manifests/kube-apiserver.yaml /etc/kubernetes/",
+		"docker exec test-control-plane crictl ps --name etcd|kube-apiserver -q",
+		"docker exec test-control-plane rm -rf /var/lib/etcd/member",
+		"docker exec test-control-plane mv /var/lib/etcd/e2e-restore/member /var/lib/etcd/member",
+		"docker exec test-control-plane rm -rf /var/lib/etcd/e2e-restore /var/lib/etcd/e2e-snapshot.db",
+		"docker exec test-control-plane mv /etc/kubernetes/etcd.yaml /etc/kubernetes/kube-apiserver.yaml /etc/kubernetes/manifests/",
+	}
+	if !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("unexpected commands:\n%s\nexpected:\n%s", strings.Join(runner.commands, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestCluster_RestoreEtcdRollback(t *testing.T) {
+	const (
+		start  = "docker exec test-control-plane mv /etc/kubernetes/etcd.yaml /etc/kubernetes/kube-apiserver.yaml /etc/kubernetes/manifests/"
+		ps     = "docker exec test-control-plane crictl ps --name etcd|kube-apiserver -q"
+		swapRm = "docker exec test-control-plane rm -rf /var/lib/etcd/member"
+	)
+	tests := []struct {
+		name    string
+		results map[string][]utils.Result
+		errors  map[string]error
+		timeout time.Duration
+		err     string
+	}{
+		{
+			name:    "control plane not stopping",
+			results: map[string][]utils.Result{ps: {{Stdout: "def456\n"}}},
+			timeout: 100 * time.Millisecond,
+			err:     "timed out waiting for control plane to stop",
+		},
+		{
+			name:   "data directory swap failure",
+			errors: map[string]error{swapRm: errors.New("device or resource busy")},
+			err:    "etcd restore failed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := map[string][]utils.Result{
+				"docker exec test-control-plane crictl ps --name etcd -q": {{Stdout: "abc123\n"}},
+			}
+			for command, result := range test.results {
+				results[command] = result
+			}
+			runner := &fakeRunner{results: results, errors: test.errors}
+			cluster := NewCluster("test")
+			cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+			ctx := context.Background()
+			if test.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, test.timeout)
+				defer cancel()
+			}
+			err := cluster.RestoreEtcd(ctx, "/tmp/snapshot.db")
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected an error containing %q, got: %v", test.err, err)
+			}
+			if last := runner.commands[len(runner.commands)-1]; last != start {
+				t.Errorf("expected the control plane to be restarted, got commands:\n%s", strings.Join(runner.commands, "\n"))
+			}
+		})
+	}
+}
+
+func TestCluster_RestoreEtcdFailure(t *testing.T) {
+	runner := &fakeRunner{
+		results: map[string][]utils.Result{
+			"docker exec test-control-plane crictl ps --name etcd -q": {{Stdout: "abc123\n"}},
+		},
+		errors: map[string]error{
+			"docker cp /tmp/snapshot.db test-control-plane:/var/lib/etcd/e2e-snapshot.db": errors.New("no such file"),
+		},
+	}
+	cluster := NewCluster("test")
+	cluster.WithOpts(WithNoLookup(), WithRunner(runner))
+	err := cluster.RestoreEtcd(context.TODO(), "/tmp/snapshot.db")
+	if err == nil || !strings.Contains(err.Error(), "copy etcd snapshot from /tmp/snapshot.db failed") {
+		t.Errorf("expected the copy failure to be reported, got: %v", err)
+	}
+	if len(runner.commands) != 2 {
+		t.Errorf("expected the control plane to be left untouched, got commands:\n%s", strings.Join(runner.commands, "\n"))
+	}
+}
+
+func TestLoadGroup_LeaderCancelled(t *testing.T) {
+	group := &loadGroup{calls: make(map[string]*loadCall)}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	load := func(ctx context.Context) error {
+		close(started)
+		select {
+		case <-release:
+			return ctx.Err()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.TODO())
+	leader := make(chan error)
+	go func() { leader <- group.do(leaderCtx, "image", load) }()
+	<-started
+
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the leader to stop waiting once cancelled, got: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	err := group.do(context.TODO(), "image", func(ctx context.Context) error {
+		return errors.New("expected the load in progress to be waited for")
+	})
+	if err != nil {
+		t.Errorf("expected the load to complete for the waiter after the leader was cancelled, got: %v", err)
+	}
+}
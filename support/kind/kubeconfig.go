@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	log "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+// kubeconfigFilePrefix is the prefix of the temporary kubeconfig files, which is followed by the pid of the
+// process that created them so that the files left behind by the processes that did not exit cleanly can be
+// identified and removed
+const kubeconfigFilePrefix = "kind-kubeconfig-"
+
+// staleKubeconfigAge is how long a kubeconfig file left behind by a process that is not visible anymore is kept.
+// The process may still be running in another pid namespace sharing the temporary directory, such as another
+// container, so only the files that are old enough to no longer be in use are removed.
+const staleKubeconfigAge = 24 * time.Hour
+
+var (
+	staleKubeconfigsOnce sync.Once
+	kubeconfigFilesMu    sync.Mutex
+	// kubeconfigFiles are the kubeconfig files of the process that have not been removed yet
+	kubeconfigFiles = map[string]struct{}{}
+	// kubeconfigExitHook tells whether removeProcessKubeconfigFiles is registered as an exit hook
+	kubeconfigExitHook bool
+)
+
+// createKubeconfigFile creates a temporary kubeconfig file for the cluster and tracks it so that Destroy
+// removes it along with the ones created before, for instance when the cluster was re-created. The files of
+// the clusters that are not destroyed are removed by the exit hooks of the utils package, which the test
+// environment runs when the test suite completes and upon a signal. As there is no way to run code when the
+// process crashes or is killed, the old files left behind by the processes that are gone are removed the first
+// time a kubeconfig file is created.
+func (k *Cluster) createKubeconfigFile() (*os.File, error) {
+	staleKubeconfigsOnce.Do(removeStaleKubeconfigFiles)
+	file, err := os.CreateTemp("", fmt.Sprintf("%s%d-%s-", kubeconfigFilePrefix, os.Getpid(), k.name))
+	if err != nil {
+		return nil, err
+	}
+	kubeconfigFilesMu.Lock()
+	kubeconfigFiles[file.Name()] = struct{}{}
+	if !kubeconfigExitHook {
+		kubeconfigExitHook = true
+		utils.RegisterExitHook(removeProcessKubeconfigFiles)
+	}
+	kubeconfigFilesMu.Unlock()
+	k.kubecfgFiles = append(k.kubecfgFiles, file.Name())
+	return file, nil
+}
+
+// removeKubeconfigFiles removes all the kubeconfig files created for the cluster
+func (k *Cluster) removeKubeconfigFiles() error {
+	var errs []error
+	for _, file := range k.kubecfgFiles {
+		log.V(4).Info("Removing kubeconfig file ", file)
+		if err := os.RemoveAll(file); err != nil {
+			errs = append(errs, fmt.Errorf("kind: remove kubeconfig %v failed: %w", file, err))
+			continue
+		}
+		kubeconfigFilesMu.Lock()
+		delete(kubeconfigFiles, file)
+		kubeconfigFilesMu.Unlock()
+	}
+	k.kubecfgFiles = nil
+	return errors.Join(errs...)
+}
+
+// Keep leaves the kubeconfig files of the cluster in place when the process exits, so that a cluster kept alive
+// for inspection, for instance using envconf.WithKeepClusterOnFailure, can still be accessed using the kubeconfig
+// file returned by GetKubeconfig. The files are still removed by Destroy.
+func (k *Cluster) Keep() {
+	kubeconfigFilesMu.Lock()
+	defer kubeconfigFilesMu.Unlock()
+	for _, file := range k.kubecfgFiles {
+		delete(kubeconfigFiles, file)
+	}
+}
+
+// removeProcessKubeconfigFiles removes the kubeconfig files created by the process that are still around
+func removeProcessKubeconfigFiles() {
+	kubeconfigFilesMu.Lock()
+	defer kubeconfigFilesMu.Unlock()
+	kubeconfigExitHook = false
+	for file := range kubeconfigFiles {
+		log.V(4).Info("Removing kubeconfig file ", file)
+		if err := os.RemoveAll(file); err != nil {
+			log.ErrorS(err, "failed to remove kubeconfig file", "path", file)
+		}
+		delete(kubeconfigFiles, file)
+	}
+}
+
+// removeStaleKubeconfigFiles removes the temporary kubeconfig files created by the processes that are not
+// running anymore and that were not modified for staleKubeconfigAge
+func removeStaleKubeconfigFiles() {
+	files, err := filepath.Glob(filepath.Join(os.TempDir(), kubeconfigFilePrefix+"*"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		pid, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(filepath.Base(file), kubeconfigFilePrefix), "-", 2)[0])
+		if err != nil || pid == os.Getpid() || processRunning(pid) {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil || time.Since(info.ModTime()) < staleKubeconfigAge {
+			continue
+		}
+		log.V(4).Info("Removing stale kubeconfig file ", file)
+		if err := os.RemoveAll(file); err != nil {
+			log.ErrorS(err, "failed to remove stale kubeconfig file", "path", file)
+		}
+	}
+}
+
+// processRunning returns false when the process is known to be gone, it returns true when this cannot be
+// determined, such as on the platforms that cannot signal processes
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return !errors.Is(process.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
+
+// renameContext renames the context from of the kubeconfig to to, updating the current context if it is the renamed
+// one. The cluster and user entries the context refers to are left as is.
+func renameContext(data []byte, from, to string) ([]byte, error) {
+	if from == to {
+		return data, nil
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, err
+	}
+	context, ok := config.Contexts[from]
+	if !ok {
+		return nil, fmt.Errorf("context %s not found", from)
+	}
+	delete(config.Contexts, from)
+	config.Contexts[to] = context
+	if config.CurrentContext == from {
+		config.CurrentContext = to
+	}
+	return clientcmd.Write(*config)
+}
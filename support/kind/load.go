@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// imageLoads coalesces the concurrent loads of the same image into the same cluster, which happen when
+// features running in parallel load the images they need, so that kind is invoked only once per image
+var imageLoads = &loadGroup{calls: make(map[string]*loadCall)}
+
+// loadCall is a load in progress, the waiters are released once done is closed
+type loadCall struct {
+	done chan struct{}
+	err  error
+}
+
+// loadGroup runs a single load per key at a time and shares its result with the callers requesting the
+// same load while it is in progress. Completed loads are not remembered, so a later call loads again.
+type loadGroup struct {
+	mu    sync.Mutex
+	calls map[string]*loadCall
+}
+
+// do runs load, unless a load for the same key is already in progress in which case its result is waited
+// for and returned instead. A caller stops waiting when its context is done, the load itself keeps going
+// until it completes for the other callers, including when the caller that started it stops waiting. The
+// load is passed the context of the caller that started it, without its cancellation and deadline.
+func (g *loadGroup) do(ctx context.Context, key string, load func(ctx context.Context) error) error {
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if !ok {
+		call = &loadCall{done: make(chan struct{})}
+		g.calls[key] = call
+		go func() {
+			call.err = load(detachedContext{ctx})
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// detachedContext keeps the values of the wrapped context while ignoring its cancellation and deadline
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detachedContext) Done() <-chan struct{}             { return nil }
+func (d detachedContext) Err() error                        { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/support"
+)
+
+const (
+	// MetadataLabelPrefix is the prefix of the container runtime labels the metadata of the clusters is stored with
+	MetadataLabelPrefix = "e2e-framework.sigs.k8s.io/"
+	// metadataVolumePrefix is the prefix of the name of the volume holding the metadata of a cluster
+	metadataVolumePrefix = "e2e-framework-kind-metadata-"
+	kindClusterLabel     = "io.x-k8s.kind.cluster"
+)
+
+// WithMetadata attaches metadata, such as the owner, the suite or the CI build ID, to the cluster. kind has no
+// support for labeling clusters, so the metadata is stored as labels, prefixed with MetadataLabelPrefix, of a
+// volume created along with the cluster using the container runtime and removed by Destroy. This lets cleanup
+// scripts find the clusters left behind using either ListClustersWithMetadata or the container runtime CLI, e.g.
+// docker volume ls --filter label=e2e-framework.sigs.k8s.io/build-id=1234
+func WithMetadata(metadata map[string]string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.metadata = metadata
+		}
+	}
+}
+
+// metadataVolume returns the name of the volume holding the metadata of the cluster
+func (k *Cluster) metadataVolume() string {
+	return metadataVolumePrefix + k.name
+}
+
+// storeMetadata creates the volume holding the metadata of the cluster, if any is configured
+func (k *Cluster) storeMetadata(ctx context.Context) error {
+	if len(k.metadata) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(k.metadata))
+	for key := range k.metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := []string{"volume", "create", "--label", fmt.Sprintf("%s=%s", kindClusterLabel, k.name)}
+	for _, key := range keys {
+		args = append(args, "--label", fmt.Sprintf("%s%s=%s", MetadataLabelPrefix, key, k.metadata[key]))
+	}
+	if res, err := k.runContainerRuntime(ctx, append(args, k.metadataVolume())...); err != nil {
+		return fmt.Errorf("kind: failed to store metadata of cluster %s: %s: %s", k.name, err, res.Output())
+	}
+	return nil
+}
+
+// removeMetadata removes the volume holding the metadata of the cluster. Failures are only logged as
+// the cluster may have been created without metadata.
+func (k *Cluster) removeMetadata(ctx context.Context) {
+	if res, err := k.runContainerRuntime(ctx, "volume", "rm", k.metadataVolume()); err != nil {
+		log.V(4).InfoS("No metadata removed for kind cluster", "cluster", k.name, "output", res.Output())
+	}
+}
+
+// ListClustersWithMetadata returns the names of the existing kind clusters created with metadata, using
+// WithMetadata, matching all the provided key/value pairs. All the clusters created with metadata are
+// returned if metadata is empty. The container runtime configured for k is used to look up the metadata.
+func (k *Cluster) ListClustersWithMetadata(ctx context.Context, metadata map[string]string) ([]string, error) {
+	if err := k.findOrInstallKind(); err != nil {
+		return nil, err
+	}
+	args := []string{"volume", "ls", "--format", "{{.Name}}", "--filter", "label=" + kindClusterLabel}
+	for key, value := range metadata {
+		args = append(args, "--filter", fmt.Sprintf("label=%s%s=%s", MetadataLabelPrefix, key, value))
+	}
+	res, err := k.runContainerRuntime(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("kind: failed to list cluster metadata: %s: %s", err, res.Output())
+	}
+
+	existing, _ := k.listClusters(ctx)
+	clusters := make(map[string]bool)
+	for _, c := range existing {
+		clusters[c] = true
+	}
+
+	var names []string
+	for _, volume := range strings.Fields(res.Stdout) {
+		if name := strings.TrimPrefix(volume, metadataVolumePrefix); name != volume && clusters[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"sigs.k8s.io/e2e-framework/support"
+)
+
+const (
+	// IPFamilyIPv4 configures the kind cluster with IPv4 networking
+	IPFamilyIPv4 = "ipv4"
+	// IPFamilyIPv6 configures the kind cluster with IPv6 networking
+	IPFamilyIPv6 = "ipv6"
+	// IPFamilyDual configures the kind cluster with dual-stack networking
+	IPFamilyDual = "dual"
+)
+
+type networking struct {
+	podSubnet     string
+	serviceSubnet string
+	ipFamily      string
+}
+
+// WithNetworking configures the pod subnet, the service subnet and the IP family of the kind cluster. The ipFamily
+// must be one of IPFamilyIPv4, IPFamilyIPv6 or IPFamilyDual. Empty values keep the kind defaults. The settings are
+// injected in the networking block of the kind config file provided to CreateWithConfig, or of a generated
+// config file if none is provided.
+func WithNetworking(podSubnet, serviceSubnet, ipFamily string) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.networking = &networking{podSubnet: podSubnet, serviceSubnet: serviceSubnet, ipFamily: ipFamily}
+		}
+	}
+}
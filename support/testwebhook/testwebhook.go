@@ -0,0 +1,308 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testwebhook provides an admission webhook test double. The webhook is served from the test
+// process and registered in the cluster using a ValidatingWebhookConfiguration, which makes it possible
+// to record the objects submitted to the API server and to delay or reject their admission in order to
+// verify how a controller behaves under those conditions.
+package testwebhook
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	log "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+const validatePath = "/validate"
+
+// Handler decides whether the request is admitted. The UID of the response is set by the Webhook.
+type Handler func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// Allow is the default Handler, it admits every request
+func Allow(*admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+// Deny returns a Handler rejecting every request with the provided message
+func Deny(message string) Handler {
+	return func(*admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Status: metav1.StatusFailure, Message: message, Code: http.StatusForbidden},
+		}
+	}
+}
+
+// Webhook is an admission webhook served by the test process
+type Webhook struct {
+	name              string
+	host              string
+	port              int
+	rules             []admissionregistrationv1.RuleWithOperations
+	namespaceSelector *metav1.LabelSelector
+	failurePolicy     admissionregistrationv1.FailurePolicyType
+	timeoutSeconds    int32
+	delay             time.Duration
+	handler           Handler
+
+	mu       sync.Mutex
+	requests []admissionv1.AdmissionRequest
+
+	server *http.Server
+	config *admissionregistrationv1.ValidatingWebhookConfiguration
+}
+
+// Option is used to customize the Webhook
+type Option func(*Webhook)
+
+// WithHost sets the address at which the API server reaches the test process. For kind clusters, this
+// is the gateway of the docker network of the cluster, which can be looked up using DockerNetworkGateway.
+func WithHost(host string) Option {
+	return func(w *Webhook) { w.host = host }
+}
+
+// WithPort sets the port the webhook listens on. A free port is picked when it is not set.
+func WithPort(port int) Option {
+	return func(w *Webhook) { w.port = port }
+}
+
+// WithRules sets the operations and resources the webhook is invoked for. Either the rules or a namespace
+// selector have to be set, as a webhook invoked for every request would slow the whole cluster down.
+func WithRules(rules ...admissionregistrationv1.RuleWithOperations) Option {
+	return func(w *Webhook) { w.rules = rules }
+}
+
+// WithNamespaceSelector limits the webhook to the namespaces matching the selector. When no rules are set
+// using WithRules, the webhook is invoked when any resource of those namespaces is created or updated.
+func WithNamespaceSelector(selector *metav1.LabelSelector) Option {
+	return func(w *Webhook) { w.namespaceSelector = selector }
+}
+
+// WithFailurePolicy sets how the API server handles errors calling the webhook. It defaults to Ignore so
+// that a webhook left behind by an interrupted test does not prevent the cluster from being used.
+func WithFailurePolicy(policy admissionregistrationv1.FailurePolicyType) Option {
+	return func(w *Webhook) { w.failurePolicy = policy }
+}
+
+// WithTimeoutSeconds sets how long the API server waits for the webhook to respond
+func WithTimeoutSeconds(seconds int32) Option {
+	return func(w *Webhook) { w.timeoutSeconds = seconds }
+}
+
+// WithDelay delays every admission response by d
+func WithDelay(d time.Duration) Option {
+	return func(w *Webhook) { w.delay = d }
+}
+
+// WithHandler sets the Handler deciding whether the requests are admitted
+func WithHandler(handler Handler) Option {
+	return func(w *Webhook) { w.handler = handler }
+}
+
+// New returns a Webhook with the provided name, which is used as the name of the ValidatingWebhookConfiguration
+func New(name string, opts ...Option) *Webhook {
+	w := &Webhook{
+		name:           name,
+		failurePolicy:  admissionregistrationv1.Ignore,
+		timeoutSeconds: 10,
+		handler:        Allow,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Start serves the webhook from the test process and registers it in the cluster using a
+// ValidatingWebhookConfiguration pointing at the host of the webhook. The rules or the namespace selector of the
+// webhook have to be set.
+func (w *Webhook) Start(ctx context.Context, r *resources.Resources) error {
+	if w.host == "" {
+		return fmt.Errorf("testwebhook: host of webhook %s is not set", w.name)
+	}
+	rules := w.rules
+	if len(rules) == 0 {
+		if w.namespaceSelector == nil {
+			return fmt.Errorf("testwebhook: webhook %s has neither rules nor namespace selector, set them using WithRules or WithNamespaceSelector", w.name)
+		}
+		rules = []admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{"*"},
+				APIVersions: []string{"*"},
+				Resources:   []string{"*"},
+			},
+		}}
+	}
+	certPEM, cert, err := newSelfSignedCert(w.host)
+	if err != nil {
+		return fmt.Errorf("testwebhook: generate certificate: %w", err)
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", w.port))
+	if err != nil {
+		return fmt.Errorf("testwebhook: listen: %w", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(validatePath, w.serveValidate)
+	w.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+	}
+	go func() {
+		if err := w.server.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.ErrorS(err, "testwebhook: server stopped", "webhook", w.name)
+		}
+	}()
+
+	url := fmt.Sprintf("https://%s%s", net.JoinHostPort(w.host, fmt.Sprint(port)), validatePath)
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	w.config = &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: w.name},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name:                    w.name + ".e2e-framework.k8s.io",
+			ClientConfig:            admissionregistrationv1.WebhookClientConfig{URL: &url, CABundle: certPEM},
+			Rules:                   rules,
+			NamespaceSelector:       w.namespaceSelector,
+			FailurePolicy:           &w.failurePolicy,
+			SideEffects:             &sideEffects,
+			TimeoutSeconds:          &w.timeoutSeconds,
+			AdmissionReviewVersions: []string{"v1"},
+		}},
+	}
+	log.V(4).InfoS("Registering test webhook", "webhook", w.name, "url", url)
+	if err := r.Create(ctx, w.config); err != nil {
+		_ = w.server.Close()
+		return fmt.Errorf("testwebhook: register webhook %s: %w", w.name, err)
+	}
+	return nil
+}
+
+// Stop removes the ValidatingWebhookConfiguration from the cluster and shuts the webhook server down
+func (w *Webhook) Stop(ctx context.Context, r *resources.Resources) error {
+	var errs []error
+	if w.config != nil {
+		if err := r.Delete(ctx, w.config); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("testwebhook: unregister webhook %s: %w", w.name, err))
+		}
+		w.config = nil
+	}
+	if w.server != nil {
+		if err := w.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("testwebhook: shutdown webhook %s: %w", w.name, err))
+		}
+		w.server = nil
+	}
+	return errors.Join(errs...)
+}
+
+// Requests returns the admission requests received by the webhook so far
+func (w *Webhook) Requests() []admissionv1.AdmissionRequest {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	requests := make([]admissionv1.AdmissionRequest, len(w.requests))
+	copy(requests, w.requests)
+	return requests
+}
+
+// RequestsFor returns the admission requests received for the object with the provided kind, namespace and name
+func (w *Webhook) RequestsFor(kind, namespace, name string) []admissionv1.AdmissionRequest {
+	var requests []admissionv1.AdmissionRequest
+	for _, req := range w.Requests() {
+		if req.Kind.Kind == kind && req.Namespace == namespace && req.Name == name {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// Reset forgets the admission requests received so far
+func (w *Webhook) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.requests = nil
+}
+
+func (w *Webhook) serveValidate(rw http.ResponseWriter, req *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(rw, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	w.mu.Lock()
+	w.requests = append(w.requests, *review.Request)
+	w.mu.Unlock()
+
+	if w.delay > 0 {
+		select {
+		case <-time.After(w.delay):
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	resp := w.handler(review.Request)
+	if resp == nil {
+		resp = &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	resp.UID = review.Request.UID
+	review.Response = resp
+	review.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(&review); err != nil {
+		log.ErrorS(err, "testwebhook: failed to write admission response", "webhook", w.name)
+	}
+}
+
+// DockerNetworkGateway returns the gateway of the docker network, which is the address at which the
+// containers attached to the network, such as the nodes of a kind cluster using the "kind" network,
+// reach the host.
+func DockerNetworkGateway(network string) (string, error) {
+	p := utils.RunCommand(fmt.Sprintf(`docker network inspect %s --format "{{range .IPAM.Config}}{{.Gateway}} {{end}}"`, network))
+	if p.Err() != nil {
+		return "", fmt.Errorf("testwebhook: inspect docker network %s: %s: %s", network, p.Err(), p.Result())
+	}
+	for _, gw := range strings.Fields(p.Result()) {
+		// prefer the IPv4 gateway as dual stack networks also list an IPv6 gateway
+		if ip := net.ParseIP(gw); ip != nil && ip.To4() != nil {
+			return gw, nil
+		}
+	}
+	if gws := strings.Fields(p.Result()); len(gws) > 0 {
+		return gws[0], nil
+	}
+	return "", fmt.Errorf("testwebhook: docker network %s has no gateway", network)
+}
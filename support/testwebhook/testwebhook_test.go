@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testwebhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// review sends an admission review for the object to the webhook and returns the response
+func review(t *testing.T, server *httptest.Server, uid, kind, namespace, name string) *admissionv1.AdmissionResponse {
+	t.Helper()
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID(uid),
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: kind},
+			Namespace: namespace,
+			Name:      name,
+			Operation: admissionv1.Create,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(server.URL+validatePath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s", resp.Status)
+	}
+	var out admissionv1.AdmissionReview
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Response == nil {
+		t.Fatal("admission review has no response")
+	}
+	return out.Response
+}
+
+func newServer(w *Webhook) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(validatePath, w.serveValidate)
+	return httptest.NewServer(mux)
+}
+
+func TestServeValidate(t *testing.T) {
+	w := New("allow")
+	server := newServer(w)
+	defer server.Close()
+
+	resp := review(t, server, "uid-1", "ConfigMap", "default", "cm")
+	if !resp.Allowed || resp.UID != "uid-1" {
+		t.Errorf("expected the request to be allowed with its UID, got %+v", resp)
+	}
+
+	w = New("deny", WithHandler(Deny("not today")))
+	denyServer := newServer(w)
+	defer denyServer.Close()
+	resp = review(t, denyServer, "uid-2", "ConfigMap", "default", "cm")
+	if resp.Allowed || resp.UID != "uid-2" || resp.Result == nil || resp.Result.Message != "not today" || resp.Result.Code != http.StatusForbidden {
+		t.Errorf("expected the request to be denied with the message, got %+v", resp)
+	}
+
+	res, err := http.Post(denyServer.URL+validatePath, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a review without request to be rejected, got %s", res.Status)
+	}
+}
+
+func TestWithDelay(t *testing.T) {
+	w := New("slow", WithDelay(200*time.Millisecond))
+	server := newServer(w)
+	defer server.Close()
+
+	start := time.Now()
+	if resp := review(t, server, "uid", "Pod", "default", "p"); !resp.Allowed {
+		t.Errorf("expected the delayed request to be allowed, got %+v", resp)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the response to be delayed, got it after %s", elapsed)
+	}
+}
+
+func TestRequestsForAndReset(t *testing.T) {
+	w := New("recorder")
+	server := newServer(w)
+	defer server.Close()
+
+	review(t, server, "1", "ConfigMap", "default", "a")
+	review(t, server, "2", "ConfigMap", "default", "b")
+	review(t, server, "3", "ConfigMap", "default", "a")
+	review(t, server, "4", "Secret", "default", "a")
+
+	if n := len(w.Requests()); n != 4 {
+		t.Errorf("expected 4 requests, got %d", n)
+	}
+	requests := w.RequestsFor("ConfigMap", "default", "a")
+	if len(requests) != 2 || requests[0].UID != "1" || requests[1].UID != "3" {
+		t.Errorf("expected the requests of configmap a in order, got %v", requests)
+	}
+
+	w.Reset()
+	if n := len(w.Requests()); n != 0 {
+		t.Errorf("expected no request after reset, got %d", n)
+	}
+	review(t, server, "5", "ConfigMap", "default", "a")
+	if requests := w.RequestsFor("ConfigMap", "default", "a"); len(requests) != 1 || requests[0].UID != "5" {
+		t.Errorf("expected the requests received after reset only, got %v", requests)
+	}
+}
+
+func TestStartRequiresRulesOrNamespaceSelector(t *testing.T) {
+	w := New("unscoped", WithHost("127.0.0.1"))
+	err := w.Start(context.TODO(), nil)
+	if err == nil || !strings.Contains(err.Error(), "neither rules nor namespace selector") {
+		t.Errorf("expected an unscoped webhook to be rejected, got: %v", err)
+	}
+}
@@ -103,3 +103,24 @@ type E2EClusterProviderWithImageLoader interface {
 	// can just provide a no-op implementation to be compliant with the interface
 	LoadImageArchive(ctx context.Context, archivePath string) error
 }
+
+// E2EClusterProviderWithCapabilities is implemented by the providers reporting the capabilities of their clusters,
+// which envfuncs.GetClusterCapabilities uses instead of discovering them.
+type E2EClusterProviderWithCapabilities interface {
+	E2EClusterProvider
+
+	// Capabilities reports what the cluster supports. Providers can complement or override the capabilities
+	// returned by DiscoverCapabilities with what they know about the clusters they create.
+	Capabilities(ctx context.Context) (Capabilities, error)
+}
+
+// E2EClusterProviderWithKeep is implemented by the providers cleaning up the local state of their clusters, such
+// as temporary kubeconfig files, when the process exits. envfuncs.DestroyCluster uses it to keep that state around
+// along with the clusters kept alive for inspection.
+type E2EClusterProviderWithKeep interface {
+	E2EClusterProvider
+
+	// Keep stops the local state of the cluster from being cleaned up when the process exits, so that the cluster
+	// can still be accessed once the test suite has completed.
+	Keep()
+}
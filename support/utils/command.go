@@ -17,15 +17,36 @@ limitations under the License.
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/vladimirvivien/gexe"
 	"github.com/vladimirvivien/gexe/exec"
+	"k8s.io/apimachinery/pkg/util/wait"
 	log "k8s.io/klog/v2"
 )
 
 var commandRunner = gexe.New()
 
+// ErrProviderNotFound is returned when the executable of a provider cannot be found and
+// installing it automatically is not allowed
+var ErrProviderNotFound = errors.New("provider not found")
+
+// FindProvider checks if the provider specified by the pPath executable exists, either as a path
+// or as a program available on the PATH. Unlike FindOrInstallGoBasedProvider, the provider is never
+// installed and an error wrapping ErrProviderNotFound is returned if it cannot be found.
+func FindProvider(pPath string) (string, error) {
+	if commandRunner.Prog().Avail(pPath) != "" {
+		log.V(4).InfoS("Found Provider tooling already installed on the machine", "command", pPath)
+		return pPath, nil
+	}
+	return "", fmt.Errorf("%w: %s is not available and automatic installation is disabled", ErrProviderNotFound, pPath)
+}
+
 // FindOrInstallGoBasedProvider check if the provider specified by the pPath executable exists or not.
 // If it exists, it returns the path with no error and if not, it uses the `go install` capabilities to
 // install the provider and setup the required binaries to perform the tests. In case if the install
@@ -40,13 +61,8 @@ func FindOrInstallGoBasedProvider(pPath, provider, module, version string) (stri
 
 	installCommand := fmt.Sprintf("go install %s@%s", module, version)
 	log.V(4).InfoS("Installing provider tooling using go install", "command", installCommand)
-	p := commandRunner.RunProc(installCommand)
-	if p.Err() != nil {
-		return "", fmt.Errorf("failed to install %s: %s", pPath, p.Err())
-	}
-
-	if !p.IsSuccess() || p.ExitCode() != 0 {
-		return "", fmt.Errorf("failed to install %s: %s", pPath, p.Result())
+	if err := runInstallCommand(installCommand); err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", pPath, err)
 	}
 
 	if providerPath := commandRunner.Prog().Avail(provider); providerPath != "" {
@@ -54,7 +70,7 @@ func FindOrInstallGoBasedProvider(pPath, provider, module, version string) (stri
 		return provider, nil
 	}
 
-	p = commandRunner.RunProc("ls $GOPATH/bin")
+	p := commandRunner.RunProc("ls $GOPATH/bin")
 	if p.Err() != nil {
 		return "", fmt.Errorf("failed to install %s: %s", pPath, p.Err())
 	}
@@ -75,10 +91,87 @@ func FindOrInstallGoBasedProvider(pPath, provider, module, version string) (stri
 	return "", fmt.Errorf("%s not available even after installation", provider)
 }
 
+// installBackoff is the backoff applied between the attempts at installing a provider
+var installBackoff = wait.Backoff{Duration: 2 * time.Second, Factor: 2, Jitter: 0.1, Steps: 4}
+
+// persistentInstallErrors match the go install error messages of failures that retrying won't fix,
+// such as an invalid module path or version or a module the proxy refuses to serve, as opposed to
+// transient network or proxy failures
+var persistentInstallErrors = []*regexp.Regexp{
+	regexp.MustCompile(`malformed module path`),
+	regexp.MustCompile(`invalid version`),
+	regexp.MustCompile(`unknown revision`),
+	regexp.MustCompile(`no matching versions`),
+	regexp.MustCompile(`cannot find module`),
+	regexp.MustCompile(`module \S+:? not found`),
+	regexp.MustCompile(`404 Not Found`),
+	regexp.MustCompile(`403 Forbidden`),
+	regexp.MustCompile(`is not available`),
+}
+
+// runInstallCommand runs the go install command, retrying with an exponential backoff when it fails
+// with what looks like a transient error, such as a module proxy error or a network timeout.
+func runInstallCommand(installCommand string) error {
+	backoff := installBackoff
+	for attempt := 1; ; attempt++ {
+		p := commandRunner.RunProc(installCommand)
+		if p.Err() == nil && p.IsSuccess() && p.ExitCode() == 0 {
+			return nil
+		}
+		if p.ExitCode() == -1 {
+			// the process could not be started, which retrying won't fix
+			return p.Err()
+		}
+		// the combined output of the process is only available once it has exited
+		output, _ := io.ReadAll(p.Out())
+		failure := strings.TrimSpace(string(output))
+		if p.Err() != nil {
+			failure = fmt.Sprintf("%s: %s", p.Err(), failure)
+		}
+		if isPersistentInstallError(failure) {
+			return errors.New(failure)
+		}
+		if backoff.Steps <= 1 {
+			return fmt.Errorf("giving up after %d attempts: %s", attempt, failure)
+		}
+		delay := backoff.Step()
+		log.V(2).InfoS("Provider installation failed, retrying", "command", installCommand, "attempt", attempt, "retryIn", delay, "error", failure)
+		time.Sleep(delay)
+	}
+}
+
+func isPersistentInstallError(output string) bool {
+	for _, msg := range persistentInstallErrors {
+		if msg.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunCommand executes the command and waits for it to complete. The returned *exec.Proc can be used
+// to inspect the outcome of the command. Err and Result provide access to the execution error and the
+// combined stdout/stderr output while ExitCode reports the numeric exit status of the process, which
+// can be used to branch on specific exit statuses of a tool instead of parsing its output. ExitCode
+// returns -1 if the process failed to start and the real exit code if it ran and exited non-zero.
 func RunCommand(command string) *exec.Proc {
 	return commandRunner.RunProc(command)
 }
 
+// RunCommandInDir works the same way as RunCommand but executes the command in the dir working directory, so that
+// the relative paths passed to the command, such as the path of a kustomize overlay, are resolved against dir
+// instead of the working directory of the current process.
+func RunCommandInDir(dir, command string) *exec.Proc {
+	p := commandRunner.NewProc(commandRunner.Eval(command))
+	if p.Err() != nil {
+		return p
+	}
+	p.Command().Dir = dir
+	p.Out()
+	return p
+}
+
+// FetchCommandOutput executes the command and returns its combined stdout/stderr output
 func FetchCommandOutput(command string) string {
 	return commandRunner.Run(command)
 }
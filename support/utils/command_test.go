@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestRunCommandInDir(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := RunCommandInDir(dir, "pwd")
+	if p.Err() != nil {
+		t.Fatalf("unexpected error running command: %s", p.Err())
+	}
+	if cwd := p.Result(); cwd != dir {
+		t.Errorf("expected the command to be executed in %s, got %s", dir, cwd)
+	}
+
+	res, err := ExecRunner{}.Run(ContextWithWorkingDir(context.TODO(), dir), "pwd")
+	if err != nil {
+		t.Fatalf("unexpected error running command: %s", err)
+	}
+	if cwd := strings.TrimSpace(res.Stdout); cwd != dir {
+		t.Errorf("expected the runner to execute the command in %s, got %s", dir, cwd)
+	}
+}
+
+func TestRunCommand_ExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		exitCode int
+	}{
+		{name: "success", command: "true", exitCode: 0},
+		{name: "failure", command: "sh -c 'exit 3'", exitCode: 3},
+		{name: "not started", command: filepath.Join(t.TempDir(), "missing-binary"), exitCode: -1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := RunCommand(tc.command)
+			if p.ExitCode() != tc.exitCode {
+				t.Errorf("expected exit code %d, got %d (error: %v)", tc.exitCode, p.ExitCode(), p.Err())
+			}
+			if tc.exitCode != 0 && p.Err() == nil {
+				t.Error("expected an error for the failed command")
+			}
+		})
+	}
+}
+
+// installScript writes a script failing with the outputs in turn, then succeeding, and returns its path along
+// with a function returning the number of times it was run
+func installScript(t *testing.T, outputs ...string) (string, func() int) {
+	t.Helper()
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "attempts")
+	script := fmt.Sprintf("#!/bin/sh\necho x >> %s\nattempt=$(wc -l < %s)\n", counter, counter)
+	for i, output := range outputs {
+		script += fmt.Sprintf("if [ $attempt -eq %d ]; then echo '%s'; exit 1; fi\n", i+1, output)
+	}
+	path := filepath.Join(dir, "install.sh")
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() int {
+		data, _ := os.ReadFile(counter)
+		return strings.Count(string(data), "x")
+	}
+}
+
+func TestRunInstallCommand(t *testing.T) {
+	backoff := installBackoff
+	installBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	t.Cleanup(func() { installBackoff = backoff })
+
+	tests := []struct {
+		name     string
+		outputs  []string
+		attempts int
+		err      string
+	}{
+		{
+			name:     "transient failure then success",
+			outputs:  []string{"go: example.com/tool@v1.0.0: dial tcp: i/o timeout"},
+			attempts: 2,
+		},
+		{
+			name:     "persistent failure",
+			outputs:  []string{"go: example.com/tool@v9.9.9: invalid version: unknown revision v9.9.9"},
+			attempts: 1,
+			err:      "invalid version",
+		},
+		{
+			name:     "module refused by the proxy",
+			outputs:  []string{"go: example.com/tool@v1.0.0: reading https://proxy.golang.org/example.com/tool/@v/list: 403 Forbidden"},
+			attempts: 1,
+			err:      "403 Forbidden",
+		},
+		{
+			name: "giving up after the steps of the backoff",
+			outputs: []string{
+				"go: example.com/tool@v1.0.0: dial tcp: i/o timeout",
+				"go: example.com/tool@v1.0.0: dial tcp: i/o timeout",
+				"go: example.com/tool@v1.0.0: dial tcp: i/o timeout",
+			},
+			attempts: 3,
+			err:      "giving up after 3 attempts",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			script, attempts := installScript(t, tc.outputs...)
+			err := runInstallCommand(script)
+			if tc.err == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Errorf("expected an error containing %q, got: %v", tc.err, err)
+			}
+			if got := attempts(); got != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, got)
+			}
+		})
+	}
+}
+
+func TestIsPersistentInstallError(t *testing.T) {
+	tests := map[string]bool{
+		"go: example.com/tool@latest: module example.com/tool: reading https://proxy.golang.org/example.com/tool/@v/list: 404 Not Found": true,
+		"go: example.com/tool@latest: module example.com/tool: not found":                                                                true,
+		"go: example.com/tool@v1.0.0: server response: This module version is not available.":                                            true,
+		"go: example.com/tool@v1.0.0: Get \"https://proxy.golang.org/example.com/tool/@v/v1.0.0.info\": dial tcp: i/o timeout":           false,
+		"go: example.com/tool@v1.0.0: reading https://proxy.golang.org/example.com/tool/@v/v1.0.0.zip: 502 Bad Gateway":                  false,
+		"go: downloading example.com/tool v1.0.0: verifying module: checksum database entry not found in the response":                   false,
+	}
+	for output, persistent := range tests {
+		if got := isPersistentInstallError(output); got != persistent {
+			t.Errorf("expected %q to be persistent %t, got %t", output, persistent, got)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunExitHooks(t *testing.T) {
+	var calls []int
+	RegisterExitHook(func() { calls = append(calls, 1) })
+	RegisterExitHook(func() { calls = append(calls, 2) })
+
+	RunExitHooks()
+	RunExitHooks()
+	if !reflect.DeepEqual(calls, []int{2, 1}) {
+		t.Errorf("expected the hooks to run once in the reverse order of their registration, got calls %v", calls)
+	}
+}
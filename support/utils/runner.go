@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Result is the outcome of a command executed by a Runner
+type Result struct {
+	// Stdout and Stderr are the outputs of the command
+	Stdout string
+	Stderr string
+	// ExitCode is the exit status of the command, or -1 if the command could not be started
+	ExitCode int
+}
+
+// Output returns the combined stderr and stdout output of the command, trimmed of surrounding whitespaces,
+// which is suitable for reporting why a command failed.
+func (r Result) Output() string {
+	return strings.TrimSpace(strings.TrimSpace(r.Stderr) + "\n" + strings.TrimSpace(r.Stdout))
+}
+
+// Runner executes commands on behalf of the cluster providers. The additional environment variables and the working
+// directory the command must be executed with, if any, are carried by the context and can be retrieved using
+// EnvFromContext and WorkingDirFromContext.
+// Implementations return an error if the command could not be started or exited with a non-zero status.
+//
+// ExecRunner is used by default. Custom implementations make it possible to test the providers without
+// executing the actual commands, by recording the commands and returning canned results instead.
+type Runner interface {
+	Run(ctx context.Context, path string, args ...string) (Result, error)
+}
+
+// ExecRunner is the Runner executing the commands as processes of the host
+type ExecRunner struct{}
+
+// Run executes the program at path, or found on the PATH, with the provided arguments and waits for it to complete.
+// The process is executed in the working directory carried by the context, if any, and is killed if the context is
+// done before it completes.
+func (ExecRunner) Run(ctx context.Context, path string, args ...string) (Result, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), EnvFromContext(ctx)...)
+	cmd.Dir = WorkingDirFromContext(ctx)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	result := Result{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: -1}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	return result, err
+}
+
+type (
+	envContextKey        struct{}
+	workingDirContextKey struct{}
+)
+
+// ContextWithEnv returns a copy of ctx carrying the environment variables, provided in the form "key=value",
+// the commands executed by a Runner using the context must be executed with, on top of the variables already
+// carried by ctx.
+func ContextWithEnv(ctx context.Context, env ...string) context.Context {
+	if len(env) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, envContextKey{}, append(EnvFromContext(ctx), env...))
+}
+
+// EnvFromContext returns the environment variables carried by ctx
+func EnvFromContext(ctx context.Context) []string {
+	env, _ := ctx.Value(envContextKey{}).([]string)
+	return append([]string(nil), env...)
+}
+
+// ContextWithWorkingDir returns a copy of ctx carrying the working directory the commands executed by a Runner using
+// the context must be executed in, so that the relative paths passed to the commands are resolved against dir. An
+// empty dir keeps the working directory of the current process.
+func ContextWithWorkingDir(ctx context.Context, dir string) context.Context {
+	if dir == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, workingDirContextKey{}, dir)
+}
+
+// WorkingDirFromContext returns the working directory carried by ctx, or an empty string if there is none
+func WorkingDirFromContext(ctx context.Context) string {
+	dir, _ := ctx.Value(workingDirContextKey{}).(string)
+	return dir
+}